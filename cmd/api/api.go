@@ -18,6 +18,7 @@ import (
 	"github.com/yogaprasetya22/api-gotokopedia/internal/auth"
 	"github.com/yogaprasetya22/api-gotokopedia/internal/env"
 	"github.com/yogaprasetya22/api-gotokopedia/internal/mailer"
+	"github.com/yogaprasetya22/api-gotokopedia/internal/payment"
 	"github.com/yogaprasetya22/api-gotokopedia/internal/ratelimiter"
 	"github.com/yogaprasetya22/api-gotokopedia/internal/store"
 	"github.com/yogaprasetya22/api-gotokopedia/internal/store/cache"
@@ -33,7 +34,9 @@ type application struct {
 	mailer            mailer.Client
 	authenticator     auth.Authenticator
 	rateLimiter       ratelimiter.Limiter
+	paymentGateway    payment.Gateway
 	googleOauthConfig *oauth2.Config
+	cursors           *store.CursorCodec
 }
 
 type config struct {
@@ -46,7 +49,14 @@ type config struct {
 	auth        authConfig
 	redisCfg    redisConfig
 	rateLimiter ratelimiter.Config
+	payment     payment.Config
+	orderExpiry orderExpiryConfig
 	google      googleConfig
+	cursor      cursorConfig
+}
+
+type cursorConfig struct {
+	secret string
 }
 
 type googleConfig struct {
@@ -108,8 +118,8 @@ func (app *application) mount() *chi.Mux {
 	r.Use(cors.Handler(cors.Options{
 		AllowedOrigins:   []string{env.GetString("CORS_ALLOWED_ORIGIN", "http://localhost:3000")},
 		AllowedMethods:   []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
-		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "X-CSRF-Token"},
-		ExposedHeaders:   []string{"Link"},
+		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "X-CSRF-Token", idempotencyKeyHeader},
+		ExposedHeaders:   []string{"Link", idempotencyReplayHeader},
 		AllowCredentials: true,
 		MaxAge:           300, // Maximum value not ignored by any of major browsers
 	}))
@@ -140,6 +150,22 @@ func (app *application) mount() *chi.Mux {
 			r.Post("/", app.createTokoHandler)
 			r.Route("/{slug_toko}", func(r chi.Router) {
 				r.Get("/", app.getProductTokoHandler)
+
+				/// seller orders
+				r.Route("/orders", func(r chi.Router) {
+					r.Use(app.AuthTokenMiddleware)
+					r.Use(app.IdempotencyMiddleware)
+					r.Use(app.tokoContextMiddleware)
+					r.Use(app.checkTokoOwnership)
+
+					r.Get("/", app.listTokoOrdersHandler)
+
+					r.Route("/{orderID}", func(r chi.Router) {
+						r.Patch("/accept", app.acceptTokoOrderHandler)
+						r.Patch("/ship", app.shipTokoOrderHandler)
+						r.Patch("/deliver", app.deliverTokoOrderHandler)
+					})
+				})
 			})
 		})
 
@@ -158,11 +184,13 @@ func (app *application) mount() *chi.Mux {
 				r.Get("/", app.getTokoHandler)
 
 				r.Route("/{slug_product}", func(r chi.Router) {
+					r.Use(app.OptionalAuthTokenMiddleware)
 					r.Get("/", app.getProductHandler)
 				})
 			})
 
 			r.Group(func(r chi.Router) {
+				r.Use(app.OptionalAuthTokenMiddleware)
 				r.Get("/feed", app.getProductFeedHandler)
 			})
 		})
@@ -177,6 +205,8 @@ func (app *application) mount() *chi.Mux {
 
 				r.Patch("/", app.checkProductOwnership(app.updateProductHandler))
 				r.Delete("/", app.checkProductOwnership(app.deleteProductHandler))
+				r.Get("/variants", app.getProductVariantsHandler)
+				r.Put("/variants", app.checkProductOwnership(app.replaceProductVariantsHandler))
 
 				/// comment
 				r.Route("/comment", func(r chi.Router) {
@@ -196,6 +226,7 @@ func (app *application) mount() *chi.Mux {
 		/// carts
 		r.Route("/cart", func(r chi.Router) {
 			r.Use(app.AuthTokenMiddleware)
+			r.Use(app.IdempotencyMiddleware)
 
 			r.Post("/", app.createCartHandler)
 			r.Get("/", app.getCartsHandler)
@@ -219,30 +250,76 @@ func (app *application) mount() *chi.Mux {
 		/// order
 		r.Route("/order", func(r chi.Router) {
 			r.Use(app.AuthTokenMiddleware)
+			r.Use(app.IdempotencyMiddleware)
 
 			r.Get("/", app.listOrdersHandler)
-			r.Get("/{id}", app.getOrderHandler)
-			// r.Patch("/{id}", app.updateOrderHandler)
-			// r.Delete("/{id}", app.deleteOrderHandler)
+
+			r.Route("/{id}", func(r chi.Router) {
+				r.Use(app.orderContextMiddleware)
+
+				r.Get("/", app.getOrderHandler)
+				r.Patch("/cancel", app.cancelOrderHandler)
+				r.Patch("/refund", app.refundOrderHandler)
+				// r.Patch("/", app.updateOrderHandler)
+				// r.Delete("/", app.deleteOrderHandler)
+			})
 		})
 
 		/// shipping addresses
 		r.Route("/shipping-addresses", func(r chi.Router) {
 			r.Use(app.AuthTokenMiddleware)
+			r.Use(app.IdempotencyMiddleware)
 
 			r.Get("/", app.getShippingAddressHandler)
 			r.Get("/default", app.getDefaultShippingAddressHandler)
 			r.Post("/", app.createShippingAddressHandler)
+
+			r.Route("/{id}", func(r chi.Router) {
+				r.Use(app.shippingAddressContextMiddleware)
+
+				r.Patch("/", app.updateShippingAddressHandler)
+				r.Delete("/", app.deleteShippingAddressHandler)
+				r.Put("/default", app.setDefaultShippingAddressHandler)
+			})
 		})
 
 		r.Route("/checkout", func(r chi.Router) {
 			r.Use(app.AuthTokenMiddleware)
+			r.Use(app.IdempotencyMiddleware)
 
 			r.Post("/start", app.startCheckoutHandler)
 			r.Get("/{session_id}", app.getCheckoutBySessionHandler)
+			r.Patch("/{session_id}", app.updateCheckoutSessionHandler)
+			r.Post("/{session_id}/shipping-quotes", app.shippingQuotesHandler)
+			r.Put("/{session_id}/voucher", app.applyCheckoutVoucherHandler)
+			r.Delete("/{session_id}/voucher", app.removeCheckoutVoucherHandler)
 			r.Post("/complete", app.completeCheckoutHandler)
 		})
 
+		/// vouchers
+		r.Route("/vouchers", func(r chi.Router) {
+			r.Use(app.AuthTokenMiddleware)
+			r.Use(app.checkAdmin)
+
+			r.Get("/", app.listVouchersHandler)
+			r.Post("/", app.createVoucherHandler)
+			r.Get("/{id}", app.getVoucherHandler)
+			r.Put("/{id}", app.updateVoucherHandler)
+			r.Delete("/{id}", app.deleteVoucherHandler)
+		})
+
+		/// payments
+		r.Route("/payments", func(r chi.Router) {
+			r.Post("/callback", app.paymentCallbackHandler)
+		})
+
+		/// regions
+		r.Route("/regions", func(r chi.Router) {
+			r.Get("/", app.listProvincesHandler)
+			r.Get("/{id}", app.getRegionHandler)
+			r.Get("/{id}/children", app.listRegionChildrenHandler)
+		})
+
 		/// shipping methods
 		r.Route("/shipping-methods", func(r chi.Router) {
 			r.Get("/", app.listShippingMethodsHandler)
@@ -308,6 +385,11 @@ func (app *application) run(mux *chi.Mux) error {
 
 	shutdown := make(chan error)
 
+	/// Job latar belakang berhenti bersama server
+	jobsCtx, stopJobs := context.WithCancel(context.Background())
+	runner := app.newJobRunner()
+	runner.Start(jobsCtx)
+
 	go func() {
 		quit := make(chan os.Signal, 1)
 
@@ -319,13 +401,20 @@ func (app *application) run(mux *chi.Mux) error {
 
 		app.logger.Infow("signal caught", "signal", s.String())
 
-		shutdown <- srv.Shutdown(ctx)
+		err := srv.Shutdown(ctx)
+
+		stopJobs()
+		runner.Wait()
+
+		shutdown <- err
 	}()
 
 	app.logger.Infow("server has started", "addr", app.config.addr, "env", app.config.env)
 
 	err := srv.ListenAndServe()
 	if !errors.Is(err, http.ErrServerClosed) {
+		stopJobs()
+		runner.Wait()
 		return err
 	}
 
@@ -14,8 +14,9 @@ type cartKey string
 const CartContext cartKey = "cart"
 
 type AddToCartPayload struct {
-	ProductID int64 `json:"product_id" validate:"required"`
-	Quantity  int64 `json:"quantity" validate:"required,min=1"`
+	ProductID int64  `json:"product_id" validate:"required"`
+	VariantID *int64 `json:"variant_id" validate:"omitempty"`
+	Quantity  int64  `json:"quantity" validate:"required,min=1"`
 }
 
 type UpdateCartItemPayload struct {
@@ -32,8 +33,9 @@ type UpdateCartItemPayload struct {
 //	@Produce		json
 //	@Param			limit	query		int		false	"limit"
 //	@Param			offset	query		int		false	"offset"
+//	@Param			cursor	query		string	false	"next_cursor or prev_cursor from the previous page, overrides offset"
 //	@Param			sort	query		string	false	"sort"
-//	@Success		200		{object}	store.Cart
+//	@Success		200		{object}	store.MetaCart
 //	@Failure		400		{object}	error
 //	@Failure		404		{object}	error
 //	@Failure		500		{object}	error
@@ -47,14 +49,8 @@ func (app *application) getCartsHandler(w http.ResponseWriter, r *http.Request)
 		Search:   "",
 	}
 
-	fq, err := fq.Parse(r)
-	if err != nil {
-		app.badRequestResponse(w, r, err)
-		return
-	}
-
-	if err := Validate.Struct(fq); err != nil {
-		app.badRequestResponse(w, r, err)
+	fq, ok := app.readFeedQuery(w, r, fq)
+	if !ok {
 		return
 	}
 
@@ -63,15 +59,12 @@ func (app *application) getCartsHandler(w http.ResponseWriter, r *http.Request)
 	ctx := r.Context()
 	cart, err := app.store.Carts.GetCartByUserIDPQ(ctx, user.ID, fq)
 	if err != nil {
-		switch {
-		case errors.Is(err, store.ErrNotFound):
-			app.notFoundResponse(w, r, err)
-		default:
-			app.internalServerError(w, r, err)
-		}
+		app.listErrorResponse(w, r, err)
 		return
 	}
 
+	cart.Pagination.Sign(app.cursors)
+
 	if err := app.jsonResponse(w, http.StatusOK, cart); err != nil {
 		app.internalServerError(w, r, err)
 		return
@@ -146,11 +139,13 @@ func (app *application) createCartHandler(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	cart, err := app.store.Carts.AddToCartTransaction(r.Context(), user.ID, payload.ProductID, payload.Quantity)
+	cart, err := app.store.Carts.AddToCartTransaction(r.Context(), user.ID, payload.ProductID, payload.VariantID, payload.Quantity)
 	if err != nil {
 		switch err {
 		case store.ErrNotFound:
 			app.notFoundResponse(w, r, err)
+		case store.ErrVariantRequired, store.ErrInvalidVariant:
+			app.badRequestResponse(w, r, err)
 		default:
 			app.internalServerError(w, r, err)
 		}
@@ -0,0 +1,261 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/yogaprasetya22/api-gotokopedia/internal/store"
+)
+
+var (
+	errShippingMethodInactive = errors.New("shipping method is not active")
+	errPaymentMethodInactive  = errors.New("payment method is not active")
+	errShippingUnavailable    = errors.New("shipping method is not available for this address")
+	errUnknownCartStore       = errors.New("cart store is not part of this checkout session")
+)
+
+// CheckoutStorePayload adalah pilihan satu cart store (satu toko), field nil tidak diubah
+type CheckoutStorePayload struct {
+	CartStoreID      uuid.UUID `json:"cart_store_id" validate:"required"`
+	ShippingMethodID *int64    `json:"shipping_method_id" validate:"omitempty,gt=0"`
+	Notes            *string   `json:"notes" validate:"omitempty,max=500"`
+}
+
+// UpdateCheckoutSessionPayload mengubah pilihan checkout session, field nil tidak diubah
+type UpdateCheckoutSessionPayload struct {
+	ShippingAddressID *string                `json:"shipping_address_id" validate:"omitempty,uuid"`
+	PaymentMethodID   *int64                 `json:"payment_method_id" validate:"omitempty,gt=0"`
+	Stores            []CheckoutStorePayload `json:"stores" validate:"omitempty,dive"`
+}
+
+// activeShippingMethod mengambil shipping method yang masih aktif
+func (app *application) activeShippingMethod(ctx context.Context, id int64) (*store.ShippingMethod, error) {
+	method, err := app.store.Orders.GetShippingMethodByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if !method.IsActive {
+		return nil, errShippingMethodInactive
+	}
+
+	return method, nil
+}
+
+// activePaymentMethod mengambil payment method yang masih aktif
+func (app *application) activePaymentMethod(ctx context.Context, id int64) (*store.PaymentMethod, error) {
+	method, err := app.store.Orders.GetPaymentMethodByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if !method.IsActive {
+		return nil, errPaymentMethodInactive
+	}
+
+	return method, nil
+}
+
+// recalculateCheckout menghitung ulang ongkir setiap toko dengan shipping method pilihannya,
+// potongan voucher dengan ongkir terbaru, lalu total checkout session
+func (app *application) recalculateCheckout(ctx context.Context, session *store.CheckoutSession) error {
+	costs := make(map[uuid.UUID]store.Money, len(session.CartStore))
+
+	// Ongkir baru bisa dihitung setelah alamat tujuan dipilih
+	if session.ShippingAddress != nil {
+		var methods []*store.ShippingMethod
+		seen := make(map[int64]bool)
+		for _, cs := range session.CartStore {
+			if m := session.ShippingMethodFor(cs.ID); m != nil && !seen[m.ID] {
+				seen[m.ID] = true
+				methods = append(methods, m)
+			}
+		}
+
+		if len(methods) > 0 {
+			quotes, err := app.quoteShipping(ctx, session, session.ShippingAddress, methods)
+			if err != nil {
+				return err
+			}
+
+			for _, q := range quotes {
+				for _, sq := range q.Stores {
+					if m := session.ShippingMethodFor(sq.CartStoreID); m == nil || m.ID != q.ShippingMethod.ID {
+						continue
+					}
+
+					if !sq.Available {
+						return fmt.Errorf("%w: %s", errShippingUnavailable, q.ShippingMethod.Name)
+					}
+					costs[sq.CartStoreID] = sq.Cost
+				}
+			}
+		}
+	}
+
+	session.ShippingCosts = costs
+
+	// Voucher bisa saja berubah sejak dipasang, potongan ongkir juga mengikuti ongkir terbaru
+	if session.Voucher != nil {
+		voucher, err := app.store.Vouchers.GetByID(ctx, session.Voucher.ID)
+		if err != nil {
+			if errors.Is(err, store.ErrNotFound) {
+				return store.ErrVoucherInactive
+			}
+			return err
+		}
+
+		if err := app.applyVoucher(ctx, session, voucher); err != nil {
+			return err
+		}
+	}
+
+	session.Recalculate()
+	return nil
+}
+
+// validateCheckoutComplete memastikan alamat, payment method dan shipping method setiap toko sudah dipilih
+func validateCheckoutComplete(session *store.CheckoutSession) error {
+	if session.ShippingAddress == nil {
+		return errors.New("shipping address is required")
+	}
+
+	if session.PaymentMethod == nil {
+		return errors.New("payment method is required")
+	}
+
+	for _, cs := range session.CartStore {
+		if session.ShippingMethodFor(cs.ID) == nil {
+			return fmt.Errorf("shipping method is required for cart store %s", cs.ID)
+		}
+	}
+
+	return nil
+}
+
+// checkoutSelectionError memetakan error pilihan checkout ke response yang sesuai
+func (app *application) checkoutSelectionError(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case errors.Is(err, store.ErrNotFound):
+		app.notFoundResponse(w, r, err)
+	case errors.Is(err, errShippingMethodInactive),
+		errors.Is(err, errPaymentMethodInactive),
+		errors.Is(err, errShippingUnavailable),
+		errors.Is(err, errUnknownCartStore):
+		app.badRequestResponse(w, r, err)
+	case isVoucherRuleError(err):
+		app.unprocessableEntityResponse(w, r, err)
+	default:
+		app.internalServerError(w, r, err)
+	}
+}
+
+// UpdateCheckoutSession godoc
+//
+//	@Summary		Update checkout session
+//	@Description	Choose the shipping address, payment method, and the shipping method and notes of each cart store before completing the checkout. Shipping costs, voucher discount and totals are recalculated and stored in the session
+//	@Tags			checkout
+//	@Accept			json
+//	@Produce		json
+//	@Param			session_id	path		string							true	"Checkout Session ID"
+//	@Param			payload		body		UpdateCheckoutSessionPayload	true	"Payload"
+//	@Success		200			{object}	store.CheckoutSession
+//	@Failure		400			{object}	error
+//	@Failure		401			{object}	error
+//	@Failure		404			{object}	error
+//	@Failure		422			{object}	error
+//	@Failure		500			{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/checkout/{session_id} [patch]
+func (app *application) updateCheckoutSessionHandler(w http.ResponseWriter, r *http.Request) {
+	user := getUserFromContext(r)
+
+	var payload UpdateCheckoutSessionPayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	checkoutSession, ok := app.getUserCheckoutSession(w, r)
+	if !ok {
+		return
+	}
+
+	ctx := r.Context()
+	if payload.ShippingAddressID != nil {
+		address, err := app.store.ShippingAddresses.GetByID(ctx, uuid.MustParse(*payload.ShippingAddressID), user.ID)
+		if err != nil {
+			app.checkoutSelectionError(w, r, err)
+			return
+		}
+		checkoutSession.ShippingAddress = address
+	}
+
+	if payload.PaymentMethodID != nil {
+		paymentMethod, err := app.activePaymentMethod(ctx, *payload.PaymentMethodID)
+		if err != nil {
+			app.checkoutSelectionError(w, r, err)
+			return
+		}
+		checkoutSession.PaymentMethod = paymentMethod
+	}
+
+	cartStores := make(map[uuid.UUID]bool, len(checkoutSession.CartStore))
+	for _, cs := range checkoutSession.CartStore {
+		cartStores[cs.ID] = true
+	}
+
+	for _, sp := range payload.Stores {
+		if !cartStores[sp.CartStoreID] {
+			app.checkoutSelectionError(w, r, fmt.Errorf("%w: %s", errUnknownCartStore, sp.CartStoreID))
+			return
+		}
+
+		if sp.ShippingMethodID != nil {
+			shippingMethod, err := app.activeShippingMethod(ctx, *sp.ShippingMethodID)
+			if err != nil {
+				app.checkoutSelectionError(w, r, err)
+				return
+			}
+
+			if checkoutSession.ShippingMethods == nil {
+				checkoutSession.ShippingMethods = make(map[uuid.UUID]*store.ShippingMethod)
+			}
+			checkoutSession.ShippingMethods[sp.CartStoreID] = shippingMethod
+		}
+
+		if sp.Notes != nil {
+			if checkoutSession.StoreNotes == nil {
+				checkoutSession.StoreNotes = make(map[uuid.UUID]string)
+			}
+			checkoutSession.StoreNotes[sp.CartStoreID] = *sp.Notes
+		}
+	}
+
+	if err := app.recalculateCheckout(ctx, checkoutSession); err != nil {
+		app.checkoutSelectionError(w, r, err)
+		return
+	}
+
+	if err := app.checkoutSessions().UpdateCheckoutSession(ctx, checkoutSession); err != nil {
+		switch {
+		case errors.Is(err, store.ErrSessionExpired):
+			app.notFoundResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, checkoutSession); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -16,14 +17,21 @@ type StartCheckoutPayload struct {
 	CartStoreID []uuid.UUID `json:"cart_store_id" validate:"required"`
 }
 
+// CompleteCheckoutPayload field selain session_id boleh kosong jika sudah dipilih lewat PATCH /checkout/{session_id}
 type CompleteCheckoutPayload struct {
 	SessionID         string `json:"session_id" validate:"required"`
-	ShippingMethodID  int64  `json:"shipping_method_id" validate:"required"`
-	PaymentMethodID   int64  `json:"payment_method_id" validate:"required"`
-	ShippingAddressID string `json:"shipping_address_id" validate:"required"`
+	ShippingMethodID  int64  `json:"shipping_method_id" validate:"omitempty,gt=0"`
+	PaymentMethodID   int64  `json:"payment_method_id" validate:"omitempty,gt=0"`
+	ShippingAddressID string `json:"shipping_address_id" validate:"omitempty,uuid"`
 	Notes             string `json:"notes"`
 }
 
+type CompleteCheckoutResponse struct {
+	Status   string             `json:"status"` // partial jika ada order yang tagihannya gagal dibuat
+	Orders   []*store.Order     `json:"orders"`
+	Payments []*CheckoutPayment `json:"payments"`
+}
+
 func (p *StartCheckoutPayload) UnmarshalJSON(data []byte) error {
 	var temp struct {
 		CartStoreID []string `json:"cart_store_id"`
@@ -69,8 +77,8 @@ func (app *application) getCheckoutBySessionHandler(w http.ResponseWriter, r *ht
 		return
 	}
 
-	// Dapatkan session dari Redis
-	checkoutSession, err := app.cacheStorage.Checkout.GetCheckoutSession(r.Context(), sessionID)
+	// Dapatkan checkout session
+	checkoutSession, err := app.checkoutSessions().GetCheckoutSession(r.Context(), sessionID)
 	if err != nil {
 		switch {
 		case errors.Is(err, store.ErrNotFound):
@@ -137,10 +145,25 @@ func (app *application) startCheckoutHandler(w http.ResponseWriter, r *http.Requ
 		return
 	}
 
-	// Mulai checkout session di Redis
-	checkoutSession, err := app.cacheStorage.Checkout.StartCheckoutSession(r.Context(), user.ID, cartStore)
+	// Mulai checkout session
+	checkoutSession, err := app.checkoutSessions().StartCheckoutSession(r.Context(), user.ID, cartStore)
 	if err != nil {
 		app.badRequestResponse(w, r, fmt.Errorf("failed to start checkout: %w", err))
+		return
+	}
+
+	// Tahan stok selama session berlaku, session dibatalkan jika stok tidak cukup
+	if err := app.inventoryReservations().Reserve(r.Context(), checkoutSession); err != nil {
+		_ = app.checkoutSessions().CompleteCheckout(r.Context(), checkoutSession.SessionID)
+
+		var stockErr *store.StockError
+		switch {
+		case errors.As(err, &stockErr):
+			app.badRequestResponse(w, r, fmt.Errorf("failed to start checkout: %w", err))
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
 	}
 
 	if err := app.jsonResponse(w, http.StatusOK, checkoutSession); err != nil {
@@ -151,13 +174,15 @@ func (app *application) startCheckoutHandler(w http.ResponseWriter, r *http.Requ
 // CompleteCheckout godoc
 //
 //	@Summary		Complete checkout process
-//	@Description	Complete checkout process and create order
+//	@Description	Complete checkout process and return the created orders, one per cart store, with their pending payments. Address, payment and shipping method may be omitted when already chosen with PATCH /checkout/{session_id}. Shipping cost of each order is calculated by the shipping rate engine and the voucher applied to the session is redeemed. When the gateway charge of an order fails that order is cancelled and returned with an error in its payment result and status partial
 //	@Tags			checkout
 //	@Accept			json
 //	@Produce		json
 //	@Param			payload	body		CompleteCheckoutPayload	true	"Payload"
-//	@Success		200		{object}	store.Order
+//	@Success		200		{object}	CompleteCheckoutResponse
 //	@Failure		400		{object}	error
+//	@Failure		404		{object}	error
+//	@Failure		422		{object}	error
 //	@Failure		500		{object}	error
 //	@Security		ApiKeyAuth
 //	@Router			/checkout/complete [post]
@@ -175,10 +200,15 @@ func (app *application) completeCheckoutHandler(w http.ResponseWriter, r *http.R
 		return
 	}
 
-	// Dapatkan session dari Redis
-	checkoutSession, err := app.cacheStorage.Checkout.GetCheckoutSession(r.Context(), payload.SessionID)
+	// Dapatkan checkout session
+	checkoutSession, err := app.checkoutSessions().GetCheckoutSession(r.Context(), payload.SessionID)
 	if err != nil {
-		app.internalServerError(w, r, err)
+		switch {
+		case errors.Is(err, store.ErrNotFound), errors.Is(err, store.ErrSessionExpired):
+			app.notFoundResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
 		return
 	}
 
@@ -188,51 +218,139 @@ func (app *application) completeCheckoutHandler(w http.ResponseWriter, r *http.R
 		return
 	}
 
-	// Dapatkan shipping address
-	shippingAddress, err := app.store.ShippingAddresses.GetByID(r.Context(), uuid.MustParse(payload.ShippingAddressID), user.ID)
-	if err != nil {
-		app.internalServerError(w, r, err)
+	// Pilihan di payload menggantikan pilihan yang sudah disimpan di session
+	if payload.ShippingAddressID != "" {
+		shippingAddress, err := app.store.ShippingAddresses.GetByID(r.Context(), uuid.MustParse(payload.ShippingAddressID), user.ID)
+		if err != nil {
+			app.checkoutSelectionError(w, r, err)
+			return
+		}
+		checkoutSession.ShippingAddress = shippingAddress
+	}
+
+	if payload.ShippingMethodID != 0 {
+		shippingMethod, err := app.activeShippingMethod(r.Context(), payload.ShippingMethodID)
+		if err != nil {
+			app.checkoutSelectionError(w, r, err)
+			return
+		}
+		checkoutSession.ShippingMethod = shippingMethod
+		checkoutSession.ShippingMethods = nil
+	}
+
+	if payload.PaymentMethodID != 0 {
+		paymentMethod, err := app.activePaymentMethod(r.Context(), payload.PaymentMethodID)
+		if err != nil {
+			app.checkoutSelectionError(w, r, err)
+			return
+		}
+		checkoutSession.PaymentMethod = paymentMethod
+	}
+
+	if payload.Notes != "" {
+		checkoutSession.Notes = payload.Notes
+		checkoutSession.StoreNotes = nil
+	}
+
+	if err := validateCheckoutComplete(checkoutSession); err != nil {
+		app.badRequestResponse(w, r, err)
 		return
 	}
 
-	// Dapatkan shipping method
-	shippingMethod, err := app.store.Orders.GetShippingMethodByID(r.Context(), payload.ShippingMethodID)
-	if err != nil {
-		app.internalServerError(w, r, err)
+	// Hitung ulang ongkir per toko dan potongan voucher, voucher bisa saja berubah sejak dipasang
+	if err := app.recalculateCheckout(r.Context(), checkoutSession); err != nil {
+		app.checkoutSelectionError(w, r, err)
 		return
 	}
 
-	// Dapatkan payment method
-	paymentMethod, err := app.store.Orders.GetPaymentMethodByID(r.Context(), payload.PaymentMethodID)
-	if err != nil {
-		app.internalServerError(w, r, err)
+	// Metode pembayaran dicek ke gateway sebelum order dibuat agar tidak ada order yang tidak bisa ditagih
+	if err := app.paymentGateway.ValidateMethod(checkoutSession.PaymentMethod.MidtransCode); err != nil {
+		app.unprocessableEntityResponse(w, r, err)
 		return
 	}
 
-	// Update checkout session dengan data lengkap
-	checkoutSession.ShippingAddress = shippingAddress
-	checkoutSession.ShippingMethod = shippingMethod
-	checkoutSession.PaymentMethod = paymentMethod
+	// Buat order permanen di database beserta pembayaran pending-nya
+	orders, err := app.store.Checkout.CreateOrderFromCheckout(r.Context(), checkoutSession)
+	if err != nil {
+		switch {
+		case isVoucherRuleError(err):
+			app.unprocessableEntityResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
 
-	// Buat order permanen di database
-	err = app.store.Checkout.CreateOrderFromCheckout(r.Context(), checkoutSession)
+	// Hapus checkout session
+	err = app.checkoutSessions().CompleteCheckout(r.Context(), payload.SessionID)
 	if err != nil {
 		app.internalServerError(w, r, err)
 		return
 	}
 
-	// Hapus session dari Redis
-	err = app.cacheStorage.Checkout.CompleteCheckout(r.Context(), payload.SessionID)
-	if err != nil {
+	// Stok sudah dipotong order, lepas tahanannya
+	if err := app.inventoryReservations().Release(r.Context(), payload.SessionID); err != nil {
 		app.internalServerError(w, r, err)
 		return
 	}
 
-	// Response dengan order yang dibuat
-	// Anda mungkin perlu menyesuaikan response sesuai kebutuhan
-	if err := app.jsonResponse(w, http.StatusOK, map[string]string{"status": "success"}); err != nil {
+	// Buat tagihan gateway untuk pembayaran pending setiap order. Order yang gagal ditagih sudah dibatalkan
+	// createOrderPayment, tetapi tetap dikembalikan bersama hasil pembayarannya karena order lain
+	// di checkout yang sama mungkin sudah punya tagihan aktif
+	status := "success"
+	payments := make([]*CheckoutPayment, 0, len(orders))
+	for i, order := range orders {
+		p, err := app.createOrderPayment(r.Context(), user, order)
+		if err != nil {
+			app.logger.Errorw("failed to create order payment", "order_id", order.ID, "error", err.Error())
+			status = "partial"
+
+			if current, err := app.store.Orders.GetByID(r.Context(), order.ID); err == nil {
+				orders[i] = current
+			}
+			p = &CheckoutPayment{OrderID: order.ID, OrderNumber: order.OrderNumber, Error: errPaymentNotCreated}
+		}
+
+		payments = append(payments, p)
+	}
+
+	response := CompleteCheckoutResponse{
+		Status:   status,
+		Orders:   orders,
+		Payments: payments,
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, response); err != nil {
 		app.internalServerError(w, r, err)
 	}
 }
 
+type checkoutSessions interface {
+	StartCheckoutSession(ctx context.Context, userID int64, cartStore []store.CartStores) (*store.CheckoutSession, error)
+	GetCheckoutSession(ctx context.Context, sessionID string) (*store.CheckoutSession, error)
+	UpdateCheckoutSession(ctx context.Context, session *store.CheckoutSession) error
+	CompleteCheckout(ctx context.Context, sessionID string) error
+}
+
+// checkoutSessions memilih session di Redis jika aktif, selain itu tabel checkout_sessions di Postgres
+func (app *application) checkoutSessions() checkoutSessions {
+	if app.config.redisCfg.enabled {
+		return app.cacheStorage.Checkout
+	}
+
+	return app.store.CheckoutSessions
+}
+
+type inventoryReservations interface {
+	Reserve(context.Context, *store.CheckoutSession) error
+	Release(ctx context.Context, sessionID string) error
+}
+
+// inventoryReservations memilih counter Redis jika aktif, selain itu tabel inventory_reservations di Postgres
+func (app *application) inventoryReservations() inventoryReservations {
+	if app.config.redisCfg.enabled {
+		return app.cacheStorage.InventoryReservations
+	}
 
+	return app.store.InventoryReservations
+}
@@ -29,9 +29,10 @@ type CreateCommentsPayload struct {
 //	@Param			slug	path		string	true	"Product Slug"
 //	@Param			limit	query		int		false	"limit"
 //	@Param			offset	query		int		false	"offset"
+//	@Param			cursor	query		string	false	"next_cursor or prev_cursor from the previous page, overrides offset"
 //	@Param			sort	query		string	false	"sort"
 //	@Param			rating	query		int		false	"rating"
-//	@Success		200		{array}		store.Comment
+//	@Success		200		{object}	store.MetaCommentPaginated
 //	@Failure		404		{object}	error
 //	@Failure		500		{object}	error
 //	@Router			/comment/{slug} [get]
@@ -45,14 +46,8 @@ func (app *application) getCommentsHandler(w http.ResponseWriter, r *http.Reques
 		Rating: 0,
 	}
 
-	fq, err := fq.Parse(r)
-	if err != nil {
-		app.badRequestResponse(w, r, err)
-		return
-	}
-
-	if err := Validate.Struct(fq); err != nil {
-		app.badRequestResponse(w, r, err)
+	fq, ok := app.readFeedQuery(w, r, fq)
+	if !ok {
 		return
 	}
 
@@ -60,10 +55,12 @@ func (app *application) getCommentsHandler(w http.ResponseWriter, r *http.Reques
 
 	comments, err := app.store.Comments.GetComments(ctx, slugProduct, fq)
 	if err != nil {
-		app.internalServerError(w, r, err)
+		app.listErrorResponse(w, r, err)
 		return
 	}
 
+	comments.Pagination.Sign(app.cursors)
+
 	if err := app.jsonResponse(w, http.StatusOK, comments); err != nil {
 		app.internalServerError(w, r, err)
 	}
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"errors"
 	"net/http"
 
@@ -37,6 +38,13 @@ func (app *application) getProductHandler(w http.ResponseWriter, r *http.Request
 		return
 	}
 
+	// Riwayat lihat hanya sinyal feed personal, gagal mencatat tidak boleh menggagalkan halaman produk
+	if user := getUserFromContext(r); user != nil {
+		if err := app.store.Products.RecordView(ctx, user.ID, product.ID); err != nil {
+			app.logger.Warnw("record product view", "product_id", product.ID, "error", err.Error())
+		}
+	}
+
 	if err := app.jsonResponse(w, http.StatusOK, product); err != nil {
 		app.internalServerError(w, r, err)
 	}
@@ -45,17 +53,26 @@ func (app *application) getProductHandler(w http.ResponseWriter, r *http.Request
 // GetCatalogueFeed gdoc
 //
 //	@Summary		fetch catalogue feed
-//	@Description	fetch catalogue feed with pagination
+//	@Description	fetch personalized catalogue feed with pagination, anonymous visitors get trending and best selling products
 //	@Tags			catalogue
 //	@Accept			json
 //	@Produce		json
-//	@Param			limit	query		int		false	"limit"
-//	@Param			offset	query		int		false	"offset"
-//	@Param			sort	query		string	false	"sort"
-//	@Param			search	query		string	false	"search"
-//	@Success		200		{object}	[]store.Product
-//	@Failure		400		{object}	error
-//	@Failure		500		{object}	error
+//	@Param			limit		query		int		false	"limit"
+//	@Param			offset		query		int		false	"offset"
+//	@Param			cursor		query		string	false	"next_cursor or prev_cursor from the previous page, overrides offset"
+//	@Param			sort		query		string	false	"sort: relevance, newest, price_asc, price_desc, rating, best_selling (default: personalized score)"
+//	@Param			search		query		string	false	"search"
+//	@Param			min_price	query		number	false	"minimum effective price"
+//	@Param			max_price	query		number	false	"maximum effective price"
+//	@Param			rating		query		int		false	"minimum average rating"
+//	@Param			country		query		string	false	"toko country"
+//	@Param			discount	query		number	false	"minimum discount percent"
+//	@Param			in_stock	query		bool	false	"only products in stock"
+//	@Param			since		query		string	false	"created since (YYYY-MM-DD HH:MM:SS)"
+//	@Param			until		query		string	false	"created until (YYYY-MM-DD HH:MM:SS)"
+//	@Success		200			{object}	ProductFeed
+//	@Failure		400			{object}	error
+//	@Failure		500			{object}	error
 //	@Router			/catalogue/feed [get]
 func (app *application) getProductFeedHandler(w http.ResponseWriter, r *http.Request) {
 	fq := store.PaginatedFeedQuery{
@@ -66,26 +83,50 @@ func (app *application) getProductFeedHandler(w http.ResponseWriter, r *http.Req
 		Search:   "",
 	}
 
-	fq, err := fq.Parse(r)
-	if err != nil {
-		app.badRequestResponse(w, r, err)
+	fq, ok := app.readFeedQuery(w, r, fq)
+	if !ok {
 		return
 	}
 
-	if err := Validate.Struct(fq); err != nil {
-		app.badRequestResponse(w, r, err)
-		return
+	var userID int64
+	if user := getUserFromContext(r); user != nil {
+		userID = user.ID
 	}
 
-	users, err := app.store.Products.GetProductFeed(r.Context(), []int64{3, 4}, fq)
+	products, err := app.getPersonalizedFeed(r.Context(), userID, fq)
 	if err != nil {
-		app.internalServerError(w, r, err)
+		app.listErrorResponse(w, r, err)
 		return
 	}
 
-	if err := app.jsonResponse(w, http.StatusOK, users); err != nil {
-		app.internalServerError(w, r, err)
+	app.productFeedResponse(w, r, products, store.NewProductFilter(fq))
+}
+
+// getPersonalizedFeed mengambil halaman feed user dari cache redis jika aktif, cursor halaman yang
+// disimpan sudah ditandatangani
+func (app *application) getPersonalizedFeed(ctx context.Context, userID int64, fq store.PaginatedFeedQuery) (*store.Paginated[*store.Product], error) {
+	if !app.config.redisCfg.enabled {
+		return app.store.Products.GetPersonalizedFeed(ctx, userID, fq)
+	}
+
+	page, err := app.cacheStorage.Feeds.Get(ctx, userID, fq)
+	if err != nil {
+		return nil, err
+	}
+
+	if page == nil {
+		page, err = app.store.Products.GetPersonalizedFeed(ctx, userID, fq)
+		if err != nil {
+			return nil, err
+		}
+
+		page.Pagination.Sign(app.cursors)
+		if err := app.cacheStorage.Feeds.Set(ctx, userID, fq, page); err != nil {
+			return nil, err
+		}
 	}
+
+	return page, nil
 }
 
 // GetCatalogueCategoryFeed gdoc
@@ -97,10 +138,19 @@ func (app *application) getProductFeedHandler(w http.ResponseWriter, r *http.Req
 //	@Produce		json
 //	@Param			limit		query		int		false	"limit"
 //	@Param			offset		query		int		false	"offset"
-//	@Param			sort		query		string	false	"sort"
+//	@Param			cursor		query		string	false	"next_cursor or prev_cursor from the previous page, overrides offset"
+//	@Param			sort		query		string	false	"sort: relevance, newest, price_asc, price_desc, rating, best_selling (asc or desc keep the default order)"
 //	@Param			category	query		string	false	"category"
 //	@Param			search		query		string	false	"search"
-//	@Success		200			{object}	[]store.Product
+//	@Param			min_price	query		number	false	"minimum effective price"
+//	@Param			max_price	query		number	false	"maximum effective price"
+//	@Param			rating		query		int		false	"minimum average rating"
+//	@Param			country		query		string	false	"toko country"
+//	@Param			discount	query		number	false	"minimum discount percent"
+//	@Param			in_stock	query		bool	false	"only products in stock"
+//	@Param			since		query		string	false	"created since (YYYY-MM-DD HH:MM:SS)"
+//	@Param			until		query		string	false	"created until (YYYY-MM-DD HH:MM:SS)"
+//	@Success		200			{object}	ProductFeed
 //	@Failure		400			{object}	error
 //	@Failure		500			{object}	error
 //	@Router			/catalogue [get]
@@ -113,32 +163,55 @@ func (app *application) getProductCategoryFeed(w http.ResponseWriter, r *http.Re
 		Search:   "",
 	}
 
-	fq, err := fq.Parse(r)
-	if err != nil {
-		app.badRequestResponse(w, r, err)
-		return
-	}
-
-	if err := Validate.Struct(fq); err != nil {
-		app.badRequestResponse(w, r, err)
+	fq, ok := app.readFeedQuery(w, r, fq)
+	if !ok {
 		return
 	}
 
 	ctx := r.Context()
 
-	var products []*store.Product
+	var (
+		products *store.Paginated[*store.Product]
+		err      error
+	)
 	if fq.Category == "" && fq.Search == "" && fq.Offset == 0 {
 		products, err = app.store.Products.GetAllProduct(ctx, fq)
 	} else {
 		products, err = app.store.Products.GetProductCategoryFeed(ctx, fq)
 	}
 
+	if err != nil {
+		app.listErrorResponse(w, r, err)
+		return
+	}
+
+	app.productFeedResponse(w, r, products, store.NewProductFilter(fq))
+}
+
+// ProductFeed adalah halaman produk katalog beserta jumlah produk per pilihan filter
+type ProductFeed struct {
+	Products   []*store.Product     `json:"products"`
+	Pagination store.CursorPage     `json:"pagination"`
+	Facets     *store.ProductFacets `json:"facets"`
+}
+
+// productFeedResponse mengirim halaman produk bersama facet yang dihitung dari filter yang sama
+func (app *application) productFeedResponse(w http.ResponseWriter, r *http.Request, page *store.Paginated[*store.Product], filter store.ProductFilter) {
+	facets, err := app.store.Products.GetProductFacets(r.Context(), filter)
 	if err != nil {
 		app.internalServerError(w, r, err)
 		return
 	}
 
-	if err := app.jsonResponse(w, http.StatusOK, products); err != nil {
+	page.Pagination.Sign(app.cursors)
+
+	feed := ProductFeed{
+		Products:   page.Data,
+		Pagination: page.Pagination,
+		Facets:     facets,
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, feed); err != nil {
 		app.internalServerError(w, r, err)
 	}
 }
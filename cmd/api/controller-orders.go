@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"errors"
 	"net/http"
 	"strconv"
@@ -10,6 +11,10 @@ import (
 	"github.com/yogaprasetya22/api-gotokopedia/internal/store"
 )
 
+type orderKey string
+
+const orderCtx orderKey = "order"
+
 // Struct untuk request membuat order
 type CreateOrderRequest struct {
 	CartID              uuid.UUID `json:"cart_id"`
@@ -25,6 +30,10 @@ type updateOrderStatusRequest struct {
 	Notes    string `json:"notes,omitempty"`
 }
 
+// Struct untuk request pembatalan / refund order
+type OrderNotesPayload struct {
+	Notes string `json:"notes" validate:"max=255"`
+}
 
 // getOrderHandler godoc
 //
@@ -42,15 +51,8 @@ type updateOrderStatusRequest struct {
 //	@Router			/order/{id} [get]
 //	@Security		ApiKeyAuth
 func (app *application) getOrderHandler(w http.ResponseWriter, r *http.Request) {
-	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
-	if err != nil {
-		app.badRequestResponse(w, r, errors.New("invalid order ID"))
-		return
-	}
-
 	user := getUserFromContext(r)
-
-	order, err := app.store.Orders.GetByID(r.Context(), id)
+	order := getOrderFromContext(r)
 
 	// Pastikan order milik user yang terautentikasi
 	if order.UserID != user.ID {
@@ -58,16 +60,6 @@ func (app *application) getOrderHandler(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	if err != nil {
-		switch {
-		case errors.Is(err, store.ErrNotFound):
-			app.notFoundResponse(w, r, err)
-		default:
-			app.internalServerError(w, r, err)
-		}
-		return
-	}
-
 	if err := app.jsonResponse(w, http.StatusOK, order); err != nil {
 		app.internalServerError(w, r, err)
 		return
@@ -83,9 +75,10 @@ func (app *application) getOrderHandler(w http.ResponseWriter, r *http.Request)
 //	@Produce		json
 //	@Param			limit	query		int		false	"Number of orders to return (default 5)"
 //	@Param			offset	query		int		false	"Offset for pagination (default 0)"
+//	@Param			cursor	query		string	false	"next_cursor or prev_cursor from the previous page, overrides offset"
 //	@Param			sort	query		string	false	"Sort order (asc or desc, default desc)"
 //	@Param			search	query		string	false	"Search term for order details"
-//	@Success		200		{object}	[]store.Order
+//	@Success		200		{object}	store.Paginated[store.Order]
 //	@Failure		400		{object}	error
 //	@Failure		404		{object}	error
 //	@Failure		500		{object}	error
@@ -99,34 +92,214 @@ func (app *application) listOrdersHandler(w http.ResponseWriter, r *http.Request
 		Search: "",
 	}
 
-	fq, err := fq.Parse(r)
+	fq, ok := app.readFeedQuery(w, r, fq)
+	if !ok {
+		return
+	}
+
+	user := getUserFromContext(r)
+
+	orders, err := app.store.Orders.ListByUserID(r.Context(), user.ID, fq)
 	if err != nil {
-		app.badRequestResponse(w, r, err)
+		app.listErrorResponse(w, r, err)
+		return
+	}
+
+	orders.Pagination.Sign(app.cursors)
+
+	if err := app.jsonResponse(w, http.StatusOK, orders); err != nil {
+		app.internalServerError(w, r, err)
 		return
 	}
+}
 
-	if err := Validate.Struct(fq); err != nil {
+// CancelOrder godoc
+//
+//	@Summary		Cancel order
+//	@Description	Cancel an order owned by the authenticated user while it is still Pending or Processing
+//	@Tags			order
+//	@Accept			json
+//	@Produce		json
+//	@Param			id		path		int					true	"Order ID"
+//	@Param			payload	body		OrderNotesPayload	false	"Cancellation notes"
+//	@Success		200		{object}	store.Order
+//	@Failure		400		{object}	error
+//	@Failure		403		{object}	error
+//	@Failure		404		{object}	error
+//	@Failure		409		{object}	error
+//	@Failure		500		{object}	error
+//	@Router			/order/{id}/cancel [patch]
+//	@Security		ApiKeyAuth
+func (app *application) cancelOrderHandler(w http.ResponseWriter, r *http.Request) {
+	user := getUserFromContext(r)
+	order := getOrderFromContext(r)
+
+	if order.UserID != user.ID {
+		app.forbiddenResponse(w, r)
+		return
+	}
+
+	payload, err := app.readOrderNotesPayload(w, r)
+	if err != nil {
 		app.badRequestResponse(w, r, err)
 		return
 	}
 
+	notes := payload.Notes
+	if notes == "" {
+		notes = "Order cancelled by buyer"
+	}
+
+	ctx := r.Context()
+	if err := app.store.Orders.Cancel(ctx, order.ID, user.ID, notes); err != nil {
+		app.orderStatusErrorResponse(w, r, err)
+		return
+	}
+
+	// Tagihan order yang belum dibayar dibatalkan di gateway
+	if order.StatusID == store.OrderStatusPending {
+		app.cancelOrderCharge(ctx, order.OrderNumber)
+	}
+
+	app.respondWithOrder(w, r, order.ID)
+}
+
+// RefundOrder godoc
+//
+//	@Summary		Refund order
+//	@Description	Refund an order, only allowed for the seller of the order items or an admin
+//	@Tags			order
+//	@Accept			json
+//	@Produce		json
+//	@Param			id		path		int					true	"Order ID"
+//	@Param			payload	body		OrderNotesPayload	false	"Refund notes"
+//	@Success		200		{object}	store.Order
+//	@Failure		400		{object}	error
+//	@Failure		403		{object}	error
+//	@Failure		404		{object}	error
+//	@Failure		409		{object}	error
+//	@Failure		500		{object}	error
+//	@Router			/order/{id}/refund [patch]
+//	@Security		ApiKeyAuth
+func (app *application) refundOrderHandler(w http.ResponseWriter, r *http.Request) {
 	user := getUserFromContext(r)
+	order := getOrderFromContext(r)
 
-	orders, err := app.store.Orders.GetByUserID(r.Context(), user.ID, fq)
+	ctx := r.Context()
+	role, err := app.orderManagerRole(ctx, user, order)
 	if err != nil {
-		switch {
-		case errors.Is(err, store.ErrNotFound):
-			app.notFoundResponse(w, r, err)
-		default:
-			app.internalServerError(w, r, err)
-		}
+		app.internalServerError(w, r, err)
 		return
 	}
 
-	if err := app.jsonResponse(w, http.StatusOK, orders); err != nil {
+	if role == "" {
+		app.forbiddenResponse(w, r)
+		return
+	}
+
+	payload, err := app.readOrderNotesPayload(w, r)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	notes := payload.Notes
+	if notes == "" {
+		notes = "Order refunded"
+	}
+
+	actor := store.OrderActor{UserID: user.ID, Role: role}
+	if err := app.store.Orders.Refund(ctx, order.ID, notes, actor); err != nil {
+		app.orderStatusErrorResponse(w, r, err)
+		return
+	}
+
+	app.respondWithOrder(w, r, order.ID)
+}
+
+// readOrderNotesPayload membaca body opsional berisi catatan perubahan status
+func (app *application) readOrderNotesPayload(w http.ResponseWriter, r *http.Request) (OrderNotesPayload, error) {
+	var payload OrderNotesPayload
+
+	if r.ContentLength != 0 {
+		if err := readJSON(w, r, &payload); err != nil {
+			return payload, err
+		}
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		return payload, err
+	}
+
+	return payload, nil
+}
+
+// orderManagerRole mengembalikan peran user terhadap order (pemilik toko atau admin), string kosong jika tidak berhak
+func (app *application) orderManagerRole(ctx context.Context, user *store.User, order *store.Order) (string, error) {
+	for _, item := range order.Items {
+		if item.Toko != nil && item.Toko.UserID == user.ID {
+			return store.OrderActorSeller, nil
+		}
+	}
+
+	isAdmin, err := app.checkRolePrecedence(ctx, user, "admin")
+	if err != nil || !isAdmin {
+		return "", err
+	}
+
+	return store.OrderActorAdmin, nil
+}
+
+func (app *application) orderStatusErrorResponse(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case errors.Is(err, store.ErrNotFound):
+		app.notFoundResponse(w, r, err)
+	case errors.Is(err, store.ErrInvalidOrderTransition), errors.Is(err, store.ErrOrderNotPaid):
+		app.conflictResponse(w, r, err)
+	default:
+		app.internalServerError(w, r, err)
+	}
+}
+
+func (app *application) respondWithOrder(w http.ResponseWriter, r *http.Request, orderID int64) {
+	order, err := app.store.Orders.GetByID(r.Context(), orderID)
+	if err != nil {
 		app.internalServerError(w, r, err)
 		return
 	}
+
+	if err := app.jsonResponse(w, http.StatusOK, order); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+func (app *application) orderContextMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+		if err != nil {
+			app.badRequestResponse(w, r, errors.New("invalid order ID"))
+			return
+		}
+
+		ctx := r.Context()
+		order, err := app.store.Orders.GetByID(ctx, id)
+		if err != nil {
+			switch {
+			case errors.Is(err, store.ErrNotFound):
+				app.notFoundResponse(w, r, err)
+			default:
+				app.internalServerError(w, r, err)
+			}
+			return
+		}
+
+		ctx = context.WithValue(ctx, orderCtx, order)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+func getOrderFromContext(r *http.Request) *store.Order {
+	return r.Context().Value(orderCtx).(*store.Order)
 }
 
 // Handler untuk update status order
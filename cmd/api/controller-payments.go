@@ -0,0 +1,169 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/yogaprasetya22/api-gotokopedia/internal/payment"
+	"github.com/yogaprasetya22/api-gotokopedia/internal/store"
+)
+
+const maxCallbackBytes = 1_048_576 // 1mb
+
+// CheckoutPayment adalah pembayaran yang dibuat untuk satu order beserta instruksi dari gateway
+// CheckoutPayment adalah hasil pembuatan tagihan satu order, Error terisi jika tagihan gagal dibuat dan order dibatalkan
+type CheckoutPayment struct {
+	OrderID     int64           `json:"order_id"`
+	OrderNumber string          `json:"order_number"`
+	Payment     *store.Payment  `json:"payment,omitempty"`
+	Charge      *payment.Charge `json:"charge,omitempty"`
+	Error       string          `json:"error,omitempty"`
+}
+
+// errPaymentNotCreated adalah pesan untuk pembeli ketika tagihan order gagal dibuat
+const errPaymentNotCreated = "payment could not be created, order cancelled"
+
+// createOrderPayment membuat tagihan di gateway untuk pembayaran pending order. Jika tagihan gagal dibuat,
+// order dibatalkan dan stoknya dikembalikan agar tidak ada order yang tidak bisa dibayar.
+func (app *application) createOrderPayment(ctx context.Context, user *store.User, order *store.Order) (*CheckoutPayment, error) {
+	charge, err := app.paymentGateway.CreateCharge(ctx, payment.ChargeRequest{
+		OrderNumber:   order.OrderNumber,
+		Amount:        order.FinalPrice,
+		MethodCode:    order.PaymentMethod.MidtransCode,
+		CustomerName:  user.Username,
+		CustomerEmail: user.Email,
+	})
+	if err != nil {
+		if failErr := app.store.Payments.FailCharge(ctx, order.ID, "Order cancelled: payment could not be created"); failErr != nil {
+			return nil, errors.Join(err, failErr)
+		}
+		return nil, err
+	}
+
+	p, err := app.store.Payments.AttachCharge(ctx, order.ID, charge.TransactionID)
+	if err != nil {
+		app.cancelOrderCharge(ctx, order.OrderNumber)
+		if failErr := app.store.Payments.FailCharge(ctx, order.ID, "Order cancelled: payment could not be created"); failErr != nil {
+			return nil, errors.Join(err, failErr)
+		}
+		return nil, err
+	}
+
+	return &CheckoutPayment{
+		OrderID:     order.ID,
+		OrderNumber: order.OrderNumber,
+		Payment:     p,
+		Charge:      charge,
+	}, nil
+}
+
+// cancelOrderCharge membatalkan tagihan gateway order yang sudah dibatalkan agar tidak bisa dibayar lagi.
+// Tagihan yang sudah dibayar tidak bisa dibatalkan, callback-nya akan menandai pembayaran refund_required.
+func (app *application) cancelOrderCharge(ctx context.Context, orderNumber string) {
+	err := app.paymentGateway.CancelCharge(ctx, orderNumber)
+	if err != nil && !errors.Is(err, payment.ErrChargeNotFound) {
+		app.logger.Warnw("failed to cancel payment charge", "order_number", orderNumber, "error", err.Error())
+	}
+}
+
+// paymentCallbackHandler godoc
+//
+//	@Summary		Payment gateway callback
+//	@Description	Receive transaction status notification from the payment gateway. The signature_key is verified with the server key and the payment is looked up by the signed order_id, retries of the same notification are ignored.
+//	@Tags			payment
+//	@Accept			json
+//	@Produce		json
+//	@Param			payload	body		payment.Notification	true	"Gateway notification"
+//	@Success		200		{object}	store.Payment
+//	@Failure		400		{object}	error
+//	@Failure		401		{object}	error
+//	@Failure		404		{object}	error
+//	@Failure		500		{object}	error
+//	@Router			/payments/callback [post]
+func (app *application) paymentCallbackHandler(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxCallbackBytes))
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	notification, err := app.paymentGateway.VerifyCallback(body)
+	if err != nil {
+		switch {
+		case errors.Is(err, payment.ErrInvalidSignature):
+			app.unauthorizedErrorResponse(w, r, err)
+		default:
+			app.badRequestResponse(w, r, err)
+		}
+		return
+	}
+
+	// Signature hanya mencakup order_id, pembayaran dicari dari nomor order dan transaction_id harus milik pembayaran tersebut
+	ctx := r.Context()
+	p, err := app.store.Payments.GetByOrderNumber(ctx, notification.OrderNumber)
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.notFoundResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	grossAmount, err := strconv.ParseFloat(notification.GrossAmount, 64)
+	if err != nil || store.MoneyFromMajor(grossAmount).Cmp(p.Amount) != 0 {
+		app.badRequestResponse(w, r, fmt.Errorf("gross_amount %q does not match payment amount", notification.GrossAmount))
+		return
+	}
+
+	if p.TransactionID == "" || p.TransactionID != notification.TransactionID {
+		app.badRequestResponse(w, r, fmt.Errorf("transaction_id %q does not belong to order %s", notification.TransactionID, notification.OrderNumber))
+		return
+	}
+
+	// Notifikasi yang dikirim ulang atau terlambat setelah lunas tidak mengubah apa pun
+	status := paymentStatusFromNotification(notification)
+	if status == store.PaymentStatusPending || status == p.Status || p.IsSettled() {
+		if err := app.jsonResponse(w, http.StatusOK, p); err != nil {
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	p, err = app.store.Payments.UpdateStatus(ctx, notification.TransactionID, status)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if p.Status == store.PaymentStatusRefundRequired {
+		app.logger.Warnw("payment received for a closed order, refund required",
+			"order_number", notification.OrderNumber, "transaction_id", p.TransactionID, "amount", p.Amount.Major())
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, p); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// paymentStatusFromNotification memetakan transaction_status gateway ke status payments
+func paymentStatusFromNotification(n *payment.Notification) string {
+	if n.IsPaid() {
+		return store.PaymentStatusPaid
+	}
+
+	switch n.TransactionStatus {
+	case payment.StatusExpire:
+		return store.PaymentStatusExpired
+	case payment.StatusCancel, payment.StatusDeny:
+		return store.PaymentStatusFailed
+	default:
+		// Termasuk capture dengan fraud_status challenge yang masih menunggu review
+		return store.PaymentStatusPending
+	}
+}
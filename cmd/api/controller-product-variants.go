@@ -0,0 +1,144 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/yogaprasetya22/api-gotokopedia/internal/store"
+)
+
+type ProductOptionPayload struct {
+	Name   string   `json:"name" validate:"required,max=50"`
+	Values []string `json:"values" validate:"required,min=1,dive,required,max=100"`
+}
+
+type ProductVariantPayload struct {
+	SKU           string            `json:"sku" validate:"required,max=100"`
+	Options       map[string]string `json:"options" validate:"required,min=1"`
+	Price         float64           `json:"price" validate:"gt=0"`
+	DiscountPrice float64           `json:"discount_price" validate:"gte=0"`
+	Stock         int               `json:"stock" validate:"gte=0"`
+	ImageURL      string            `json:"image_url" validate:"omitempty,url"`
+	IsActive      *bool             `json:"is_active"`
+}
+
+type ReplaceProductVariantsPayload struct {
+	Options  []ProductOptionPayload  `json:"options" validate:"dive"`
+	Variants []ProductVariantPayload `json:"variants" validate:"dive"`
+}
+
+// toMatrix mengubah payload menjadi matriks varian, varian aktif jika is_active tidak diisi
+func (p ReplaceProductVariantsPayload) toMatrix() *store.VariantMatrix {
+	matrix := &store.VariantMatrix{
+		Options:  make([]*store.ProductOptionType, 0, len(p.Options)),
+		Variants: make([]*store.ProductVariant, 0, len(p.Variants)),
+	}
+
+	for _, o := range p.Options {
+		option := &store.ProductOptionType{Name: o.Name}
+		for _, v := range o.Values {
+			option.Values = append(option.Values, &store.ProductOptionValue{Value: v})
+		}
+		matrix.Options = append(matrix.Options, option)
+	}
+
+	for _, v := range p.Variants {
+		isActive := true
+		if v.IsActive != nil {
+			isActive = *v.IsActive
+		}
+
+		matrix.Variants = append(matrix.Variants, &store.ProductVariant{
+			SKU:           v.SKU,
+			Options:       v.Options,
+			Price:         store.MoneyFromMajor(v.Price),
+			DiscountPrice: store.MoneyFromMajor(v.DiscountPrice),
+			Stock:         v.Stock,
+			ImageURL:      v.ImageURL,
+			IsActive:      isActive,
+		})
+	}
+
+	return matrix
+}
+
+// GetProductVariants godoc
+//
+//	@Summary		Get product variants
+//	@Description	Get option types and the variant matrix (SKU, price, stock, image) of a product
+//	@Tags			product
+//	@Accept			json
+//	@Produce		json
+//	@Param			productID	path		int	true	"product ID"
+//	@Success		200			{object}	store.VariantMatrix
+//	@Failure		404			{object}	error
+//	@Failure		500			{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/product/{productID}/variants [get]
+func (app *application) getProductVariantsHandler(w http.ResponseWriter, r *http.Request) {
+	product := getProductFromContext(r)
+
+	matrix, err := app.store.ProductVariants.GetByProductID(r.Context(), product.ID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, matrix); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// ReplaceProductVariants godoc
+//
+//	@Summary		Replace product variants
+//	@Description	Replace the option types and variant matrix of a product. Variants are matched by SKU so existing variant IDs are kept, variants missing from the payload are removed
+//	@Tags			product
+//	@Accept			json
+//	@Produce		json
+//	@Param			productID	path		int								true	"product ID"
+//	@Param			payload		body		ReplaceProductVariantsPayload	true	"variant matrix"
+//	@Success		200			{object}	store.VariantMatrix
+//	@Failure		400			{object}	error
+//	@Failure		403			{object}	error
+//	@Failure		409			{object}	error
+//	@Failure		500			{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/product/{productID}/variants [put]
+func (app *application) replaceProductVariantsHandler(w http.ResponseWriter, r *http.Request) {
+	product := getProductFromContext(r)
+
+	var payload ReplaceProductVariantsPayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	ctx := r.Context()
+	if err := app.store.ProductVariants.Replace(ctx, product.ID, payload.toMatrix()); err != nil {
+		switch {
+		case errors.Is(err, store.ErrInvalidVariant):
+			app.badRequestResponse(w, r, err)
+		case errors.Is(err, store.ErrConflict):
+			app.conflictResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	matrix, err := app.store.ProductVariants.GetByProductID(ctx, product.ID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, matrix); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
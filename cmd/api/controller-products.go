@@ -30,6 +30,10 @@ type CreateProductPayload struct {
 	ImageUrls     []string `json:"image_urls" validate:"required"`
 	TokoID        int64    `json:"toko_id" validate:"required"`
 	CategoryID    int64    `json:"category_id" validate:"required"`
+	WeightGram    int      `json:"weight_gram" validate:"required,gt=0"`
+	LengthCm      int      `json:"length_cm" validate:"omitempty,gte=0"`
+	WidthCm       int      `json:"width_cm" validate:"omitempty,gte=0"`
+	HeightCm      int      `json:"height_cm" validate:"omitempty,gte=0"`
 }
 
 // CreateProduct gdoc
@@ -65,8 +69,8 @@ func (app *application) createProductHandler(w http.ResponseWriter, r *http.Requ
 		Name:          payload.Name,
 		Slug:          payload.Slug,
 		Description:   payload.Description,
-		Price:         payload.Price,
-		DiscountPrice: payload.DiscountPrice,
+		Price:         store.MoneyFromMajor(payload.Price),
+		DiscountPrice: store.MoneyFromMajor(payload.DiscountPrice),
 		Discount:      payload.Discount,
 		Rating:        payload.Rating,
 		Estimation:    payload.Estimation,
@@ -75,6 +79,10 @@ func (app *application) createProductHandler(w http.ResponseWriter, r *http.Requ
 		IsForSale:     payload.IsForSale,
 		IsApproved:    payload.IsApproved,
 		ImageUrls:     payload.ImageUrls,
+		WeightGram:    payload.WeightGram,
+		LengthCm:      payload.LengthCm,
+		WidthCm:       payload.WidthCm,
+		HeightCm:      payload.HeightCm,
 		Category: &store.Category{
 			ID: payload.CategoryID,
 		},
@@ -108,6 +116,10 @@ type UpdateProductPayload struct {
 	IsApproved    *bool     `json:"is_approved" validate:"omitempty"`
 	ImageUrls     *[]string `json:"image_urls" validate:"omitempty"`
 	Version       *int      `json:"version"`
+	WeightGram    *int      `json:"weight_gram" validate:"omitempty,gt=0"`
+	LengthCm      *int      `json:"length_cm" validate:"omitempty,gte=0"`
+	WidthCm       *int      `json:"width_cm" validate:"omitempty,gte=0"`
+	HeightCm      *int      `json:"height_cm" validate:"omitempty,gte=0"`
 }
 
 // UpdateProduct godoc
@@ -153,10 +165,10 @@ func (app *application) updateProductHandler(w http.ResponseWriter, r *http.Requ
 		product.Description = *payload.Description
 	}
 	if payload.Price != nil {
-		product.Price = *payload.Price
+		product.Price = store.MoneyFromMajor(*payload.Price)
 	}
 	if payload.DiscountPrice != nil {
-		product.DiscountPrice = *payload.DiscountPrice
+		product.DiscountPrice = store.MoneyFromMajor(*payload.DiscountPrice)
 	}
 	if payload.Discount != nil {
 		product.Discount = *payload.Discount
@@ -182,6 +194,18 @@ func (app *application) updateProductHandler(w http.ResponseWriter, r *http.Requ
 	if payload.ImageUrls != nil {
 		product.ImageUrls = *payload.ImageUrls
 	}
+	if payload.WeightGram != nil {
+		product.WeightGram = *payload.WeightGram
+	}
+	if payload.LengthCm != nil {
+		product.LengthCm = *payload.LengthCm
+	}
+	if payload.WidthCm != nil {
+		product.WidthCm = *payload.WidthCm
+	}
+	if payload.HeightCm != nil {
+		product.HeightCm = *payload.HeightCm
+	}
 
 	if err := app.updateProduct(r.Context(), product); err != nil {
 		app.internalServerError(w, r, err)
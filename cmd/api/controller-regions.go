@@ -0,0 +1,96 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/yogaprasetya22/api-gotokopedia/internal/store"
+)
+
+// listProvincesHandler godoc
+//
+//	@Summary		List provinces
+//	@Description	Get all provinces, the first level of the cascading region dropdown
+//	@Tags			region
+//	@Accept			json
+//	@Produce		json
+//	@Success		200	{array}		store.Region
+//	@Failure		500	{object}	error
+//	@Router			/regions [get]
+func (app *application) listProvincesHandler(w http.ResponseWriter, r *http.Request) {
+	regions, err := app.store.Regions.GetChildren(r.Context(), "")
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, regions); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// getRegionHandler godoc
+//
+//	@Summary		Get region
+//	@Description	Get a region by its code
+//	@Tags			region
+//	@Accept			json
+//	@Produce		json
+//	@Param			id	path		string	true	"Region code"
+//	@Success		200	{object}	store.Region
+//	@Failure		404	{object}	error
+//	@Failure		500	{object}	error
+//	@Router			/regions/{id} [get]
+func (app *application) getRegionHandler(w http.ResponseWriter, r *http.Request) {
+	region, err := app.store.Regions.GetByID(r.Context(), chi.URLParam(r, "id"))
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.notFoundResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, region); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// listRegionChildrenHandler godoc
+//
+//	@Summary		List child regions
+//	@Description	Get the cities of a province, the districts of a city or the sub-districts (with postal code) of a district
+//	@Tags			region
+//	@Accept			json
+//	@Produce		json
+//	@Param			id	path		string	true	"Parent region code"
+//	@Success		200	{array}		store.Region
+//	@Failure		404	{object}	error
+//	@Failure		500	{object}	error
+//	@Router			/regions/{id}/children [get]
+func (app *application) listRegionChildrenHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	parent, err := app.store.Regions.GetByID(ctx, chi.URLParam(r, "id"))
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.notFoundResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	regions, err := app.store.Regions.GetChildren(ctx, parent.ID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, regions); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
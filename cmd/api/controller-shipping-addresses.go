@@ -1,9 +1,13 @@
 package main
 
 import (
+	"context"
 	"errors"
+	"fmt"
 	"net/http"
 
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
 	"github.com/yogaprasetya22/api-gotokopedia/internal/store"
 )
 
@@ -79,18 +83,28 @@ func (app *application) getDefaultShippingAddressHandler(w http.ResponseWriter,
 	}
 }
 
+var errInvalidAddressRegion = errors.New("invalid address region")
+
 type shippingAddressRequest struct {
-	Label          string `json:"label"`
-	RecipientName  string `json:"recipient_name"`
-	RecipientPhone string `json:"recipient_phone"`
-	AddressLine1   string `json:"address_line1"`
-	NoteForCourier string `json:"note_for_courier"`
+	Label          string   `json:"label"`
+	RecipientName  string   `json:"recipient_name"`
+	RecipientPhone string   `json:"recipient_phone"`
+	AddressLine1   string   `json:"address_line1"`
+	NoteForCourier string   `json:"note_for_courier"`
+	RegionID       string   `json:"region_id" validate:"omitempty,max=13"`
+	Province       string   `json:"province" validate:"required_without=RegionID,max=100"`
+	City           string   `json:"city" validate:"required_without=RegionID,max=100"`
+	District       string   `json:"district" validate:"required_without=RegionID,max=100"`
+	SubDistrict    string   `json:"sub_district" validate:"required_without=RegionID,max=100"`
+	PostalCode     string   `json:"postal_code" validate:"required,numeric,len=5"`
+	Latitude       *float64 `json:"latitude" validate:"required_with=Longitude,omitempty,latitude"`
+	Longitude      *float64 `json:"longitude" validate:"required_with=Latitude,omitempty,longitude"`
 }
 
 // CreateShippingAddressHandler godoc
 //
 //	@Summary		Create shipping address
-//	@Description	Create a new shipping address
+//	@Description	Create a new shipping address. region_id is validated against the region table, without it province, city, district and sub_district are required
 //	@Tags			shipping-address
 //	@Accept			json
 //	@Produce		json
@@ -114,7 +128,7 @@ func (app *application) createShippingAddressHandler(w http.ResponseWriter, r *h
 
 	err = Validate.Struct(payload)
 	if err != nil {
-		app.internalServerError(w, r, err)
+		app.badRequestResponse(w, r, err)
 		return
 	}
 
@@ -124,9 +138,26 @@ func (app *application) createShippingAddressHandler(w http.ResponseWriter, r *h
 		RecipientName:  payload.RecipientName,
 		RecipientPhone: payload.RecipientPhone,
 		AddressLine1:   payload.AddressLine1,
+		NoteForCourier: payload.NoteForCourier,
+		Province:       payload.Province,
+		City:           payload.City,
+		District:       payload.District,
+		SubDistrict:    payload.SubDistrict,
+		Latitude:       payload.Latitude,
+		Longitude:      payload.Longitude,
 		IsDefault:      false,
 	}
 
+	if err := app.applyAddressRegion(ctx, address, payload.RegionID, payload.PostalCode); err != nil {
+		switch {
+		case errors.Is(err, errInvalidAddressRegion):
+			app.badRequestResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
 	if err := app.store.ShippingAddresses.Create(ctx, address); err != nil {
 		switch {
 		case errors.Is(err, store.ErrNotFound):
@@ -142,3 +173,264 @@ func (app *application) createShippingAddressHandler(w http.ResponseWriter, r *h
 		app.internalServerError(w, r, err)
 	}
 }
+
+type updateShippingAddressRequest struct {
+	Label          *string  `json:"label" validate:"omitempty,max=100"`
+	RecipientName  *string  `json:"recipient_name" validate:"omitempty,max=100"`
+	RecipientPhone *string  `json:"recipient_phone" validate:"omitempty,max=50"`
+	AddressLine1   *string  `json:"address_line1"`
+	NoteForCourier *string  `json:"note_for_courier"`
+	RegionID       *string  `json:"region_id" validate:"omitempty,max=13"`
+	Province       *string  `json:"province" validate:"omitempty,max=100"`
+	City           *string  `json:"city" validate:"omitempty,max=100"`
+	District       *string  `json:"district" validate:"omitempty,max=100"`
+	SubDistrict    *string  `json:"sub_district" validate:"omitempty,max=100"`
+	PostalCode     *string  `json:"postal_code" validate:"omitempty,numeric,len=5"`
+	Latitude       *float64 `json:"latitude" validate:"required_with=Longitude,omitempty,latitude"`
+	Longitude      *float64 `json:"longitude" validate:"required_with=Latitude,omitempty,longitude"`
+}
+
+// UpdateShippingAddressHandler godoc
+//
+//	@Summary		Update shipping address
+//	@Description	Update a shipping address owned by the authenticated user. Changing region_id or postal_code validates them again
+//	@Tags			shipping-address
+//	@Accept			json
+//	@Produce		json
+//	@Param			id					path		string							true	"Shipping Address ID"
+//	@Param			shipping_address	body		updateShippingAddressRequest	true	"Shipping Address"
+//	@Success		200					{object}	store.ShippingAddresses
+//	@Failure		400					{object}	error
+//	@Failure		404					{object}	error
+//	@Failure		500					{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/shipping-addresses/{id} [patch]
+func (app *application) updateShippingAddressHandler(w http.ResponseWriter, r *http.Request) {
+	address := getShippingAddressFromContext(r)
+
+	var payload updateShippingAddressRequest
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if payload.Label != nil {
+		address.Label = *payload.Label
+	}
+	if payload.RecipientName != nil {
+		address.RecipientName = *payload.RecipientName
+	}
+	if payload.RecipientPhone != nil {
+		address.RecipientPhone = *payload.RecipientPhone
+	}
+	if payload.AddressLine1 != nil {
+		address.AddressLine1 = *payload.AddressLine1
+	}
+	if payload.NoteForCourier != nil {
+		address.NoteForCourier = *payload.NoteForCourier
+	}
+	if payload.Latitude != nil {
+		address.Latitude = payload.Latitude
+		address.Longitude = payload.Longitude
+	}
+
+	// Nama wilayah hanya bisa diisi manual untuk alamat tanpa region_id, selain itu diambil dari tabel regions
+	if address.RegionID == "" && (payload.RegionID == nil || *payload.RegionID == "") {
+		if payload.Province != nil {
+			address.Province = *payload.Province
+		}
+		if payload.City != nil {
+			address.City = *payload.City
+		}
+		if payload.District != nil {
+			address.District = *payload.District
+		}
+		if payload.SubDistrict != nil {
+			address.SubDistrict = *payload.SubDistrict
+		}
+	}
+
+	ctx := r.Context()
+	if payload.RegionID != nil || payload.PostalCode != nil {
+		regionID, postalCode := address.RegionID, address.PostalCode
+		if payload.RegionID != nil {
+			regionID = *payload.RegionID
+		}
+		if payload.PostalCode != nil {
+			postalCode = *payload.PostalCode
+		}
+
+		// Koordinat lama tidak berlaku lagi jika wilayah berubah tanpa koordinat baru
+		if regionID != address.RegionID && payload.Latitude == nil {
+			address.Latitude, address.Longitude = nil, nil
+		}
+
+		if err := app.applyAddressRegion(ctx, address, regionID, postalCode); err != nil {
+			switch {
+			case errors.Is(err, errInvalidAddressRegion):
+				app.badRequestResponse(w, r, err)
+			default:
+				app.internalServerError(w, r, err)
+			}
+			return
+		}
+	}
+
+	if err := app.store.ShippingAddresses.Update(ctx, address); err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.notFoundResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, address); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// DeleteShippingAddressHandler godoc
+//
+//	@Summary		Delete shipping address
+//	@Description	Delete a shipping address owned by the authenticated user. Addresses used by orders are soft deleted and another address is promoted when the default address is deleted
+//	@Tags			shipping-address
+//	@Accept			json
+//	@Produce		json
+//	@Param			id	path		string	true	"Shipping Address ID"
+//	@Success		204	{object}	string
+//	@Failure		404	{object}	error
+//	@Failure		500	{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/shipping-addresses/{id} [delete]
+func (app *application) deleteShippingAddressHandler(w http.ResponseWriter, r *http.Request) {
+	address := getShippingAddressFromContext(r)
+
+	if err := app.store.ShippingAddresses.Delete(r.Context(), address.ID, address.UserID); err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.notFoundResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// SetDefaultShippingAddressHandler godoc
+//
+//	@Summary		Set default shipping address
+//	@Description	Make a shipping address owned by the authenticated user the default address
+//	@Tags			shipping-address
+//	@Accept			json
+//	@Produce		json
+//	@Param			id	path		string	true	"Shipping Address ID"
+//	@Success		200	{object}	store.ShippingAddresses
+//	@Failure		404	{object}	error
+//	@Failure		500	{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/shipping-addresses/{id}/default [put]
+func (app *application) setDefaultShippingAddressHandler(w http.ResponseWriter, r *http.Request) {
+	address := getShippingAddressFromContext(r)
+
+	if err := app.store.ShippingAddresses.SetDefaultAddress(r.Context(), address.ID, address.UserID); err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.notFoundResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	address.IsDefault = true
+	if err := app.jsonResponse(w, http.StatusOK, address); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// shippingAddressContextMiddleware memuat alamat milik user yang login, alamat user lain dianggap tidak ada
+func (app *application) shippingAddressContextMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id, err := uuid.Parse(chi.URLParam(r, "id"))
+		if err != nil {
+			app.badRequestResponse(w, r, errors.New("invalid shipping address ID"))
+			return
+		}
+
+		user := getUserFromContext(r)
+		ctx := r.Context()
+		address, err := app.store.ShippingAddresses.GetByID(ctx, id, user.ID)
+		if err != nil {
+			switch {
+			case errors.Is(err, store.ErrNotFound):
+				app.notFoundResponse(w, r, err)
+			default:
+				app.internalServerError(w, r, err)
+			}
+			return
+		}
+
+		ctx = context.WithValue(ctx, ShippingAddresContext, address)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+func getShippingAddressFromContext(r *http.Request) *store.ShippingAddresses {
+	return r.Context().Value(ShippingAddresContext).(*store.ShippingAddresses)
+}
+
+// applyAddressRegion memvalidasi kelurahan dan kode pos alamat lalu mengisi nama wilayahnya.
+// Jika koordinat tidak dikirim, dipakai koordinat wilayah terdalam yang tersedia untuk perhitungan ongkir.
+// Selama data wilayah belum lengkap, alamat tanpa region_id tetap diterima dengan nama wilayah dari request
+func (app *application) applyAddressRegion(ctx context.Context, address *store.ShippingAddresses, regionID, postalCode string) error {
+	if regionID == "" {
+		address.RegionID = ""
+		address.PostalCode = postalCode
+		return nil
+	}
+
+	path, err := app.store.Regions.GetPath(ctx, regionID)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			return fmt.Errorf("%w: region %s not found", errInvalidAddressRegion, regionID)
+		}
+		return err
+	}
+
+	subDistrict := path[len(path)-1]
+	if subDistrict.Level != store.RegionLevelSubDistrict || len(path) != store.RegionLevelSubDistrict {
+		return fmt.Errorf("%w: region %s is not a sub-district", errInvalidAddressRegion, regionID)
+	}
+
+	if subDistrict.PostalCode != "" && subDistrict.PostalCode != postalCode {
+		return fmt.Errorf("%w: postal code %s does not match %s", errInvalidAddressRegion, postalCode, subDistrict.Name)
+	}
+
+	address.RegionID = subDistrict.ID
+	address.Province = path[0].Name
+	address.City = path[1].Name
+	address.District = path[2].Name
+	address.SubDistrict = subDistrict.Name
+	address.PostalCode = postalCode
+
+	if address.Latitude == nil || address.Longitude == nil {
+		for i := len(path) - 1; i >= 0; i-- {
+			if path[i].Latitude != nil {
+				address.Latitude = path[i].Latitude
+				address.Longitude = path[i].Longitude
+				break
+			}
+		}
+	}
+
+	return nil
+}
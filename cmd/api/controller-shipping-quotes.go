@@ -0,0 +1,227 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/yogaprasetya22/api-gotokopedia/internal/shipping"
+	"github.com/yogaprasetya22/api-gotokopedia/internal/store"
+)
+
+type ShippingQuotePayload struct {
+	ShippingAddressID string `json:"shipping_address_id" validate:"required,uuid"`
+}
+
+// StoreShippingQuote adalah ongkir satu cart store (satu toko) untuk satu shipping method
+type StoreShippingQuote struct {
+	CartStoreID uuid.UUID       `json:"cart_store_id"`
+	TokoID      int64           `json:"toko_id"`
+	Cost        store.Money     `json:"cost"`
+	Available   bool            `json:"available"`
+	FlatRate    bool            `json:"flat_rate"` // lokasi atau tabel tarif belum ada, memakai harga flat shipping method
+	Estimated   bool            `json:"estimated"` // jarak dari titik tengah wilayah alamat atau harga flat, bukan koordinat alamat
+	Quote       *shipping.Quote `json:"quote,omitempty"`
+}
+
+// ShippingMethodQuote adalah total ongkir seluruh cart store di session untuk satu shipping method
+type ShippingMethodQuote struct {
+	ShippingMethod *store.ShippingMethod `json:"shipping_method"`
+	Available      bool                  `json:"available"`
+	Estimated      bool                  `json:"estimated"` // minimal satu toko memakai ongkir perkiraan
+	TotalCost      store.Money           `json:"total_cost"`
+	Stores         []*StoreShippingQuote `json:"stores"`
+}
+
+// ShippingCosts mengembalikan ongkir per cart store untuk disimpan ke checkout session
+func (q *ShippingMethodQuote) ShippingCosts() map[uuid.UUID]store.Money {
+	costs := make(map[uuid.UUID]store.Money, len(q.Stores))
+	for _, s := range q.Stores {
+		costs[s.CartStoreID] = s.Cost
+	}
+	return costs
+}
+
+// quoteShipping menghitung ongkir setiap shipping method untuk cart store di checkout session ke alamat tujuan
+func (app *application) quoteShipping(ctx context.Context, session *store.CheckoutSession, address *store.ShippingAddresses, methods []*store.ShippingMethod) ([]*ShippingMethodQuote, error) {
+	cartStoreIDs := make([]uuid.UUID, 0, len(session.CartStore))
+	for _, cs := range session.CartStore {
+		cartStoreIDs = append(cartStoreIDs, cs.ID)
+	}
+
+	parcels, err := app.store.ShippingRates.GetCartStoreParcels(ctx, cartStoreIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	methodIDs := make([]int64, 0, len(methods))
+	for _, m := range methods {
+		methodIDs = append(methodIDs, m.ID)
+	}
+
+	rates, err := app.store.ShippingRates.GetByMethodIDs(ctx, methodIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	destination, estimated, err := app.shippingDestination(ctx, address)
+	if err != nil {
+		return nil, err
+	}
+
+	quotes := make([]*ShippingMethodQuote, 0, len(methods))
+	for _, m := range methods {
+		mq := &ShippingMethodQuote{
+			ShippingMethod: m,
+			Available:      true,
+			Stores:         make([]*StoreShippingQuote, 0, len(parcels)),
+		}
+
+		for _, parcel := range parcels {
+			sq := &StoreShippingQuote{
+				CartStoreID: parcel.CartStoreID,
+				TokoID:      parcel.TokoID,
+				Available:   true,
+			}
+
+			quote, err := shipping.Calculate(rates[m.ID], shipping.Shipment{
+				Origin:      parcel.Origin,
+				Destination: destination,
+				Items:       parcel.Items,
+			})
+			switch {
+			case err == nil:
+				sq.Cost = store.IDR(quote.Cost)
+				sq.Quote = quote
+				sq.Estimated = estimated
+			case errors.Is(err, shipping.ErrUnknownLocation) || len(rates[m.ID]) == 0:
+				sq.Cost = m.Price
+				sq.FlatRate = true
+				sq.Estimated = true
+			case errors.Is(err, shipping.ErrNoRate):
+				sq.Available = false
+				mq.Available = false
+			default:
+				return nil, err
+			}
+
+			mq.Estimated = mq.Estimated || sq.Estimated
+			mq.TotalCost = mq.TotalCost.Add(sq.Cost)
+			mq.Stores = append(mq.Stores, sq)
+		}
+
+		if !mq.Available {
+			mq.TotalCost = store.IDR(0)
+		}
+
+		quotes = append(quotes, mq)
+	}
+
+	return quotes, nil
+}
+
+// shippingDestination menentukan titik tujuan pengiriman. Tanpa koordinat alamat, dipakai koordinat wilayah
+// paling spesifik dari region alamat dan hasilnya ditandai sebagai perkiraan.
+func (app *application) shippingDestination(ctx context.Context, address *store.ShippingAddresses) (point *shipping.Point, estimated bool, err error) {
+	if address.Latitude != nil && address.Longitude != nil {
+		return &shipping.Point{Latitude: *address.Latitude, Longitude: *address.Longitude}, false, nil
+	}
+
+	if address.RegionID == "" {
+		return nil, true, nil
+	}
+
+	path, err := app.store.Regions.GetPath(ctx, address.RegionID)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			return nil, true, nil
+		}
+		return nil, true, err
+	}
+
+	for i := len(path) - 1; i >= 0; i-- {
+		if path[i].Latitude != nil && path[i].Longitude != nil {
+			return &shipping.Point{Latitude: *path[i].Latitude, Longitude: *path[i].Longitude}, true, nil
+		}
+	}
+
+	return nil, true, nil
+}
+
+// ShippingQuotes godoc
+//
+//	@Summary		Get shipping quotes for a checkout session
+//	@Description	Calculate shipping cost of every active shipping method for the checkout session based on product weight and dimensions, toko location and the destination address. Addresses without coordinates use the center of their region and the quote is marked as estimated
+//	@Tags			checkout
+//	@Accept			json
+//	@Produce		json
+//	@Param			session_id	path		string					true	"Checkout Session ID"
+//	@Param			payload		body		ShippingQuotePayload	true	"Payload"
+//	@Success		200			{array}		ShippingMethodQuote
+//	@Failure		400			{object}	error
+//	@Failure		401			{object}	error
+//	@Failure		404			{object}	error
+//	@Failure		500			{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/checkout/{session_id}/shipping-quotes [post]
+func (app *application) shippingQuotesHandler(w http.ResponseWriter, r *http.Request) {
+	user := getUserFromContext(r)
+
+	var payload ShippingQuotePayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	ctx := r.Context()
+	checkoutSession, err := app.checkoutSessions().GetCheckoutSession(ctx, chi.URLParam(r, "session_id"))
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound), errors.Is(err, store.ErrSessionExpired):
+			app.notFoundResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	// Verifikasi session milik user yang benar
+	if checkoutSession.UserID != user.ID {
+		app.unauthorizedErrorResponse(w, r, errors.New("unauthorized to access this session"))
+		return
+	}
+
+	address, err := app.store.ShippingAddresses.GetByID(ctx, uuid.MustParse(payload.ShippingAddressID), user.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.notFoundResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	methods, err := app.store.Orders.GetShippingMethods(ctx)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	quotes, err := app.quoteShipping(ctx, checkoutSession, address, methods)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, quotes); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
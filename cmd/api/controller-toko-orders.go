@@ -0,0 +1,244 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/yogaprasetya22/api-gotokopedia/internal/store"
+)
+
+// Struct untuk request pengiriman pesanan oleh seller
+type ShipOrderPayload struct {
+	TrackingNumber string `json:"tracking_number" validate:"required,max=100"`
+	Notes          string `json:"notes" validate:"max=255"`
+}
+
+// listTokoOrdersHandler godoc
+//
+//	@Summary		List incoming orders of a toko
+//	@Description	List orders containing products of the toko, filtered by status and date range. Only the toko owner can access it.
+//	@Tags			toko
+//	@Accept			json
+//	@Produce		json
+//	@Param			slug_toko	path		string	true	"slug toko"
+//	@Param			status		query		int		false	"Order status ID"
+//	@Param			since		query		string	false	"Created since (YYYY-MM-DD HH:MM:SS)"
+//	@Param			until		query		string	false	"Created until (YYYY-MM-DD HH:MM:SS)"
+//	@Param			limit		query		int		false	"Number of orders to return (default 10)"
+//	@Param			offset		query		int		false	"Offset for pagination (default 0)"
+//	@Success		200			{object}	[]store.Order
+//	@Failure		400			{object}	error
+//	@Failure		403			{object}	error
+//	@Failure		404			{object}	error
+//	@Failure		500			{object}	error
+//	@Router			/toko/{slug_toko}/orders [get]
+//	@Security		ApiKeyAuth
+func (app *application) listTokoOrdersHandler(w http.ResponseWriter, r *http.Request) {
+	fq := store.PaginatedFeedQuery{
+		Limit:  10,
+		Offset: 0,
+		Sort:   "desc",
+	}
+
+	fq, err := fq.Parse(r)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(fq); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	filter := store.SellerOrderFilter{
+		Since: fq.Since,
+		Until: fq.Until,
+	}
+
+	if status := r.URL.Query().Get("status"); status != "" {
+		statusID, err := strconv.ParseInt(status, 10, 64)
+		if err != nil || statusID < store.OrderStatusPending || statusID > store.OrderStatusRefunded {
+			app.badRequestResponse(w, r, errors.New("invalid order status"))
+			return
+		}
+
+		filter.StatusID = statusID
+	}
+
+	t := getTokoFromContext(r)
+
+	orders, err := app.store.Orders.GetByTokoID(r.Context(), t.ID, filter, fq)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, orders); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// acceptTokoOrderHandler godoc
+//
+//	@Summary		Accept an order
+//	@Description	Toko owner accepts a paid Pending order and moves it to Processing. Unpaid or already accepted orders are rejected with 409
+//	@Tags			toko
+//	@Accept			json
+//	@Produce		json
+//	@Param			slug_toko	path		string				true	"slug toko"
+//	@Param			orderID		path		int					true	"Order ID"
+//	@Param			payload		body		OrderNotesPayload	false	"Optional notes"
+//	@Success		200			{object}	store.Order
+//	@Failure		400			{object}	error
+//	@Failure		403			{object}	error
+//	@Failure		404			{object}	error
+//	@Failure		409			{object}	error
+//	@Failure		500			{object}	error
+//	@Router			/toko/{slug_toko}/orders/{orderID}/accept [patch]
+//	@Security		ApiKeyAuth
+func (app *application) acceptTokoOrderHandler(w http.ResponseWriter, r *http.Request) {
+	orderID, ok := app.readTokoOrderID(w, r)
+	if !ok {
+		return
+	}
+
+	payload, err := app.readOrderNotesPayload(w, r)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	notes := payload.Notes
+	if notes == "" {
+		notes = "Order accepted by seller"
+	}
+
+	user := getUserFromContext(r)
+	t := getTokoFromContext(r)
+
+	actor := store.OrderActor{UserID: user.ID, Role: store.OrderActorSeller}
+	if err := app.store.Orders.AcceptBySeller(r.Context(), t.ID, orderID, notes, actor); err != nil {
+		app.orderStatusErrorResponse(w, r, err)
+		return
+	}
+
+	app.respondWithOrder(w, r, orderID)
+}
+
+// shipTokoOrderHandler godoc
+//
+//	@Summary		Ship an order
+//	@Description	Toko owner marks an order as shipped with the courier tracking number
+//	@Tags			toko
+//	@Accept			json
+//	@Produce		json
+//	@Param			slug_toko	path		string				true	"slug toko"
+//	@Param			orderID		path		int					true	"Order ID"
+//	@Param			payload		body		ShipOrderPayload	true	"Tracking number and notes"
+//	@Success		200			{object}	store.Order
+//	@Failure		400			{object}	error
+//	@Failure		403			{object}	error
+//	@Failure		404			{object}	error
+//	@Failure		409			{object}	error
+//	@Failure		500			{object}	error
+//	@Router			/toko/{slug_toko}/orders/{orderID}/ship [patch]
+//	@Security		ApiKeyAuth
+func (app *application) shipTokoOrderHandler(w http.ResponseWriter, r *http.Request) {
+	orderID, ok := app.readTokoOrderID(w, r)
+	if !ok {
+		return
+	}
+
+	var payload ShipOrderPayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	notes := payload.Notes
+	if notes == "" {
+		notes = "Order shipped by seller"
+	}
+
+	user := getUserFromContext(r)
+	t := getTokoFromContext(r)
+
+	actor := store.OrderActor{UserID: user.ID, Role: store.OrderActorSeller}
+	err := app.store.Orders.ShipBySeller(r.Context(), t.ID, orderID, payload.TrackingNumber, notes, actor)
+	if err != nil {
+		if errors.Is(err, store.ErrTrackingNumberRequired) {
+			app.badRequestResponse(w, r, err)
+			return
+		}
+
+		app.orderStatusErrorResponse(w, r, err)
+		return
+	}
+
+	app.respondWithOrder(w, r, orderID)
+}
+
+// deliverTokoOrderHandler godoc
+//
+//	@Summary		Mark an order delivered
+//	@Description	Toko owner marks a shipped order as delivered
+//	@Tags			toko
+//	@Accept			json
+//	@Produce		json
+//	@Param			slug_toko	path		string				true	"slug toko"
+//	@Param			orderID		path		int					true	"Order ID"
+//	@Param			payload		body		OrderNotesPayload	false	"Optional notes"
+//	@Success		200			{object}	store.Order
+//	@Failure		400			{object}	error
+//	@Failure		403			{object}	error
+//	@Failure		404			{object}	error
+//	@Failure		409			{object}	error
+//	@Failure		500			{object}	error
+//	@Router			/toko/{slug_toko}/orders/{orderID}/deliver [patch]
+//	@Security		ApiKeyAuth
+func (app *application) deliverTokoOrderHandler(w http.ResponseWriter, r *http.Request) {
+	orderID, ok := app.readTokoOrderID(w, r)
+	if !ok {
+		return
+	}
+
+	payload, err := app.readOrderNotesPayload(w, r)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	notes := payload.Notes
+	if notes == "" {
+		notes = "Order delivered"
+	}
+
+	user := getUserFromContext(r)
+	t := getTokoFromContext(r)
+
+	actor := store.OrderActor{UserID: user.ID, Role: store.OrderActorSeller}
+	if err := app.store.Orders.DeliverBySeller(r.Context(), t.ID, orderID, notes, actor); err != nil {
+		app.orderStatusErrorResponse(w, r, err)
+		return
+	}
+
+	app.respondWithOrder(w, r, orderID)
+}
+
+func (app *application) readTokoOrderID(w http.ResponseWriter, r *http.Request) (int64, bool) {
+	orderID, err := strconv.ParseInt(chi.URLParam(r, "orderID"), 10, 64)
+	if err != nil || orderID < 1 {
+		app.badRequestResponse(w, r, errors.New("invalid order ID"))
+		return 0, false
+	}
+
+	return orderID, true
+}
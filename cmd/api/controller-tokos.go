@@ -1,6 +1,8 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"net/http"
 	"time"
 
@@ -40,9 +42,15 @@ type TokoToProduct struct {
 //	@Param			slug_toko	path		string	true	"slug toko"
 //	@Param			limit		query		int		false	"limit"
 //	@Param			offset		query		int		false	"offset"
-//	@Param			sort		query		string	false	"sort"
+//	@Param			cursor		query		string	false	"next_cursor or prev_cursor from the previous page, overrides offset"
+//	@Param			sort		query		string	false	"sort: relevance, newest, price_asc, price_desc, rating, best_selling (asc or desc keep the default order)"
 //	@Param			search		query		string	false	"search"
-//	@Success		200			{object}	store.Product
+//	@Param			min_price	query		number	false	"minimum effective price"
+//	@Param			max_price	query		number	false	"maximum effective price"
+//	@Param			rating		query		int		false	"minimum average rating"
+//	@Param			discount	query		number	false	"minimum discount percent"
+//	@Param			in_stock	query		bool	false	"only products in stock"
+//	@Success		200			{object}	ProductFeed
 //	@Failure		404			{object}	error
 //	@Failure		500			{object}	error
 //	@Router			/toko/{slug_toko} [get]
@@ -56,16 +64,21 @@ func (app *application) getProductTokoHandler(w http.ResponseWriter, r *http.Req
 	}
 	slugToko := chi.URLParam(r, "slug_toko")
 
+	fq, ok := app.readFeedQuery(w, r, fq)
+	if !ok {
+		return
+	}
+
 	ctx := r.Context()
 	product, err := app.store.Products.GetProductByTokos(ctx, slugToko, fq)
 	if err != nil {
-		app.internalServerError(w, r, err)
+		app.listErrorResponse(w, r, err)
 		return
 	}
 
-	if err := app.jsonResponse(w, http.StatusOK, product); err != nil {
-		app.internalServerError(w, r, err)
-	}
+	filter := store.NewProductFilter(fq)
+	filter.TokoSlug = slugToko
+	app.productFeedResponse(w, r, product, filter)
 }
 
 // GetToko gdoc
@@ -159,3 +172,42 @@ func (app *application) createTokoHandler(w http.ResponseWriter, r *http.Request
 	}
 
 }
+
+func (app *application) tokoContextMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		slugToko := chi.URLParam(r, "slug_toko")
+
+		ctx := r.Context()
+		t, err := app.store.Tokos.GetBySlug(ctx, slugToko)
+		if err != nil {
+			switch {
+			case errors.Is(err, store.ErrNotFound):
+				app.notFoundResponse(w, r, err)
+			default:
+				app.internalServerError(w, r, err)
+			}
+			return
+		}
+
+		ctx = context.WithValue(ctx, TokoCtx, t)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+func (app *application) checkTokoOwnership(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user := getUserFromContext(r)
+		t := getTokoFromContext(r)
+
+		if t.UserID == user.ID {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		app.forbiddenResponse(w, r)
+	})
+}
+
+func getTokoFromContext(r *http.Request) *store.Toko {
+	return r.Context().Value(TokoCtx).(*store.Toko)
+}
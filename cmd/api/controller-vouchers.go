@@ -0,0 +1,463 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/yogaprasetya22/api-gotokopedia/internal/store"
+)
+
+type VoucherPayload struct {
+	Code              string    `json:"code" validate:"required,alphanum,max=50"`
+	Name              string    `json:"name" validate:"required,max=100"`
+	Description       string    `json:"description" validate:"max=1000"`
+	Type              string    `json:"type" validate:"required,oneof=percentage fixed free_shipping"`
+	Amount            float64   `json:"amount" validate:"gte=0"`
+	Percent           float64   `json:"percent" validate:"gte=0,lte=100"`
+	MaxDiscount       *float64  `json:"max_discount" validate:"omitempty,gt=0"`
+	MinSpend          float64   `json:"min_spend" validate:"gte=0"`
+	TokoID            *int64    `json:"toko_id" validate:"omitempty,gt=0"`
+	UsageLimit        *int      `json:"usage_limit" validate:"omitempty,gt=0"`
+	UsageLimitPerUser *int      `json:"usage_limit_per_user" validate:"omitempty,gt=0"`
+	StartsAt          time.Time `json:"starts_at" validate:"required"`
+	EndsAt            time.Time `json:"ends_at" validate:"required,gtfield=StartsAt"`
+	IsActive          *bool     `json:"is_active"`
+}
+
+type ApplyVoucherPayload struct {
+	Code string `json:"code" validate:"required,max=50"`
+}
+
+// isVoucherRuleError mengecek apakah err berasal dari aturan voucher, bukan kegagalan sistem
+func isVoucherRuleError(err error) bool {
+	return errors.Is(err, store.ErrVoucherInactive) ||
+		errors.Is(err, store.ErrVoucherUsageLimit) ||
+		errors.Is(err, store.ErrVoucherMinSpend) ||
+		errors.Is(err, store.ErrVoucherNotApplicable)
+}
+
+// readVoucherPayload membaca dan memvalidasi payload voucher dari admin
+func (app *application) readVoucherPayload(w http.ResponseWriter, r *http.Request) (*store.Voucher, error) {
+	var payload VoucherPayload
+	if err := readJSON(w, r, &payload); err != nil {
+		return nil, err
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		return nil, err
+	}
+
+	switch payload.Type {
+	case store.VoucherTypePercentage:
+		if payload.Percent <= 0 || payload.Percent > 100 {
+			return nil, errors.New("percentage voucher percent must be between 0 and 100")
+		}
+		payload.Amount = 0
+	case store.VoucherTypeFixed:
+		if payload.Amount <= 0 {
+			return nil, errors.New("fixed voucher amount must be greater than 0")
+		}
+		payload.Percent = 0
+	default:
+		payload.Amount, payload.Percent = 0, 0
+	}
+
+	if payload.TokoID != nil {
+		if _, err := app.store.Tokos.GetByID(r.Context(), *payload.TokoID); err != nil {
+			if errors.Is(err, store.ErrNotFound) {
+				return nil, errors.New("toko not found")
+			}
+			return nil, err
+		}
+	}
+
+	isActive := true
+	if payload.IsActive != nil {
+		isActive = *payload.IsActive
+	}
+
+	var maxDiscount *store.Money
+	if payload.MaxDiscount != nil {
+		m := store.MoneyFromMajor(*payload.MaxDiscount)
+		maxDiscount = &m
+	}
+
+	return &store.Voucher{
+		Code:              payload.Code,
+		Name:              payload.Name,
+		Description:       payload.Description,
+		Type:              payload.Type,
+		Amount:            store.MoneyFromMajor(payload.Amount),
+		Percent:           payload.Percent,
+		MaxDiscount:       maxDiscount,
+		MinSpend:          store.MoneyFromMajor(payload.MinSpend),
+		TokoID:            payload.TokoID,
+		UsageLimit:        payload.UsageLimit,
+		UsageLimitPerUser: payload.UsageLimitPerUser,
+		StartsAt:          payload.StartsAt,
+		EndsAt:            payload.EndsAt,
+		IsActive:          isActive,
+	}, nil
+}
+
+// listVouchersHandler godoc
+//
+//	@Summary		List vouchers
+//	@Description	Get all vouchers, admin only
+//	@Tags			voucher
+//	@Accept			json
+//	@Produce		json
+//	@Success		200	{array}		store.Voucher
+//	@Failure		403	{object}	error
+//	@Failure		500	{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/vouchers [get]
+func (app *application) listVouchersHandler(w http.ResponseWriter, r *http.Request) {
+	vouchers, err := app.store.Vouchers.List(r.Context())
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, vouchers); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// getVoucherHandler godoc
+//
+//	@Summary		Get voucher
+//	@Description	Get a voucher by ID, admin only
+//	@Tags			voucher
+//	@Accept			json
+//	@Produce		json
+//	@Param			id	path		int	true	"Voucher ID"
+//	@Success		200	{object}	store.Voucher
+//	@Failure		400	{object}	error
+//	@Failure		403	{object}	error
+//	@Failure		404	{object}	error
+//	@Failure		500	{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/vouchers/{id} [get]
+func (app *application) getVoucherHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		app.badRequestResponse(w, r, errors.New("invalid id"))
+		return
+	}
+
+	voucher, err := app.store.Vouchers.GetByID(r.Context(), id)
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.notFoundResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, voucher); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// createVoucherHandler godoc
+//
+//	@Summary		Create voucher
+//	@Description	Create a percentage, fixed or free shipping voucher. A voucher without toko_id applies to every toko. Admin only
+//	@Tags			voucher
+//	@Accept			json
+//	@Produce		json
+//	@Param			payload	body		VoucherPayload	true	"Voucher"
+//	@Success		201		{object}	store.Voucher
+//	@Failure		400		{object}	error
+//	@Failure		403		{object}	error
+//	@Failure		409		{object}	error
+//	@Failure		500		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/vouchers [post]
+func (app *application) createVoucherHandler(w http.ResponseWriter, r *http.Request) {
+	voucher, err := app.readVoucherPayload(w, r)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := app.store.Vouchers.Create(r.Context(), voucher); err != nil {
+		switch {
+		case errors.Is(err, store.ErrConflict):
+			app.conflictResponse(w, r, errors.New("voucher code already exists"))
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusCreated, voucher); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// updateVoucherHandler godoc
+//
+//	@Summary		Update voucher
+//	@Description	Replace a voucher, its usage count is kept. Admin only
+//	@Tags			voucher
+//	@Accept			json
+//	@Produce		json
+//	@Param			id		path		int				true	"Voucher ID"
+//	@Param			payload	body		VoucherPayload	true	"Voucher"
+//	@Success		200		{object}	store.Voucher
+//	@Failure		400		{object}	error
+//	@Failure		403		{object}	error
+//	@Failure		404		{object}	error
+//	@Failure		409		{object}	error
+//	@Failure		500		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/vouchers/{id} [put]
+func (app *application) updateVoucherHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		app.badRequestResponse(w, r, errors.New("invalid id"))
+		return
+	}
+
+	voucher, err := app.readVoucherPayload(w, r)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+	voucher.ID = id
+
+	if err := app.store.Vouchers.Update(r.Context(), voucher); err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.notFoundResponse(w, r, err)
+		case errors.Is(err, store.ErrConflict):
+			app.conflictResponse(w, r, errors.New("voucher code already exists"))
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, voucher); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// deleteVoucherHandler godoc
+//
+//	@Summary		Delete voucher
+//	@Description	Delete a voucher, orders that used it keep their discount. Admin only
+//	@Tags			voucher
+//	@Accept			json
+//	@Produce		json
+//	@Param			id	path	int	true	"Voucher ID"
+//	@Success		204
+//	@Failure		400	{object}	error
+//	@Failure		403	{object}	error
+//	@Failure		404	{object}	error
+//	@Failure		500	{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/vouchers/{id} [delete]
+func (app *application) deleteVoucherHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		app.badRequestResponse(w, r, errors.New("invalid id"))
+		return
+	}
+
+	if err := app.store.Vouchers.Delete(r.Context(), id); err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.notFoundResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// voucherLines mengubah cart store di checkout session menjadi dasar perhitungan voucher.
+// Ongkir yang belum dihitung dianggap 0 sampai shipping method dipilih.
+func voucherLines(session *store.CheckoutSession) []store.VoucherLine {
+	lines := make([]store.VoucherLine, 0, len(session.CartStore))
+	for _, cs := range session.CartStore {
+		line := store.VoucherLine{
+			CartStoreID:  cs.ID,
+			TokoID:       cs.TokoID,
+			ShippingCost: session.ShippingCosts[cs.ID],
+		}
+		for _, item := range cs.Items {
+			if item.Product != nil {
+				line.Subtotal = line.Subtotal.Add(store.IDR(item.Pricing().Subtotal))
+			}
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+// applyVoucher memvalidasi voucher untuk user lalu menyimpan voucher beserta potongannya ke checkout session
+func (app *application) applyVoucher(ctx context.Context, session *store.CheckoutSession, voucher *store.Voucher) error {
+	usage, err := app.store.Vouchers.CountUserUsage(ctx, voucher.ID, session.UserID)
+	if err != nil {
+		return err
+	}
+
+	if err := voucher.Validate(time.Now(), usage); err != nil {
+		return err
+	}
+
+	discounts, err := voucher.Calculate(voucherLines(session))
+	if err != nil {
+		return err
+	}
+
+	session.Voucher = voucher
+	session.Discounts = discounts
+	session.Recalculate()
+	return nil
+}
+
+// getUserCheckoutSession mengambil checkout session dari path milik user yang login
+func (app *application) getUserCheckoutSession(w http.ResponseWriter, r *http.Request) (*store.CheckoutSession, bool) {
+	user := getUserFromContext(r)
+
+	checkoutSession, err := app.checkoutSessions().GetCheckoutSession(r.Context(), chi.URLParam(r, "session_id"))
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound), errors.Is(err, store.ErrSessionExpired):
+			app.notFoundResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return nil, false
+	}
+
+	// Verifikasi session milik user yang benar
+	if checkoutSession.UserID != user.ID {
+		app.unauthorizedErrorResponse(w, r, errors.New("unauthorized to access this session"))
+		return nil, false
+	}
+
+	return checkoutSession, true
+}
+
+// ApplyCheckoutVoucher godoc
+//
+//	@Summary		Apply a voucher to a checkout session
+//	@Description	Validate the voucher code and store the voucher with its discount per cart store in the checkout session. Free shipping discount is recalculated when the checkout is completed
+//	@Tags			checkout
+//	@Accept			json
+//	@Produce		json
+//	@Param			session_id	path		string				true	"Checkout Session ID"
+//	@Param			payload		body		ApplyVoucherPayload	true	"Payload"
+//	@Success		200			{object}	store.CheckoutSession
+//	@Failure		400			{object}	error
+//	@Failure		401			{object}	error
+//	@Failure		404			{object}	error
+//	@Failure		422			{object}	error
+//	@Failure		500			{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/checkout/{session_id}/voucher [put]
+func (app *application) applyCheckoutVoucherHandler(w http.ResponseWriter, r *http.Request) {
+	var payload ApplyVoucherPayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	checkoutSession, ok := app.getUserCheckoutSession(w, r)
+	if !ok {
+		return
+	}
+
+	ctx := r.Context()
+	voucher, err := app.store.Vouchers.GetByCode(ctx, payload.Code)
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.notFoundResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	if err := app.applyVoucher(ctx, checkoutSession, voucher); err != nil {
+		switch {
+		case isVoucherRuleError(err):
+			app.unprocessableEntityResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	if err := app.checkoutSessions().UpdateCheckoutSession(ctx, checkoutSession); err != nil {
+		switch {
+		case errors.Is(err, store.ErrSessionExpired):
+			app.notFoundResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, checkoutSession); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// RemoveCheckoutVoucher godoc
+//
+//	@Summary		Remove the voucher from a checkout session
+//	@Description	Remove the applied voucher and its discount from the checkout session
+//	@Tags			checkout
+//	@Accept			json
+//	@Produce		json
+//	@Param			session_id	path		string	true	"Checkout Session ID"
+//	@Success		200			{object}	store.CheckoutSession
+//	@Failure		401			{object}	error
+//	@Failure		404			{object}	error
+//	@Failure		500			{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/checkout/{session_id}/voucher [delete]
+func (app *application) removeCheckoutVoucherHandler(w http.ResponseWriter, r *http.Request) {
+	checkoutSession, ok := app.getUserCheckoutSession(w, r)
+	if !ok {
+		return
+	}
+
+	checkoutSession.Voucher = nil
+	checkoutSession.Discounts = nil
+	checkoutSession.Recalculate()
+
+	if err := app.checkoutSessions().UpdateCheckoutSession(r.Context(), checkoutSession); err != nil {
+		switch {
+		case errors.Is(err, store.ErrSessionExpired):
+			app.notFoundResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, checkoutSession); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	"github.com/yogaprasetya22/api-gotokopedia/internal/jobs"
+)
+
+type orderExpiryConfig struct {
+	enabled   bool
+	deadline  time.Duration
+	interval  time.Duration
+	batchSize int
+}
+
+// newJobRunner mendaftarkan job latar belakang yang aktif sesuai konfigurasi
+func (app *application) newJobRunner() *jobs.Runner {
+	runner := jobs.NewRunner(app.logger)
+
+	if app.config.orderExpiry.enabled {
+		runner.Add(jobs.Job{
+			Name:     "expire-unpaid-orders",
+			Interval: app.config.orderExpiry.interval,
+			Run:      app.expireUnpaidOrdersJob,
+		})
+	}
+
+	// Snapshot feed personal selalu disimpan di Postgres
+	runner.Add(jobs.Job{
+		Name:     "purge-feed-snapshots",
+		Interval: 10 * time.Minute,
+		Run:      app.purgeFeedSnapshotsJob,
+	})
+
+	// Redis menghapus key idempotency, checkout session dan tahanan stok lewat TTL, tabel Postgres perlu dibersihkan sendiri
+	if !app.config.redisCfg.enabled {
+		runner.Add(jobs.Job{
+			Name:     "purge-idempotency-keys",
+			Interval: time.Hour,
+			Run:      app.purgeIdempotencyKeysJob,
+		})
+		runner.Add(jobs.Job{
+			Name:     "purge-checkout-sessions",
+			Interval: time.Minute,
+			Run:      app.purgeCheckoutSessionsJob,
+		})
+		runner.Add(jobs.Job{
+			Name:     "purge-inventory-reservations",
+			Interval: time.Minute,
+			Run:      app.purgeInventoryReservationsJob,
+		})
+	}
+
+	return runner
+}
+
+// expireUnpaidOrdersJob membatalkan order Pending yang melewati batas waktu pembayaran
+func (app *application) expireUnpaidOrdersJob(ctx context.Context) error {
+	deadline := time.Now().Add(-app.config.orderExpiry.deadline)
+
+	expired, err := app.store.Orders.ExpireUnpaid(ctx, deadline, app.config.orderExpiry.batchSize)
+	if err != nil {
+		return err
+	}
+
+	if len(expired) == 0 {
+		return nil
+	}
+
+	orderIDs := make([]int64, 0, len(expired))
+	for _, o := range expired {
+		orderIDs = append(orderIDs, o.ID)
+		app.cancelOrderCharge(ctx, o.OrderNumber)
+	}
+
+	app.logger.Infow("unpaid orders expired", "count", len(expired), "order_ids", orderIDs)
+
+	return nil
+}
+
+// purgeIdempotencyKeysJob menghapus response idempotency yang sudah kadaluarsa
+func (app *application) purgeIdempotencyKeysJob(ctx context.Context) error {
+	deleted, err := app.store.IdempotencyKeys.DeleteExpired(ctx)
+	if err != nil {
+		return err
+	}
+
+	if deleted > 0 {
+		app.logger.Infow("expired idempotency keys purged", "count", deleted)
+	}
+
+	return nil
+}
+
+// purgeFeedSnapshotsJob menghapus snapshot feed personal yang sudah kadaluarsa
+func (app *application) purgeFeedSnapshotsJob(ctx context.Context) error {
+	deleted, err := app.store.Products.DeleteExpiredFeedSnapshots(ctx)
+	if err != nil {
+		return err
+	}
+
+	if deleted > 0 {
+		app.logger.Infow("expired feed snapshots purged", "count", deleted)
+	}
+
+	return nil
+}
+
+// purgeCheckoutSessionsJob menghapus checkout session yang sudah kadaluarsa
+func (app *application) purgeCheckoutSessionsJob(ctx context.Context) error {
+	deleted, err := app.store.CheckoutSessions.DeleteExpired(ctx)
+	if err != nil {
+		return err
+	}
+
+	if deleted > 0 {
+		app.logger.Infow("expired checkout sessions purged", "count", deleted)
+	}
+
+	return nil
+}
+
+// purgeInventoryReservationsJob menghapus tahanan stok dari checkout session yang sudah kadaluarsa
+func (app *application) purgeInventoryReservationsJob(ctx context.Context) error {
+	deleted, err := app.store.InventoryReservations.DeleteExpired(ctx)
+	if err != nil {
+		return err
+	}
+
+	if deleted > 0 {
+		app.logger.Infow("expired inventory reservations purged", "count", deleted)
+	}
+
+	return nil
+}
@@ -13,6 +13,7 @@ import (
 	"github.com/yogaprasetya22/api-gotokopedia/internal/db"
 	"github.com/yogaprasetya22/api-gotokopedia/internal/env"
 	"github.com/yogaprasetya22/api-gotokopedia/internal/mailer"
+	"github.com/yogaprasetya22/api-gotokopedia/internal/payment"
 	"github.com/yogaprasetya22/api-gotokopedia/internal/ratelimiter"
 	"github.com/yogaprasetya22/api-gotokopedia/internal/store"
 	"github.com/yogaprasetya22/api-gotokopedia/internal/store/cache"
@@ -81,6 +82,20 @@ func main() {
 			TimeFrame:            time.Second * 5,
 			Enabled:              env.GetBool("RATE_LIMITER_ENABLED", true),
 		},
+		payment: payment.Config{
+			Gateway:   env.GetString("PAYMENT_GATEWAY", "fake"),
+			ServerKey: env.GetString("PAYMENT_SERVER_KEY", ""),
+			ExpiresIn: env.GetDuration("ORDER_PAYMENT_DEADLINE", time.Hour*24),
+		},
+		orderExpiry: orderExpiryConfig{
+			enabled:   env.GetBool("ORDER_EXPIRY_ENABLED", true),
+			deadline:  env.GetDuration("ORDER_PAYMENT_DEADLINE", time.Hour*24),
+			interval:  env.GetDuration("ORDER_EXPIRY_INTERVAL", time.Minute*5),
+			batchSize: env.GetInt("ORDER_EXPIRY_BATCH_SIZE", 100),
+		},
+		cursor: cursorConfig{
+			secret: env.GetString("CURSOR_SECRET", ""),
+		},
 		auth: authConfig{
 			basic: basicConfig{
 				usrname: env.GetString("AUTH_BASIC_USRNAME", "jagresuye"),
@@ -156,6 +171,19 @@ func main() {
 		cfg.rateLimiter.TimeFrame,
 	)
 
+	// Payment gateway, server key lokal hanya dipakai di development agar callback tidak bisa dipalsukan
+	if cfg.payment.ServerKey == "" && cfg.env == "development" {
+		cfg.payment.ServerKey = payment.DevServerKey
+	}
+	if cfg.env != "development" && cfg.payment.ServerKey == payment.DevServerKey {
+		logger.Fatal("PAYMENT_SERVER_KEY must be set outside development")
+	}
+
+	paymentGateway, err := payment.New(cfg.payment)
+	if err != nil {
+		logger.Fatal(err)
+	}
+
 	// JWT Authenticator
 	jwtAuthenticator := auth.NewJWTAuthenticator(cfg.auth.token.secret, cfg.auth.token.iss, cfg.auth.token.iss)
 
@@ -171,6 +199,14 @@ func main() {
 	// Store
 	cacheStorage := cache.NewRedisStore(rdb)
 
+	// Secret cursor lokal hanya dipakai di development agar cursor dan scope-nya tidak bisa dipalsukan
+	if cfg.cursor.secret == "" {
+		if cfg.env != "development" {
+			logger.Fatal("CURSOR_SECRET must be set outside development")
+		}
+		cfg.cursor.secret = store.DevCursorSecret
+	}
+	cursors := store.NewCursorCodec([]byte(cfg.cursor.secret))
 	store := store.NewStorage(db)
 
 	sessionStore := sessions.NewCookieStore([]byte(cfg.auth.token.secret))
@@ -190,7 +226,9 @@ func main() {
 		mailer:            mailer,
 		authenticator:     jwtAuthenticator,
 		rateLimiter:       rateLimiter,
+		paymentGateway:    paymentGateway,
 		googleOauthConfig: googleOauthConfig,
+		cursors:           cursors,
 	}
 
 	// matrucs collected
@@ -1,9 +1,14 @@
 package main
 
 import (
+	"bytes"
 	"context"
+	"crypto/sha256"
 	"encoding/base64"
+	"encoding/hex"
+	"errors"
 	"fmt"
+	"io"
 	"net/http"
 	"strconv"
 	"strings"
@@ -27,39 +32,52 @@ func (app *application) AuthTokenMiddleware(next http.Handler) http.Handler {
 		// 	return
 		// }
 
-		cookie, err := r.Cookie("auth_token")
+		user, err := app.userFromToken(r)
 		if err != nil {
-			app.unauthorizedErrorResponse(w, r, fmt.Errorf("auth token not found in cookies"))
+			app.unauthorizedErrorResponse(w, r, err)
 			return
 		}
 
-		tokenString := cookie.Value
+		ctx := context.WithValue(r.Context(), userCtx, user)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
 
-		jwtToken, err := app.authenticator.ValidateToken(tokenString)
+// OptionalAuthTokenMiddleware mengisi user di context jika token valid, tanpa token request tetap
+// diteruskan sebagai pengunjung anonim
+func (app *application) OptionalAuthTokenMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, err := app.userFromToken(r)
 		if err != nil {
-			app.unauthorizedErrorResponse(w, r, err)
+			next.ServeHTTP(w, r)
 			return
 		}
 
-		claims, _ := jwtToken.Claims.(jwt.MapClaims)
+		ctx := context.WithValue(r.Context(), userCtx, user)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
 
-		userID, err := strconv.ParseInt(fmt.Sprintf("%.f", claims["sub"]), 10, 64)
-		if err != nil {
-			app.unauthorizedErrorResponse(w, r, err)
-			return
-		}
+// userFromToken membaca user dari token JWT di cookie auth_token
+func (app *application) userFromToken(r *http.Request) (*store.User, error) {
+	cookie, err := r.Cookie("auth_token")
+	if err != nil {
+		return nil, fmt.Errorf("auth token not found in cookies")
+	}
 
-		ctx := r.Context()
+	jwtToken, err := app.authenticator.ValidateToken(cookie.Value)
+	if err != nil {
+		return nil, err
+	}
 
-		user, err := app.getUser(ctx, userID)
-		if err != nil {
-			app.unauthorizedErrorResponse(w, r, err)
-			return
-		}
+	claims, _ := jwtToken.Claims.(jwt.MapClaims)
 
-		ctx = context.WithValue(ctx, userCtx, user)
-		next.ServeHTTP(w, r.WithContext(ctx))
-	})
+	userID, err := strconv.ParseInt(fmt.Sprintf("%.f", claims["sub"]), 10, 64)
+	if err != nil {
+		return nil, err
+	}
+
+	return app.getUser(r.Context(), userID)
 }
 
 func (app *application) BasicAuthMiddleware() func(http.Handler) http.Handler {
@@ -130,14 +148,34 @@ func (app *application) checkCommentOwnership(next http.HandlerFunc) http.Handle
 	})
 }
 
-// func (app *application) checkRolePrecedence(ctx context.Context, user *store.User, roleName string) (bool, error) {
-// 	role, err := app.store.Roles.GetByName(ctx, roleName)
-// 	if err != nil {
-// 		return false, err
-// 	}
+func (app *application) checkRolePrecedence(ctx context.Context, user *store.User, roleName string) (bool, error) {
+	role, err := app.store.Roles.GetByName(ctx, roleName)
+	if err != nil {
+		return false, err
+	}
+
+	return user.Role.Level >= role.Level, nil
+}
+
+// checkAdmin hanya meneruskan request dari user dengan role admin atau lebih tinggi
+func (app *application) checkAdmin(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user := getUserFromContext(r)
+
+		allowed, err := app.checkRolePrecedence(r.Context(), user, "admin")
+		if err != nil {
+			app.internalServerError(w, r, err)
+			return
+		}
+
+		if !allowed {
+			app.forbiddenResponse(w, r)
+			return
+		}
 
-// 	return user.Role.Level >= role.Level, nil
-// }
+		next.ServeHTTP(w, r)
+	})
+}
 
 func (app *application) getUser(ctx context.Context, userID int64) (*store.User, error) {
 	if !app.config.redisCfg.enabled {
@@ -175,3 +213,159 @@ func (app *application) RateLimiterMiddleware(next http.Handler) http.Handler {
 		next.ServeHTTP(w, r)
 	})
 }
+
+const (
+	idempotencyKeyHeader    = "Idempotency-Key"
+	idempotencyReplayHeader = "Idempotent-Replayed"
+	maxIdempotencyKeyLength = 255
+)
+
+type idempotencyStore interface {
+	Get(ctx context.Context, userID int64, key string) (*store.IdempotencyRecord, error)
+	Reserve(context.Context, *store.IdempotencyRecord) (bool, error)
+	Save(context.Context, *store.IdempotencyRecord) error
+	Delete(ctx context.Context, userID int64, key string) error
+}
+
+// IdempotencyMiddleware menyimpan response pertama dari POST/PATCH/DELETE yang membawa header Idempotency-Key
+// lalu mengirim ulang response tersebut untuk retry dengan key yang sama. Harus dipasang setelah AuthTokenMiddleware.
+func (app *application) IdempotencyMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := strings.TrimSpace(r.Header.Get(idempotencyKeyHeader))
+		if key == "" || !isIdempotentMethod(r.Method) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if len(key) > maxIdempotencyKeyLength {
+			app.badRequestResponse(w, r, fmt.Errorf("%s must be at most %d characters", idempotencyKeyHeader, maxIdempotencyKeyLength))
+			return
+		}
+
+		body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, 1_048_578))
+		if err != nil {
+			app.badRequestResponse(w, r, err)
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+
+		user := getUserFromContext(r)
+		ctx := r.Context()
+		idempotency := app.idempotencyStore()
+
+		record := &store.IdempotencyRecord{
+			UserID:      user.ID,
+			Key:         key,
+			RequestHash: idempotencyRequestHash(r, body),
+		}
+
+		reserved, err := idempotency.Reserve(ctx, record)
+		if err != nil {
+			app.internalServerError(w, r, err)
+			return
+		}
+
+		if !reserved {
+			existing, err := idempotency.Get(ctx, user.ID, key)
+			if err != nil {
+				if errors.Is(err, store.ErrNotFound) {
+					app.conflictResponse(w, r, fmt.Errorf("request with this %s is being retried, try again", idempotencyKeyHeader))
+					return
+				}
+				app.internalServerError(w, r, err)
+				return
+			}
+
+			switch {
+			case existing.RequestHash != record.RequestHash:
+				app.unprocessableEntityResponse(w, r, fmt.Errorf("%s was already used with a different request", idempotencyKeyHeader))
+			case !existing.IsCompleted():
+				app.conflictResponse(w, r, fmt.Errorf("request with this %s is still being processed", idempotencyKeyHeader))
+			default:
+				w.Header().Set("Content-Type", existing.ContentType)
+				w.Header().Set(idempotencyReplayHeader, "true")
+				w.WriteHeader(existing.StatusCode)
+				w.Write(existing.Body)
+			}
+			return
+		}
+
+		rec := &responseRecorder{ResponseWriter: w, status: http.StatusOK}
+		defer func() {
+			if p := recover(); p != nil {
+				idempotency.Delete(context.WithoutCancel(ctx), user.ID, key)
+				panic(p)
+			}
+		}()
+
+		next.ServeHTTP(rec, r)
+
+		// Response 5xx tidak disimpan agar client bisa mengulang request dengan key yang sama
+		if rec.status >= http.StatusInternalServerError {
+			if err := idempotency.Delete(context.WithoutCancel(ctx), user.ID, key); err != nil {
+				app.logger.Errorw("failed to release idempotency key", "key", key, "error", err.Error())
+			}
+			return
+		}
+
+		record.StatusCode = rec.status
+		record.ContentType = rec.Header().Get("Content-Type")
+		record.Body = rec.body.Bytes()
+
+		if err := idempotency.Save(context.WithoutCancel(ctx), record); err != nil {
+			app.logger.Errorw("failed to save idempotent response", "key", key, "error", err.Error())
+		}
+	})
+}
+
+// idempotencyStore memilih Redis jika aktif, selain itu tabel idempotency_keys di Postgres
+func (app *application) idempotencyStore() idempotencyStore {
+	if app.config.redisCfg.enabled {
+		return app.cacheStorage.Idempotency
+	}
+
+	return app.store.IdempotencyKeys
+}
+
+func isIdempotentMethod(method string) bool {
+	switch method {
+	case http.MethodPost, http.MethodPatch, http.MethodDelete:
+		return true
+	default:
+		return false
+	}
+}
+
+func idempotencyRequestHash(r *http.Request, body []byte) string {
+	h := sha256.New()
+	h.Write([]byte(r.Method + " " + r.URL.Path + "\n"))
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// responseRecorder meneruskan response ke client sambil menyimpan salinannya
+type responseRecorder struct {
+	http.ResponseWriter
+	status      int
+	body        bytes.Buffer
+	wroteHeader bool
+}
+
+func (rr *responseRecorder) WriteHeader(status int) {
+	if rr.wroteHeader {
+		return
+	}
+
+	rr.status = status
+	rr.wroteHeader = true
+	rr.ResponseWriter.WriteHeader(status)
+}
+
+func (rr *responseRecorder) Write(b []byte) (int, error) {
+	if !rr.wroteHeader {
+		rr.WriteHeader(http.StatusOK)
+	}
+
+	rr.body.Write(b)
+	return rr.ResponseWriter.Write(b)
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/yogaprasetya22/api-gotokopedia/internal/store"
+)
+
+// readFeedQuery membaca, memvalidasi dan memeriksa cursor query pagination. Jika gagal response
+// 400 sudah dikirim dan ok bernilai false.
+func (app *application) readFeedQuery(w http.ResponseWriter, r *http.Request, fq store.PaginatedFeedQuery) (store.PaginatedFeedQuery, bool) {
+	fq, err := fq.Parse(r)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return fq, false
+	}
+
+	if err := Validate.Struct(fq); err != nil {
+		app.badRequestResponse(w, r, err)
+		return fq, false
+	}
+
+	if fq.Cursor != "" {
+		fq.Position, err = app.cursors.Decode(fq.Cursor)
+		if err != nil {
+			app.badRequestResponse(w, r, err)
+			return fq, false
+		}
+	}
+
+	return fq, true
+}
+
+// listErrorResponse memetakan error daftar berbasis cursor, cursor dari daftar atau urutan lain dijawab 400
+func (app *application) listErrorResponse(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case errors.Is(err, store.ErrInvalidCursor):
+		app.badRequestResponse(w, r, err)
+	case errors.Is(err, store.ErrNotFound):
+		app.notFoundResponse(w, r, err)
+	default:
+		app.internalServerError(w, r, err)
+	}
+}
@@ -31,7 +31,7 @@ func TestCartAndLogout(t *testing.T) {
 	// Mock cart store
 	mockCart := &store.Cart{ID: 1, UserID: 10}
 	app.store.Carts = &store.MockCartStore{}
-	app.store.Carts.(*store.MockCartStore).On("AddToCartTransaction", mock.Anything, int64(10), int64(258), int64(1)).Return(mockCart, nil)
+	app.store.Carts.(*store.MockCartStore).On("AddToCartTransaction", mock.Anything, int64(10), int64(258), (*int64)(nil), int64(1)).Return(mockCart, nil)
 
 	// Generate valid JWT token
 	testToken, err := app.authenticator.GenerateToken(nil)
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/yogaprasetya22/api-gotokopedia/internal/store"
+)
+
+func TestIdempotencyKey(t *testing.T) {
+	app := newTestApplication(t, config{})
+	mux := app.mount()
+
+	mockCart := &store.Cart{ID: 1, UserID: 10}
+	mockCarts := app.store.Carts.(*store.MockCartStore)
+	mockCarts.On("AddToCartTransaction", mock.Anything, int64(10), int64(258), (*int64)(nil), int64(1)).Return(mockCart, nil)
+
+	testToken, err := app.authenticator.GenerateToken(nil)
+	require.NoError(t, err)
+
+	addToCart := func(key, body string) *http.Response {
+		req, err := http.NewRequest(http.MethodPost, "/api/v1/cart", strings.NewReader(body))
+		require.NoError(t, err)
+		req.Header.Set("Content-Type", "application/json")
+		if key != "" {
+			req.Header.Set(idempotencyKeyHeader, key)
+		}
+		addJWTToRequest(req, testToken)
+
+		return executeRequest(req, mux).Result()
+	}
+
+	t.Run("retry replays the first response", func(t *testing.T) {
+		first := addToCart("cart-key-1", `{"product_id":258,"quantity":1}`)
+		require.Equal(t, http.StatusCreated, first.StatusCode)
+		require.Empty(t, first.Header.Get(idempotencyReplayHeader))
+
+		retry := addToCart("cart-key-1", `{"product_id":258,"quantity":1}`)
+		require.Equal(t, http.StatusCreated, retry.StatusCode)
+		require.Equal(t, "true", retry.Header.Get(idempotencyReplayHeader))
+
+		mockCarts.AssertNumberOfCalls(t, "AddToCartTransaction", 1)
+	})
+
+	t.Run("same key with a different body is rejected", func(t *testing.T) {
+		res := addToCart("cart-key-1", `{"product_id":258,"quantity":2}`)
+		require.Equal(t, http.StatusUnprocessableEntity, res.StatusCode)
+
+		mockCarts.AssertNumberOfCalls(t, "AddToCartTransaction", 1)
+	})
+
+	t.Run("requests without a key are not deduplicated", func(t *testing.T) {
+		require.Equal(t, http.StatusCreated, addToCart("", `{"product_id":258,"quantity":1}`).StatusCode)
+		require.Equal(t, http.StatusCreated, addToCart("", `{"product_id":258,"quantity":1}`).StatusCode)
+
+		mockCarts.AssertNumberOfCalls(t, "AddToCartTransaction", 3)
+	})
+}
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/yogaprasetya22/api-gotokopedia/internal/payment"
+	"github.com/yogaprasetya22/api-gotokopedia/internal/store"
+)
+
+// TestPaymentCallbackReplay mengirim ulang payload callback yang direkam di testdata/payment_callbacks
+func TestPaymentCallbackReplay(t *testing.T) {
+	app := newTestApplication(t, config{})
+	mux := app.mount()
+
+	const (
+		settledTxID = "b7c1e7f0-3c1a-4e4f-9a0e-2d6b5c9f1a11"
+		expiredTxID = "0f2d9a44-8b51-4c1e-b7c3-6a1e2f3d4c55"
+	)
+
+	const (
+		settledOrder = "ORD-20261017-000001"
+		expiredOrder = "ORD-20261017-000002"
+	)
+
+	pending := &store.Payment{ID: 1, OrderID: 1, Amount: store.IDR(150000), TransactionID: settledTxID, Status: store.PaymentStatusPending}
+	paid := &store.Payment{ID: 1, OrderID: 1, Amount: store.IDR(150000), TransactionID: settledTxID, Status: store.PaymentStatusPaid}
+
+	// Pembayaran order pertama masih pending sampai notifikasi settlement pertama diproses
+	mockPayments := app.store.Payments.(*store.MockPaymentStore)
+	mockPayments.On("GetByOrderNumber", mock.Anything, settledOrder).Return(pending, nil).Twice()
+	mockPayments.On("GetByOrderNumber", mock.Anything, settledOrder).Return(paid, nil)
+	mockPayments.On("GetByOrderNumber", mock.Anything, expiredOrder).
+		Return(&store.Payment{ID: 2, OrderID: 2, Amount: store.IDR(75000), TransactionID: expiredTxID, Status: store.PaymentStatusPending}, nil)
+	mockPayments.On("UpdateStatus", mock.Anything, settledTxID, store.PaymentStatusPaid).Return(paid, nil)
+	mockPayments.On("UpdateStatus", mock.Anything, expiredTxID, store.PaymentStatusExpired).
+		Return(&store.Payment{ID: 2, OrderID: 2, Amount: store.IDR(75000), TransactionID: expiredTxID, Status: store.PaymentStatusExpired}, nil)
+
+	cases := []struct {
+		fixture  string
+		expected int
+	}{
+		{"pending.json", http.StatusOK},
+		{"settlement.json", http.StatusOK},
+		{"settlement.json", http.StatusOK}, // gateway mengirim ulang notifikasi yang sama
+		{"expire.json", http.StatusOK},
+		{"invalid_signature.json", http.StatusUnauthorized},
+		{"amount_mismatch.json", http.StatusBadRequest},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.fixture, func(t *testing.T) {
+			payload, err := os.ReadFile(filepath.Join("testdata", "payment_callbacks", tc.fixture))
+			require.NoError(t, err)
+
+			req, err := http.NewRequest(http.MethodPost, "/api/v1/payments/callback", bytes.NewReader(payload))
+			require.NoError(t, err)
+			req.Header.Set("Content-Type", "application/json")
+
+			rr := executeRequest(req, mux)
+			require.Equal(t, tc.expected, rr.Code, rr.Body.String())
+		})
+	}
+
+	// Notifikasi settlement kedua tidak mengubah pembayaran yang sudah lunas
+	mockPayments.AssertNumberOfCalls(t, "UpdateStatus", 2)
+	mockPayments.AssertCalled(t, "UpdateStatus", mock.Anything, settledTxID, store.PaymentStatusPaid)
+	mockPayments.AssertCalled(t, "UpdateStatus", mock.Anything, expiredTxID, store.PaymentStatusExpired)
+}
+
+// TestPaymentCallbackForeignTransaction memastikan callback bertanda tangan tidak bisa dipakai untuk pembayaran order lain
+func TestPaymentCallbackForeignTransaction(t *testing.T) {
+	app := newTestApplication(t, config{})
+	mux := app.mount()
+
+	const orderNumber = "ORD-20261017-000003"
+
+	mockPayments := app.store.Payments.(*store.MockPaymentStore)
+	mockPayments.On("GetByOrderNumber", mock.Anything, orderNumber).
+		Return(&store.Payment{ID: 3, OrderID: 3, Amount: store.IDR(150000), TransactionID: "own-transaction", Status: store.PaymentStatusPending}, nil)
+
+	// Signature valid untuk order ini, tetapi transaction_id milik pembayaran lain dengan nominal sama
+	notification := payment.Notification{
+		TransactionID:     "b7c1e7f0-3c1a-4e4f-9a0e-2d6b5c9f1a11",
+		OrderNumber:       orderNumber,
+		TransactionStatus: payment.StatusSettlement,
+		StatusCode:        "200",
+		GrossAmount:       "150000.00",
+		FraudStatus:       "accept",
+		SignatureKey:      payment.Signature(orderNumber, "200", "150000.00", "test"),
+	}
+	payload, err := json.Marshal(notification)
+	require.NoError(t, err)
+
+	req, err := http.NewRequest(http.MethodPost, "/api/v1/payments/callback", bytes.NewReader(payload))
+	require.NoError(t, err)
+	req.Header.Set("Content-Type", "application/json")
+
+	rr := executeRequest(req, mux)
+	require.Equal(t, http.StatusBadRequest, rr.Code, rr.Body.String())
+	mockPayments.AssertNotCalled(t, "UpdateStatus", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestPaymentStatusFromNotification(t *testing.T) {
+	cases := []struct {
+		status, fraud string
+		expected      string
+	}{
+		{payment.StatusSettlement, "", store.PaymentStatusPaid},
+		{payment.StatusCapture, "accept", store.PaymentStatusPaid},
+		{payment.StatusCapture, "challenge", store.PaymentStatusPending},
+		{payment.StatusDeny, "", store.PaymentStatusFailed},
+		{payment.StatusExpire, "", store.PaymentStatusExpired},
+	}
+
+	for _, tc := range cases {
+		n := &payment.Notification{TransactionStatus: tc.status, FraudStatus: tc.fraud}
+		require.Equal(t, tc.expected, paymentStatusFromNotification(n), tc.status+" "+tc.fraud)
+	}
+}
+
+// TestCreateOrderPaymentFailure memastikan order yang tagihannya gagal dibuat langsung dibatalkan
+func TestCreateOrderPaymentFailure(t *testing.T) {
+	app := newTestApplication(t, config{})
+	ctx := context.Background()
+	user := &store.User{ID: 1, Username: "pembeli", Email: "pembeli@example.com"}
+
+	mockPayments := app.store.Payments.(*store.MockPaymentStore)
+	mockPayments.On("FailCharge", mock.Anything, int64(10), mock.Anything).Return(nil)
+	mockPayments.On("AttachCharge", mock.Anything, int64(11), mock.Anything).Return((*store.Payment)(nil), errors.New("db down"))
+	mockPayments.On("FailCharge", mock.Anything, int64(11), mock.Anything).Return(nil)
+
+	t.Run("gateway rejects the charge", func(t *testing.T) {
+		order := &store.Order{ID: 10, OrderNumber: "ORD-10", FinalPrice: store.IDR(150000), PaymentMethod: &store.PaymentMethod{MidtransCode: "unknown"}}
+		_, err := app.createOrderPayment(ctx, user, order)
+		require.ErrorIs(t, err, payment.ErrUnsupportedMethod)
+		mockPayments.AssertCalled(t, "FailCharge", mock.Anything, int64(10), mock.Anything)
+	})
+
+	t.Run("charge cannot be attached", func(t *testing.T) {
+		order := &store.Order{ID: 11, OrderNumber: "ORD-11", FinalPrice: store.IDR(150000), PaymentMethod: &store.PaymentMethod{MidtransCode: "qris"}}
+		_, err := app.createOrderPayment(ctx, user, order)
+		require.Error(t, err)
+		mockPayments.AssertCalled(t, "FailCharge", mock.Anything, int64(11), mock.Anything)
+	})
+}
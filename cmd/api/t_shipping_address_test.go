@@ -0,0 +1,149 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/yogaprasetya22/api-gotokopedia/internal/store"
+)
+
+func TestCreateShippingAddressRegion(t *testing.T) {
+	app := newTestApplication(t, config{})
+	mux := app.mount()
+
+	lat, lng := -6.2280, 106.8037
+	path := []*store.Region{
+		{ID: "31", Level: store.RegionLevelProvince, Name: "DKI Jakarta"},
+		{ID: "31.74", Level: store.RegionLevelCity, Name: "Kota Jakarta Selatan"},
+		{ID: "31.74.07", Level: store.RegionLevelDistrict, Name: "Kebayoran Baru"},
+		{ID: "31.74.07.1010", Level: store.RegionLevelSubDistrict, Name: "Senayan", PostalCode: "12190", Latitude: &lat, Longitude: &lng},
+	}
+
+	mockRegions := app.store.Regions.(*store.MockRegionStore)
+	mockRegions.On("GetPath", mock.Anything, "31.74.07.1010").Return(path, nil)
+	mockRegions.On("GetPath", mock.Anything, "31.74.07").Return(path[:3], nil)
+	mockRegions.On("GetPath", mock.Anything, "99.99.99.9999").Return([]*store.Region(nil), store.ErrNotFound)
+
+	mockAddresses := app.store.ShippingAddresses.(*store.MockShippingAddressStore)
+	mockAddresses.On("Create", mock.Anything, mock.AnythingOfType("*store.ShippingAddresses")).Return(nil)
+
+	testToken, err := app.authenticator.GenerateToken(nil)
+	require.NoError(t, err)
+
+	createAddress := func(regionID, postalCode string) *http.Response {
+		body := `{"label":"Kantor","recipient_name":"Budi","recipient_phone":"081234567890","address_line1":"Jl. Asia Afrika No. 8",` +
+			`"region_id":"` + regionID + `","postal_code":"` + postalCode + `"}`
+
+		req, err := http.NewRequest(http.MethodPost, "/api/v1/shipping-addresses", strings.NewReader(body))
+		require.NoError(t, err)
+		req.Header.Set("Content-Type", "application/json")
+		addJWTToRequest(req, testToken)
+
+		return executeRequest(req, mux).Result()
+	}
+
+	t.Run("postal code matches the sub-district", func(t *testing.T) {
+		res := createAddress("31.74.07.1010", "12190")
+		require.Equal(t, http.StatusCreated, res.StatusCode)
+
+		var envelope struct {
+			Data store.ShippingAddresses `json:"data"`
+		}
+		require.NoError(t, json.NewDecoder(res.Body).Decode(&envelope))
+		require.Equal(t, "DKI Jakarta", envelope.Data.Province)
+		require.Equal(t, "Kota Jakarta Selatan", envelope.Data.City)
+		require.Equal(t, "Kebayoran Baru", envelope.Data.District)
+		require.Equal(t, "Senayan", envelope.Data.SubDistrict)
+		require.NotNil(t, envelope.Data.Latitude)
+	})
+
+	t.Run("postal code does not match the sub-district", func(t *testing.T) {
+		require.Equal(t, http.StatusBadRequest, createAddress("31.74.07.1010", "10310").StatusCode)
+	})
+
+	t.Run("region is not a sub-district", func(t *testing.T) {
+		require.Equal(t, http.StatusBadRequest, createAddress("31.74.07", "12190").StatusCode)
+	})
+
+	t.Run("unknown region", func(t *testing.T) {
+		require.Equal(t, http.StatusBadRequest, createAddress("99.99.99.9999", "12190").StatusCode)
+	})
+
+	t.Run("address outside the seeded regions uses region names", func(t *testing.T) {
+		body := `{"label":"Rumah","recipient_name":"Budi","recipient_phone":"081234567890","address_line1":"Jl. Sultan Hasanuddin No. 1",` +
+			`"province":"Sulawesi Selatan","city":"Kota Makassar","district":"Ujung Pandang","sub_district":"Baru","postal_code":"90174"}`
+
+		req, err := http.NewRequest(http.MethodPost, "/api/v1/shipping-addresses", strings.NewReader(body))
+		require.NoError(t, err)
+		req.Header.Set("Content-Type", "application/json")
+		addJWTToRequest(req, testToken)
+
+		res := executeRequest(req, mux).Result()
+		require.Equal(t, http.StatusCreated, res.StatusCode)
+
+		var envelope struct {
+			Data store.ShippingAddresses `json:"data"`
+		}
+		require.NoError(t, json.NewDecoder(res.Body).Decode(&envelope))
+		require.Empty(t, envelope.Data.RegionID)
+		require.Equal(t, "Kota Makassar", envelope.Data.City)
+		require.Equal(t, "90174", envelope.Data.PostalCode)
+	})
+
+	t.Run("address without region or region names", func(t *testing.T) {
+		require.Equal(t, http.StatusBadRequest, createAddress("", "12190").StatusCode)
+	})
+
+	mockAddresses.AssertNumberOfCalls(t, "Create", 2)
+}
+
+func TestShippingAddressOwnership(t *testing.T) {
+	app := newTestApplication(t, config{})
+	mux := app.mount()
+
+	ownID := uuid.New()
+	otherID := uuid.New()
+
+	mockAddresses := app.store.ShippingAddresses.(*store.MockShippingAddressStore)
+	mockAddresses.On("GetByID", mock.Anything, ownID, int64(10)).Return(&store.ShippingAddresses{ID: ownID, UserID: 10, Label: "Rumah"}, nil)
+	mockAddresses.On("GetByID", mock.Anything, otherID, int64(10)).Return((*store.ShippingAddresses)(nil), store.ErrNotFound)
+	mockAddresses.On("Update", mock.Anything, mock.AnythingOfType("*store.ShippingAddresses")).Return(nil)
+	mockAddresses.On("SetDefaultAddress", mock.Anything, ownID, int64(10)).Return(nil)
+	mockAddresses.On("Delete", mock.Anything, ownID, int64(10)).Return(nil)
+
+	testToken, err := app.authenticator.GenerateToken(nil)
+	require.NoError(t, err)
+
+	send := func(method, path, body string) int {
+		req, err := http.NewRequest(method, "/api/v1/shipping-addresses/"+path, strings.NewReader(body))
+		require.NoError(t, err)
+		req.Header.Set("Content-Type", "application/json")
+		addJWTToRequest(req, testToken)
+
+		return executeRequest(req, mux).Code
+	}
+
+	t.Run("owner can manage the address", func(t *testing.T) {
+		require.Equal(t, http.StatusOK, send(http.MethodPatch, ownID.String(), `{"label":"Kantor"}`))
+		require.Equal(t, http.StatusOK, send(http.MethodPut, ownID.String()+"/default", ""))
+		require.Equal(t, http.StatusNoContent, send(http.MethodDelete, ownID.String(), ""))
+	})
+
+	t.Run("address of another user is not found", func(t *testing.T) {
+		require.Equal(t, http.StatusNotFound, send(http.MethodPatch, otherID.String(), `{"label":"Kantor"}`))
+		require.Equal(t, http.StatusNotFound, send(http.MethodPut, otherID.String()+"/default", ""))
+		require.Equal(t, http.StatusNotFound, send(http.MethodDelete, otherID.String(), ""))
+	})
+
+	t.Run("invalid address ID", func(t *testing.T) {
+		require.Equal(t, http.StatusBadRequest, send(http.MethodDelete, "not-a-uuid", ""))
+	})
+
+	mockAddresses.AssertNumberOfCalls(t, "Update", 1)
+	mockAddresses.AssertNumberOfCalls(t, "Delete", 1)
+}
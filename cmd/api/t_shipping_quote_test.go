@@ -0,0 +1,51 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/yogaprasetya22/api-gotokopedia/internal/store"
+)
+
+func TestShippingDestination(t *testing.T) {
+	app := newTestApplication(t, config{})
+	ctx := context.Background()
+
+	cityLat, cityLng := -6.2615, 106.8106
+	path := []*store.Region{
+		{ID: "31", Level: store.RegionLevelProvince, Name: "DKI Jakarta"},
+		{ID: "31.74", Level: store.RegionLevelCity, Name: "Kota Jakarta Selatan", Latitude: &cityLat, Longitude: &cityLng},
+		{ID: "31.74.07", Level: store.RegionLevelDistrict, Name: "Kebayoran Baru"},
+	}
+
+	mockRegions := app.store.Regions.(*store.MockRegionStore)
+	mockRegions.On("GetPath", mock.Anything, "31.74.07").Return(path, nil)
+	mockRegions.On("GetPath", mock.Anything, "99.99.99").Return([]*store.Region(nil), store.ErrNotFound)
+
+	t.Run("address coordinates are exact", func(t *testing.T) {
+		lat, lng := -6.2280, 106.8037
+		point, estimated, err := app.shippingDestination(ctx, &store.ShippingAddresses{RegionID: "31.74.07", Latitude: &lat, Longitude: &lng})
+		require.NoError(t, err)
+		require.False(t, estimated)
+		require.Equal(t, lat, point.Latitude)
+	})
+
+	t.Run("region without coordinates falls back to its parent", func(t *testing.T) {
+		point, estimated, err := app.shippingDestination(ctx, &store.ShippingAddresses{RegionID: "31.74.07"})
+		require.NoError(t, err)
+		require.True(t, estimated)
+		require.Equal(t, cityLat, point.Latitude)
+		require.Equal(t, cityLng, point.Longitude)
+	})
+
+	t.Run("unknown region has no destination", func(t *testing.T) {
+		for _, address := range []*store.ShippingAddresses{{RegionID: "99.99.99"}, {}} {
+			point, estimated, err := app.shippingDestination(ctx, address)
+			require.NoError(t, err)
+			require.True(t, estimated)
+			require.Nil(t, point)
+		}
+	})
+}
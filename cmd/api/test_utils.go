@@ -5,9 +5,11 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/gorilla/sessions"
 	"github.com/yogaprasetya22/api-gotokopedia/internal/auth"
+	"github.com/yogaprasetya22/api-gotokopedia/internal/payment"
 	"github.com/yogaprasetya22/api-gotokopedia/internal/ratelimiter"
 	"github.com/yogaprasetya22/api-gotokopedia/internal/store"
 	"github.com/yogaprasetya22/api-gotokopedia/internal/store/cache"
@@ -40,12 +42,14 @@ func newTestApplication(t *testing.T, cfg config) *application {
 	)
 
 	return &application{
-		logger:        logger,
-		store:         mockStore,
-		cacheStorage:  mockCacheStore,
-		authenticator: testAuth,
-		config:        cfg,
-		rateLimiter:   rateLimiter,
+		logger:         logger,
+		store:          mockStore,
+		cacheStorage:   mockCacheStore,
+		authenticator:  testAuth,
+		config:         cfg,
+		rateLimiter:    rateLimiter,
+		paymentGateway: payment.NewFakeGateway("test", time.Hour),
+		cursors:        store.NewCursorCodec([]byte("test")),
 	}
 }
 
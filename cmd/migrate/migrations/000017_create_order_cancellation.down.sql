@@ -0,0 +1 @@
+DROP FUNCTION IF EXISTS restore_order_stock(bigint);
@@ -0,0 +1,16 @@
+-- Function to put back product stock and sold count taken by an order
+CREATE OR REPLACE FUNCTION restore_order_stock(p_order_id bigint) RETURNS void AS $$
+BEGIN
+    UPDATE products p
+    SET stock = p.stock + oi.quantity,
+        sold = GREATEST(p.sold - oi.quantity, 0),
+        updated_at = now()
+    FROM (
+        SELECT product_id, SUM(quantity) AS quantity
+        FROM order_items
+        WHERE order_id = p_order_id
+        GROUP BY product_id
+    ) oi
+    WHERE p.id = oi.product_id;
+END;
+$$ LANGUAGE plpgsql;
@@ -0,0 +1,25 @@
+DROP FUNCTION IF EXISTS update_order_status(bigint, bigint, text, bigint, varchar);
+
+CREATE OR REPLACE FUNCTION update_order_status(
+    p_order_id bigint,
+    p_status_id bigint,
+    p_notes text DEFAULT NULL
+) RETURNS void AS $$
+BEGIN
+    UPDATE orders 
+    SET status_id = p_status_id,
+        updated_at = now()
+    WHERE id = p_order_id;
+    
+    INSERT INTO order_tracking (order_id, status_id, notes)
+    VALUES (p_order_id, p_status_id, p_notes);
+END;
+$$ LANGUAGE plpgsql;
+
+ALTER TABLE order_tracking DROP CONSTRAINT IF EXISTS order_tracking_actor_id_fkey;
+
+ALTER TABLE order_tracking
+DROP COLUMN IF EXISTS actor_id,
+DROP COLUMN IF EXISTS actor_role;
+
+DROP TABLE IF EXISTS order_status_transitions;
@@ -0,0 +1,80 @@
+-- Allowed order status transitions
+CREATE TABLE IF NOT EXISTS
+    order_status_transitions (
+        from_status_id bigint NOT NULL,
+        to_status_id bigint NOT NULL,
+        CONSTRAINT order_status_transitions_pkey PRIMARY KEY (from_status_id, to_status_id),
+        CONSTRAINT order_status_transitions_from_fkey FOREIGN KEY (from_status_id) REFERENCES order_status (id),
+        CONSTRAINT order_status_transitions_to_fkey FOREIGN KEY (to_status_id) REFERENCES order_status (id)
+    );
+
+INSERT INTO
+    order_status_transitions (from_status_id, to_status_id)
+SELECT f.id, t.id
+FROM (
+    VALUES
+        ('Pending', 'Processing'),
+        ('Pending', 'Cancelled'),
+        ('Processing', 'Shipped'),
+        ('Processing', 'Cancelled'),
+        ('Processing', 'Refunded'),
+        ('Shipped', 'Delivered'),
+        ('Shipped', 'Refunded'),
+        ('Delivered', 'Refunded')
+) AS v (from_name, to_name)
+JOIN order_status f ON f.name = v.from_name
+JOIN order_status t ON t.name = v.to_name
+ON CONFLICT DO NOTHING;
+
+-- Who made each status change
+ALTER TABLE order_tracking
+ADD COLUMN IF NOT EXISTS actor_id bigint NULL,
+ADD COLUMN IF NOT EXISTS actor_role varchar(20) NULL;
+
+ALTER TABLE order_tracking ADD CONSTRAINT order_tracking_actor_id_fkey FOREIGN KEY (actor_id) REFERENCES users (id) ON DELETE SET NULL;
+
+-- Orders are created by their buyer
+UPDATE order_tracking ot
+SET actor_id = o.user_id,
+    actor_role = 'buyer'
+FROM orders o
+WHERE ot.order_id = o.id AND ot.actor_id IS NULL AND ot.notes = 'Order created';
+
+DROP FUNCTION IF EXISTS update_order_status(bigint, bigint, text);
+
+-- Function to update order status, rejecting transitions not listed in order_status_transitions
+CREATE OR REPLACE FUNCTION update_order_status(
+    p_order_id bigint,
+    p_status_id bigint,
+    p_notes text DEFAULT NULL,
+    p_actor_id bigint DEFAULT NULL,
+    p_actor_role varchar(20) DEFAULT NULL
+) RETURNS void AS $$
+DECLARE
+    v_current_status_id bigint;
+BEGIN
+    SELECT status_id INTO v_current_status_id FROM orders WHERE id = p_order_id FOR UPDATE;
+
+    IF v_current_status_id IS NULL THEN
+        RAISE EXCEPTION 'Order % tidak ditemukan', p_order_id USING ERRCODE = 'no_data_found';
+    END IF;
+
+    IF v_current_status_id <> p_status_id AND NOT EXISTS (
+        SELECT 1 FROM order_status_transitions
+        WHERE from_status_id = v_current_status_id AND to_status_id = p_status_id
+    ) THEN
+        RAISE EXCEPTION 'Perubahan status order % dari % ke % tidak diizinkan', p_order_id, v_current_status_id, p_status_id
+            USING ERRCODE = 'OS409';
+    END IF;
+
+    -- Update order status
+    UPDATE orders 
+    SET status_id = p_status_id,
+        updated_at = now()
+    WHERE id = p_order_id;
+    
+    -- Add tracking record
+    INSERT INTO order_tracking (order_id, status_id, notes, actor_id, actor_role)
+    VALUES (p_order_id, p_status_id, p_notes, p_actor_id, p_actor_role);
+END;
+$$ LANGUAGE plpgsql;
@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_order_items_toko_order;
+
+ALTER TABLE orders
+DROP COLUMN IF EXISTS tracking_number;
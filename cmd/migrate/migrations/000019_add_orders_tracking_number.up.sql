@@ -0,0 +1,6 @@
+-- Courier tracking number filled in by the seller when the order is shipped
+ALTER TABLE orders
+ADD COLUMN IF NOT EXISTS tracking_number varchar(100) NULL;
+
+-- Seller order listing filters order_items by toko and joins back to orders
+CREATE INDEX IF NOT EXISTS idx_order_items_toko_order ON order_items (toko_id, order_id);
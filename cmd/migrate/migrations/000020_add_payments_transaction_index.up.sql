@@ -0,0 +1,3 @@
+-- Gateway transaction IDs are looked up when the gateway calls back
+CREATE UNIQUE INDEX IF NOT EXISTS idx_payments_transaction_id ON payments (transaction_id)
+WHERE transaction_id IS NOT NULL;
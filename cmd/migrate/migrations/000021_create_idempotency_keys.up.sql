@@ -0,0 +1,16 @@
+-- Stored responses for requests sent with an Idempotency-Key header, used when Redis is disabled
+CREATE TABLE IF NOT EXISTS
+    idempotency_keys (
+        user_id bigint NOT NULL,
+        key varchar(255) NOT NULL,
+        request_hash varchar(64) NOT NULL,
+        status_code int DEFAULT 0 NOT NULL,
+        content_type varchar(100) NULL,
+        response_body bytea NULL,
+        created_at timestamptz (0) DEFAULT now () NOT NULL,
+        expires_at timestamptz (0) NOT NULL,
+        CONSTRAINT idempotency_keys_pkey PRIMARY KEY (user_id, key),
+        CONSTRAINT idempotency_keys_user_id_fkey FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+    );
+
+CREATE INDEX IF NOT EXISTS idx_idempotency_keys_expires_at ON idempotency_keys (expires_at);
@@ -0,0 +1,202 @@
+-- Berat dan dimensi paket per unit produk untuk menghitung berat volumetrik
+ALTER TABLE products
+ADD COLUMN IF NOT EXISTS weight_gram int4 DEFAULT 1000 NOT NULL,
+ADD COLUMN IF NOT EXISTS length_cm int4 DEFAULT 0 NOT NULL,
+ADD COLUMN IF NOT EXISTS width_cm int4 DEFAULT 0 NOT NULL,
+ADD COLUMN IF NOT EXISTS height_cm int4 DEFAULT 0 NOT NULL;
+
+-- Lokasi asal pengiriman toko dan lokasi tujuan alamat
+ALTER TABLE tokos
+ADD COLUMN IF NOT EXISTS latitude float8 NULL,
+ADD COLUMN IF NOT EXISTS longitude float8 NULL;
+
+ALTER TABLE shipping_addresses
+ADD COLUMN IF NOT EXISTS latitude float8 NULL,
+ADD COLUMN IF NOT EXISTS longitude float8 NULL;
+
+-- Tabel tarif per shipping method berdasarkan rentang jarak, max_distance_km NULL berarti tanpa batas
+CREATE TABLE
+    IF NOT EXISTS shipping_rates (
+        id bigserial NOT NULL,
+        shipping_method_id int8 NOT NULL,
+        min_distance_km float8 DEFAULT 0 NOT NULL,
+        max_distance_km float8 NULL,
+        base_price float8 NOT NULL,
+        price_per_kg float8 NOT NULL,
+        etd_min_days int4 DEFAULT 1 NOT NULL,
+        etd_max_days int4 DEFAULT 1 NOT NULL,
+        CONSTRAINT shipping_rates_pkey PRIMARY KEY (id),
+        CONSTRAINT shipping_rates_distance_check CHECK (
+            max_distance_km IS NULL
+            OR max_distance_km > min_distance_km
+        ),
+        CONSTRAINT shipping_rates_shipping_method_id_fkey FOREIGN KEY (shipping_method_id) REFERENCES shipping_methods (id) ON DELETE CASCADE
+    );
+
+CREATE INDEX IF NOT EXISTS idx_shipping_rates_method_distance ON shipping_rates USING btree (shipping_method_id, min_distance_km);
+
+-- Initial data for shipping rates
+INSERT INTO
+    shipping_rates (
+        shipping_method_id,
+        min_distance_km,
+        max_distance_km,
+        base_price,
+        price_per_kg,
+        etd_min_days,
+        etd_max_days
+    )
+SELECT
+    sm.id,
+    r.min_distance_km,
+    r.max_distance_km,
+    r.base_price,
+    r.price_per_kg,
+    r.etd_min_days,
+    r.etd_max_days
+FROM
+    (
+        VALUES
+            ('Standard Shipping', 0, 50, 5000, 4000, 1, 2),
+            ('Standard Shipping', 50, 500, 7000, 8000, 2, 4),
+            ('Standard Shipping', 500, NULL, 9000, 15000, 3, 5),
+            ('Express Shipping', 0, 50, 8000, 6000, 1, 1),
+            ('Express Shipping', 50, 500, 10000, 12000, 1, 2),
+            ('Express Shipping', 500, NULL, 12000, 22000, 2, 3),
+            ('Same Day Delivery', 0, 40, 15000, 9000, 0, 0)
+    ) AS r (
+        method_name,
+        min_distance_km,
+        max_distance_km,
+        base_price,
+        price_per_kg,
+        etd_min_days,
+        etd_max_days
+    )
+    JOIN shipping_methods sm ON sm.name = r.method_name;
+
+-- Ongkir dihitung oleh rate engine di aplikasi dan dikirim lewat p_shipping_cost,
+-- NULL tetap memakai harga flat shipping_methods.price
+DROP FUNCTION IF EXISTS public.create_order_from_cart(bigint, uuid, bigint, bigint, uuid, text);
+
+CREATE OR REPLACE FUNCTION public.create_order_from_cart(p_user_id bigint, p_cart_store_id uuid, p_payment_method_id bigint, p_shipping_method_id bigint, p_shipping_addresses_id uuid, p_notes text DEFAULT NULL::text, p_shipping_cost float8 DEFAULT NULL::float8)
+ RETURNS bigint
+ LANGUAGE plpgsql
+AS $function$
+DECLARE
+    v_order_id bigint;
+    v_shipping_cost float8;
+    v_total_price float8 := 0;
+    v_final_price float8;
+    v_order_number varchar(50);
+    v_cart_item record;
+    v_cart_id bigint;
+BEGIN
+    -- Dapatkan cart_id dan verifikasi kepemilikan user
+    SELECT cs.cart_id INTO v_cart_id 
+    FROM cart_stores cs
+    JOIN carts c ON cs.cart_id = c.id
+    WHERE cs.id = p_cart_store_id AND c.user_id = p_user_id;
+    
+    IF v_cart_id IS NULL THEN
+        RAISE EXCEPTION 'Cart store dengan ID % tidak ditemukan atau bukan milik user %', p_cart_store_id, p_user_id;
+    END IF;
+    
+    -- Get shipping cost
+    SELECT price INTO v_shipping_cost FROM shipping_methods WHERE id = p_shipping_method_id;
+    IF v_shipping_cost IS NULL THEN
+        RAISE EXCEPTION 'Invalid shipping method ID %', p_shipping_method_id;
+    END IF;
+
+    IF p_shipping_cost IS NOT NULL THEN
+        IF p_shipping_cost < 0 THEN
+            RAISE EXCEPTION 'Invalid shipping cost %', p_shipping_cost;
+        END IF;
+        v_shipping_cost := p_shipping_cost;
+    END IF;
+    
+    -- Generate order number
+    v_order_number := generate_order_number();
+    
+    -- Create order
+    INSERT INTO orders (
+        user_id,
+        order_number,
+        status_id,
+        payment_method_id,
+        shipping_method_id,
+        shipping_addresses_id,
+        shipping_cost,
+        total_price,
+        final_price,
+        notes
+    ) VALUES (
+        p_user_id,
+        v_order_number,
+        1, -- Pending status
+        p_payment_method_id,
+        p_shipping_method_id,
+        p_shipping_addresses_id,
+        v_shipping_cost,
+        0, -- Will be calculated
+        0, -- Will be calculated
+        p_notes
+    ) RETURNING id INTO v_order_id;
+    
+    -- Process cart items
+    FOR v_cart_item IN SELECT * FROM cart_items WHERE cart_store_id = p_cart_store_id
+    LOOP
+        -- Add order item
+        INSERT INTO order_items (
+            order_id,
+            product_id,
+            toko_id,
+            quantity,
+            price,
+            discount_price,
+            discount,
+            subtotal
+        ) VALUES (
+            v_order_id,
+            v_cart_item.product_id,
+            (SELECT toko_id FROM cart_stores WHERE id = p_cart_store_id),
+            v_cart_item.quantity,
+            (SELECT price FROM products WHERE id = v_cart_item.product_id),
+            (SELECT discount_price FROM products WHERE id = v_cart_item.product_id),
+            (SELECT discount FROM products WHERE id = v_cart_item.product_id),
+            (SELECT price FROM products WHERE id = v_cart_item.product_id) * v_cart_item.quantity
+        );
+        
+        -- Update total price
+        v_total_price := v_total_price + (SELECT price FROM products WHERE id = v_cart_item.product_id) * v_cart_item.quantity;
+        
+        -- Update product stock and sold count
+        UPDATE products 
+        SET stock = stock - v_cart_item.quantity, 
+            sold = sold + v_cart_item.quantity,
+            updated_at = now()
+        WHERE id = v_cart_item.product_id;
+    END LOOP;
+    
+    -- Calculate final price (total + shipping)
+    v_final_price := v_total_price + v_shipping_cost;
+    
+    -- Update order with calculated prices
+    UPDATE orders 
+    SET total_price = v_total_price,
+        final_price = v_final_price,
+        updated_at = now()
+    WHERE id = v_order_id;
+    
+    -- Add initial order tracking
+    INSERT INTO order_tracking (order_id, status_id, notes)
+    VALUES (v_order_id, 1, 'Order created');
+    
+    -- Clear the cart
+    DELETE FROM cart_items WHERE cart_store_id = p_cart_store_id;
+    DELETE FROM cart_stores WHERE id = p_cart_store_id;
+    
+    RETURN v_order_id;
+END;
+$function$
+;
@@ -0,0 +1,10 @@
+ALTER TABLE shipping_addresses
+DROP CONSTRAINT IF EXISTS shipping_addresses_region_id_fkey,
+DROP COLUMN IF EXISTS region_id,
+DROP COLUMN IF EXISTS province,
+DROP COLUMN IF EXISTS city,
+DROP COLUMN IF EXISTS district,
+DROP COLUMN IF EXISTS sub_district,
+DROP COLUMN IF EXISTS postal_code;
+
+DROP TABLE IF EXISTS regions;
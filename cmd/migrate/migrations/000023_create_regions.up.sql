@@ -0,0 +1,68 @@
+-- Referensi wilayah bertingkat: 1 provinsi, 2 kota/kabupaten, 3 kecamatan, 4 kelurahan/desa.
+-- id memakai kode wilayah Kemendagri, kode pos dan koordinat pusat wilayah bersifat opsional
+CREATE TABLE
+    IF NOT EXISTS regions (
+        id varchar(13) NOT NULL,
+        parent_id varchar(13) NULL,
+        level int2 NOT NULL,
+        name varchar(100) NOT NULL,
+        postal_code varchar(5) NULL,
+        latitude float8 NULL,
+        longitude float8 NULL,
+        CONSTRAINT regions_pkey PRIMARY KEY (id),
+        CONSTRAINT regions_level_check CHECK (level BETWEEN 1 AND 4),
+        CONSTRAINT regions_parent_id_fkey FOREIGN KEY (parent_id) REFERENCES regions (id)
+    );
+
+CREATE INDEX IF NOT EXISTS idx_regions_parent_name ON regions USING btree (parent_id, name);
+
+CREATE INDEX IF NOT EXISTS idx_regions_postal_code ON regions USING btree (postal_code)
+WHERE
+    postal_code IS NOT NULL;
+
+-- Alamat menyimpan kelurahan terpilih beserta nama wilayahnya saat alamat dibuat
+ALTER TABLE shipping_addresses
+ADD COLUMN IF NOT EXISTS region_id varchar(13) NULL,
+ADD COLUMN IF NOT EXISTS province varchar(100) DEFAULT '' NOT NULL,
+ADD COLUMN IF NOT EXISTS city varchar(100) DEFAULT '' NOT NULL,
+ADD COLUMN IF NOT EXISTS district varchar(100) DEFAULT '' NOT NULL,
+ADD COLUMN IF NOT EXISTS sub_district varchar(100) DEFAULT '' NOT NULL,
+ADD COLUMN IF NOT EXISTS postal_code varchar(5) DEFAULT '' NOT NULL,
+ADD CONSTRAINT shipping_addresses_region_id_fkey FOREIGN KEY (region_id) REFERENCES regions (id);
+
+-- Initial data for regions
+INSERT INTO
+    regions (id, parent_id, level, name, postal_code, latitude, longitude)
+VALUES
+    ('31', NULL, 1, 'DKI Jakarta', NULL, -6.2088, 106.8456),
+    ('31.71', '31', 2, 'Kota Jakarta Pusat', NULL, -6.1864, 106.8341),
+    ('31.71.06', '31.71', 3, 'Menteng', NULL, -6.1963, 106.8326),
+    ('31.71.06.1001', '31.71.06', 4, 'Menteng', '10310', -6.1956, 106.8322),
+    ('31.71.06.1002', '31.71.06', 4, 'Pegangsaan', '10320', -6.2036, 106.8410),
+    ('31.71.06.1003', '31.71.06', 4, 'Cikini', '10330', -6.1892, 106.8396),
+    ('31.71.06.1004', '31.71.06', 4, 'Gondangdia', '10350', -6.1869, 106.8307),
+    ('31.74', '31', 2, 'Kota Jakarta Selatan', NULL, -6.2615, 106.8106),
+    ('31.74.07', '31.74', 3, 'Kebayoran Baru', NULL, -6.2425, 106.7985),
+    ('31.74.07.1001', '31.74.07', 4, 'Selong', '12110', -6.2367, 106.8009),
+    ('31.74.07.1002', '31.74.07', 4, 'Gunung', '12120', -6.2347, 106.7928),
+    ('31.74.07.1003', '31.74.07', 4, 'Kramat Pela', '12130', -6.2433, 106.7868),
+    ('31.74.07.1004', '31.74.07', 4, 'Gandaria Utara', '12140', -6.2519, 106.7926),
+    ('31.74.07.1006', '31.74.07', 4, 'Melawai', '12160', -6.2440, 106.8016),
+    ('31.74.07.1010', '31.74.07', 4, 'Senayan', '12190', -6.2280, 106.8037),
+    ('32', NULL, 1, 'Jawa Barat', NULL, -6.9039, 107.6186),
+    ('32.73', '32', 2, 'Kota Bandung', NULL, -6.9175, 107.6191),
+    ('32.73.02', '32.73', 3, 'Coblong', NULL, -6.8870, 107.6149),
+    ('32.73.02.1001', '32.73.02', 4, 'Cipaganti', '40131', -6.8925, 107.6049),
+    ('32.73.02.1002', '32.73.02', 4, 'Lebak Gede', '40132', -6.8864, 107.6175),
+    ('32.73.02.1004', '32.73.02', 4, 'Dago', '40135', -6.8777, 107.6168),
+    ('32.75', '32', 2, 'Kota Bekasi', NULL, -6.2383, 106.9756),
+    ('32.75.01', '32.75', 3, 'Bekasi Timur', NULL, -6.2478, 107.0125),
+    ('32.75.01.1001', '32.75.01', 4, 'Margahayu', '17113', -6.2558, 107.0081),
+    ('32.75.01.1002', '32.75.01', 4, 'Bekasi Jaya', '17112', -6.2386, 107.0158),
+    ('35', NULL, 1, 'Jawa Timur', NULL, -7.2504, 112.7688),
+    ('35.78', '35', 2, 'Kota Surabaya', NULL, -7.2575, 112.7521),
+    ('35.78.05', '35.78', 3, 'Genteng', NULL, -7.2590, 112.7426),
+    ('35.78.05.1001', '35.78.05', 4, 'Embong Kaliasin', '60271', -7.2661, 112.7413),
+    ('35.78.05.1004', '35.78.05', 4, 'Genteng', '60275', -7.2568, 112.7449),
+    ('35.78.05.1005', '35.78.05', 4, 'Peneleh', '60274', -7.2511, 112.7401)
+ON CONFLICT (id) DO NOTHING;
@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS idx_orders_shipping_addresses_id;
+
+DROP INDEX IF EXISTS idx_shipping_addresses_user_active;
+
+ALTER TABLE shipping_addresses
+DROP COLUMN IF EXISTS deleted_at;
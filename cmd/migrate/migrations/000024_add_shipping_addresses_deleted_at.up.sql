@@ -0,0 +1,9 @@
+-- Alamat yang sudah dipakai order dihapus secara soft delete agar riwayat order tetap utuh
+ALTER TABLE shipping_addresses
+ADD COLUMN IF NOT EXISTS deleted_at timestamptz (0) NULL;
+
+CREATE INDEX IF NOT EXISTS idx_shipping_addresses_user_active ON shipping_addresses USING btree (user_id)
+WHERE
+    deleted_at IS NULL;
+
+CREATE INDEX IF NOT EXISTS idx_orders_shipping_addresses_id ON orders USING btree (shipping_addresses_id);
@@ -0,0 +1,252 @@
+-- Voucher: potongan persentase, nominal tetap atau gratis ongkir, berlaku di semua toko
+-- (toko_id NULL) atau satu toko saja
+CREATE TABLE
+    IF NOT EXISTS vouchers (
+        id bigserial NOT NULL,
+        code varchar(50) NOT NULL,
+        name varchar(100) NOT NULL,
+        description text NULL,
+        type varchar(20) NOT NULL,
+        value float8 DEFAULT 0 NOT NULL,
+        max_discount float8 NULL,
+        min_spend float8 DEFAULT 0 NOT NULL,
+        toko_id int8 NULL,
+        usage_limit int4 NULL,
+        usage_limit_per_user int4 NULL,
+        used_count int4 DEFAULT 0 NOT NULL,
+        starts_at timestamptz (0) NOT NULL,
+        ends_at timestamptz (0) NOT NULL,
+        is_active boolean DEFAULT true NOT NULL,
+        created_at timestamptz (0) DEFAULT now () NOT NULL,
+        updated_at timestamptz (0) DEFAULT now () NOT NULL,
+        CONSTRAINT vouchers_pkey PRIMARY KEY (id),
+        CONSTRAINT vouchers_code_key UNIQUE (code),
+        CONSTRAINT vouchers_type_check CHECK (type IN ('percentage', 'fixed', 'free_shipping')),
+        CONSTRAINT vouchers_value_check CHECK (
+            value >= 0
+            AND (type <> 'percentage' OR value <= 100)
+        ),
+        CONSTRAINT vouchers_period_check CHECK (ends_at > starts_at),
+        CONSTRAINT vouchers_toko_id_fkey FOREIGN KEY (toko_id) REFERENCES tokos (id) ON DELETE CASCADE
+    );
+
+-- Satu baris per checkout yang memakai voucher, dipakai untuk kuota per user
+CREATE TABLE
+    IF NOT EXISTS voucher_usages (
+        id bigserial NOT NULL,
+        voucher_id int8 NOT NULL,
+        user_id int8 NOT NULL,
+        session_id varchar(64) NOT NULL,
+        discount float8 NOT NULL,
+        created_at timestamptz (0) DEFAULT now () NOT NULL,
+        CONSTRAINT voucher_usages_pkey PRIMARY KEY (id),
+        CONSTRAINT voucher_usages_session_key UNIQUE (voucher_id, session_id),
+        CONSTRAINT voucher_usages_voucher_id_fkey FOREIGN KEY (voucher_id) REFERENCES vouchers (id) ON DELETE CASCADE,
+        CONSTRAINT voucher_usages_user_id_fkey FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+    );
+
+CREATE INDEX IF NOT EXISTS idx_voucher_usages_voucher_user ON voucher_usages USING btree (voucher_id, user_id);
+
+ALTER TABLE orders
+ADD COLUMN IF NOT EXISTS discount float8 DEFAULT 0 NOT NULL,
+ADD COLUMN IF NOT EXISTS voucher_id int8 NULL,
+ADD COLUMN IF NOT EXISTS voucher_usage_id int8 NULL, -- pemakaian voucher dikembalikan jika semua order dari checkout yang sama dibatalkan
+ADD CONSTRAINT orders_voucher_id_fkey FOREIGN KEY (voucher_id) REFERENCES vouchers (id) ON DELETE SET NULL,
+ADD CONSTRAINT orders_voucher_usage_id_fkey FOREIGN KEY (voucher_usage_id) REFERENCES voucher_usages (id) ON DELETE SET NULL;
+
+CREATE INDEX IF NOT EXISTS idx_orders_voucher_usage_id ON orders (voucher_usage_id);
+
+-- View ringkasan order untuk OrderDetail
+CREATE OR REPLACE VIEW order_details AS
+SELECT
+    o.id,
+    o.order_number,
+    u.username AS customer_name,
+    u.email AS customer_email,
+    os.name AS status,
+    pm.name AS payment_method,
+    sm.name AS shipping_method,
+    o.shipping_cost,
+    o.total_price,
+    o.discount,
+    o.final_price,
+    o.notes,
+    o.created_at,
+    o.updated_at
+FROM
+    orders o
+    JOIN users u ON o.user_id = u.id
+    JOIN order_status os ON o.status_id = os.id
+    JOIN payment_methods pm ON o.payment_method_id = pm.id
+    JOIN shipping_methods sm ON o.shipping_method_id = sm.id;
+
+DROP FUNCTION IF EXISTS public.create_order_from_cart(bigint, uuid, bigint, bigint, uuid, text, float8);
+
+CREATE OR REPLACE FUNCTION public.create_order_from_cart(p_user_id bigint, p_cart_store_id uuid, p_payment_method_id bigint, p_shipping_method_id bigint, p_shipping_addresses_id uuid, p_notes text DEFAULT NULL::text, p_shipping_cost float8 DEFAULT NULL::float8, p_discount float8 DEFAULT 0, p_voucher_id bigint DEFAULT NULL::bigint)
+ RETURNS bigint
+ LANGUAGE plpgsql
+AS $function$
+DECLARE
+    v_order_id bigint;
+    v_shipping_cost float8;
+    v_total_price float8 := 0;
+    v_final_price float8;
+    v_order_number varchar(50);
+    v_cart_item record;
+    v_cart_id bigint;
+    v_address record;
+BEGIN
+    -- Dapatkan cart_id dan verifikasi kepemilikan user
+    SELECT cs.cart_id INTO v_cart_id 
+    FROM cart_stores cs
+    JOIN carts c ON cs.cart_id = c.id
+    WHERE cs.id = p_cart_store_id AND c.user_id = p_user_id;
+    
+    IF v_cart_id IS NULL THEN
+        RAISE EXCEPTION 'Cart store dengan ID % tidak ditemukan atau bukan milik user %', p_cart_store_id, p_user_id;
+    END IF;
+    
+    -- Snapshot alamat pengiriman, alamat harus milik user dan belum dihapus
+    SELECT
+        sa.recipient_name,
+        sa.recipient_phone,
+        concat_ws(', ', NULLIF(sa.address_line1, ''), NULLIF(sa.sub_district, ''), NULLIF(sa.district, ''),
+            NULLIF(sa.city, ''), NULLIF(sa.province, ''), NULLIF(sa.postal_code, '')) AS address,
+        COALESCE(sa.note_for_courier, '') AS note
+    INTO v_address
+    FROM shipping_addresses sa
+    WHERE sa.id = p_shipping_addresses_id AND sa.user_id = p_user_id AND sa.deleted_at IS NULL;
+
+    IF NOT FOUND THEN
+        RAISE EXCEPTION 'Shipping address % tidak ditemukan atau bukan milik user %', p_shipping_addresses_id, p_user_id;
+    END IF;
+
+    -- Get shipping cost
+    SELECT price INTO v_shipping_cost FROM shipping_methods WHERE id = p_shipping_method_id;
+    IF v_shipping_cost IS NULL THEN
+        RAISE EXCEPTION 'Invalid shipping method ID %', p_shipping_method_id;
+    END IF;
+
+    IF p_shipping_cost IS NOT NULL THEN
+        IF p_shipping_cost < 0 THEN
+            RAISE EXCEPTION 'Invalid shipping cost %', p_shipping_cost;
+        END IF;
+        v_shipping_cost := p_shipping_cost;
+    END IF;
+    
+    -- Generate order number
+    v_order_number := generate_order_number();
+    
+    -- Create order
+    INSERT INTO orders (
+        user_id,
+        order_number,
+        status_id,
+        payment_method_id,
+        shipping_method_id,
+        shipping_addresses_id,
+        shipping_recipient_name,
+        shipping_recipient_phone,
+        shipping_address,
+        shipping_note,
+        shipping_cost,
+        total_price,
+        discount,
+        voucher_id,
+        final_price,
+        notes
+    ) VALUES (
+        p_user_id,
+        v_order_number,
+        1, -- Pending status
+        p_payment_method_id,
+        p_shipping_method_id,
+        p_shipping_addresses_id,
+        v_address.recipient_name,
+        v_address.recipient_phone,
+        v_address.address,
+        v_address.note,
+        v_shipping_cost,
+        0, -- Will be calculated
+        0, -- Will be calculated
+        p_voucher_id,
+        0, -- Will be calculated
+        p_notes
+    ) RETURNING id INTO v_order_id;
+    
+    -- Process cart items
+    FOR v_cart_item IN
+        SELECT ci.product_id, ci.quantity, cs.toko_id,
+               p.name, p.slug, COALESCE(p.image_urls[1], '') AS image,
+               p.price, p.discount_price, p.discount
+        FROM cart_items ci
+        JOIN cart_stores cs ON ci.cart_store_id = cs.id
+        JOIN products p ON ci.product_id = p.id
+        WHERE ci.cart_store_id = p_cart_store_id
+    LOOP
+        -- Add order item beserta snapshot produk
+        INSERT INTO order_items (
+            order_id,
+            product_id,
+            toko_id,
+            product_name,
+            product_slug,
+            product_image,
+            quantity,
+            price,
+            discount_price,
+            discount,
+            subtotal
+        ) VALUES (
+            v_order_id,
+            v_cart_item.product_id,
+            v_cart_item.toko_id,
+            v_cart_item.name,
+            v_cart_item.slug,
+            v_cart_item.image,
+            v_cart_item.quantity,
+            v_cart_item.price,
+            v_cart_item.discount_price,
+            v_cart_item.discount,
+            v_cart_item.price * v_cart_item.quantity
+        );
+        
+        -- Update total price
+        v_total_price := v_total_price + v_cart_item.price * v_cart_item.quantity;
+        
+        -- Update product stock and sold count
+        UPDATE products 
+        SET stock = stock - v_cart_item.quantity, 
+            sold = sold + v_cart_item.quantity,
+            updated_at = now()
+        WHERE id = v_cart_item.product_id;
+    END LOOP;
+    
+    -- Potongan voucher tidak boleh negatif atau melebihi total + ongkir
+    IF COALESCE(p_discount, 0) < 0 OR COALESCE(p_discount, 0) > v_total_price + v_shipping_cost THEN
+        RAISE EXCEPTION 'Invalid discount % for order total %', p_discount, v_total_price + v_shipping_cost;
+    END IF;
+
+    -- Calculate final price (total + shipping - discount)
+    v_final_price := v_total_price + v_shipping_cost - COALESCE(p_discount, 0);
+    
+    -- Update order with calculated prices
+    UPDATE orders 
+    SET total_price = v_total_price,
+        discount = COALESCE(p_discount, 0),
+        final_price = v_final_price,
+        updated_at = now()
+    WHERE id = v_order_id;
+    
+    -- Add initial order tracking
+    INSERT INTO order_tracking (order_id, status_id, notes)
+    VALUES (v_order_id, 1, 'Order created');
+    
+    -- Clear the cart
+    DELETE FROM cart_items WHERE cart_store_id = p_cart_store_id;
+    DELETE FROM cart_stores WHERE id = p_cart_store_id;
+    
+    RETURN v_order_id;
+END;
+$function$
+;
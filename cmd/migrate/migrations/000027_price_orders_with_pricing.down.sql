@@ -0,0 +1,170 @@
+DROP FUNCTION IF EXISTS public.create_order_from_cart(bigint, uuid, bigint, bigint, uuid, text, float8, float8, bigint, jsonb);
+
+CREATE OR REPLACE FUNCTION public.create_order_from_cart(p_user_id bigint, p_cart_store_id uuid, p_payment_method_id bigint, p_shipping_method_id bigint, p_shipping_addresses_id uuid, p_notes text DEFAULT NULL::text, p_shipping_cost float8 DEFAULT NULL::float8, p_discount float8 DEFAULT 0, p_voucher_id bigint DEFAULT NULL::bigint)
+ RETURNS bigint
+ LANGUAGE plpgsql
+AS $function$
+DECLARE
+    v_order_id bigint;
+    v_shipping_cost float8;
+    v_total_price float8 := 0;
+    v_final_price float8;
+    v_order_number varchar(50);
+    v_cart_item record;
+    v_cart_id bigint;
+    v_address record;
+BEGIN
+    -- Dapatkan cart_id dan verifikasi kepemilikan user
+    SELECT cs.cart_id INTO v_cart_id 
+    FROM cart_stores cs
+    JOIN carts c ON cs.cart_id = c.id
+    WHERE cs.id = p_cart_store_id AND c.user_id = p_user_id;
+    
+    IF v_cart_id IS NULL THEN
+        RAISE EXCEPTION 'Cart store dengan ID % tidak ditemukan atau bukan milik user %', p_cart_store_id, p_user_id;
+    END IF;
+    
+    -- Snapshot alamat pengiriman, alamat harus milik user dan belum dihapus
+    SELECT
+        sa.recipient_name,
+        sa.recipient_phone,
+        concat_ws(', ', NULLIF(sa.address_line1, ''), NULLIF(sa.sub_district, ''), NULLIF(sa.district, ''),
+            NULLIF(sa.city, ''), NULLIF(sa.province, ''), NULLIF(sa.postal_code, '')) AS address,
+        COALESCE(sa.note_for_courier, '') AS note
+    INTO v_address
+    FROM shipping_addresses sa
+    WHERE sa.id = p_shipping_addresses_id AND sa.user_id = p_user_id AND sa.deleted_at IS NULL;
+
+    IF NOT FOUND THEN
+        RAISE EXCEPTION 'Shipping address % tidak ditemukan atau bukan milik user %', p_shipping_addresses_id, p_user_id;
+    END IF;
+
+    -- Get shipping cost
+    SELECT price INTO v_shipping_cost FROM shipping_methods WHERE id = p_shipping_method_id;
+    IF v_shipping_cost IS NULL THEN
+        RAISE EXCEPTION 'Invalid shipping method ID %', p_shipping_method_id;
+    END IF;
+
+    IF p_shipping_cost IS NOT NULL THEN
+        IF p_shipping_cost < 0 THEN
+            RAISE EXCEPTION 'Invalid shipping cost %', p_shipping_cost;
+        END IF;
+        v_shipping_cost := p_shipping_cost;
+    END IF;
+    
+    -- Generate order number
+    v_order_number := generate_order_number();
+    
+    -- Create order
+    INSERT INTO orders (
+        user_id,
+        order_number,
+        status_id,
+        payment_method_id,
+        shipping_method_id,
+        shipping_addresses_id,
+        shipping_recipient_name,
+        shipping_recipient_phone,
+        shipping_address,
+        shipping_note,
+        shipping_cost,
+        total_price,
+        discount,
+        voucher_id,
+        final_price,
+        notes
+    ) VALUES (
+        p_user_id,
+        v_order_number,
+        1, -- Pending status
+        p_payment_method_id,
+        p_shipping_method_id,
+        p_shipping_addresses_id,
+        v_address.recipient_name,
+        v_address.recipient_phone,
+        v_address.address,
+        v_address.note,
+        v_shipping_cost,
+        0, -- Will be calculated
+        0, -- Will be calculated
+        p_voucher_id,
+        0, -- Will be calculated
+        p_notes
+    ) RETURNING id INTO v_order_id;
+    
+    -- Process cart items
+    FOR v_cart_item IN
+        SELECT ci.product_id, ci.quantity, cs.toko_id,
+               p.name, p.slug, COALESCE(p.image_urls[1], '') AS image,
+               p.price, p.discount_price, p.discount
+        FROM cart_items ci
+        JOIN cart_stores cs ON ci.cart_store_id = cs.id
+        JOIN products p ON ci.product_id = p.id
+        WHERE ci.cart_store_id = p_cart_store_id
+    LOOP
+        -- Add order item beserta snapshot produk
+        INSERT INTO order_items (
+            order_id,
+            product_id,
+            toko_id,
+            product_name,
+            product_slug,
+            product_image,
+            quantity,
+            price,
+            discount_price,
+            discount,
+            subtotal
+        ) VALUES (
+            v_order_id,
+            v_cart_item.product_id,
+            v_cart_item.toko_id,
+            v_cart_item.name,
+            v_cart_item.slug,
+            v_cart_item.image,
+            v_cart_item.quantity,
+            v_cart_item.price,
+            v_cart_item.discount_price,
+            v_cart_item.discount,
+            v_cart_item.price * v_cart_item.quantity
+        );
+        
+        -- Update total price
+        v_total_price := v_total_price + v_cart_item.price * v_cart_item.quantity;
+        
+        -- Update product stock and sold count
+        UPDATE products 
+        SET stock = stock - v_cart_item.quantity, 
+            sold = sold + v_cart_item.quantity,
+            updated_at = now()
+        WHERE id = v_cart_item.product_id;
+    END LOOP;
+    
+    -- Potongan voucher tidak boleh negatif atau melebihi total + ongkir
+    IF COALESCE(p_discount, 0) < 0 OR COALESCE(p_discount, 0) > v_total_price + v_shipping_cost THEN
+        RAISE EXCEPTION 'Invalid discount % for order total %', p_discount, v_total_price + v_shipping_cost;
+    END IF;
+
+    -- Calculate final price (total + shipping - discount)
+    v_final_price := v_total_price + v_shipping_cost - COALESCE(p_discount, 0);
+    
+    -- Update order with calculated prices
+    UPDATE orders 
+    SET total_price = v_total_price,
+        discount = COALESCE(p_discount, 0),
+        final_price = v_final_price,
+        updated_at = now()
+    WHERE id = v_order_id;
+    
+    -- Add initial order tracking
+    INSERT INTO order_tracking (order_id, status_id, notes)
+    VALUES (v_order_id, 1, 'Order created');
+    
+    -- Clear the cart
+    DELETE FROM cart_items WHERE cart_store_id = p_cart_store_id;
+    DELETE FROM cart_stores WHERE id = p_cart_store_id;
+    
+    RETURN v_order_id;
+END;
+$function$
+;
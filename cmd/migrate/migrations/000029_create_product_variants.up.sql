@@ -0,0 +1,296 @@
+-- Varian produk: jenis opsi (warna, ukuran, penyimpanan), nilai opsi, dan satu SKU per kombinasi
+-- nilai opsi dengan harga, stok dan gambar sendiri
+CREATE TABLE
+    IF NOT EXISTS product_option_types (
+        id bigserial NOT NULL,
+        product_id int8 NOT NULL,
+        name varchar(50) NOT NULL,
+        position int4 DEFAULT 0 NOT NULL,
+        CONSTRAINT product_option_types_pkey PRIMARY KEY (id),
+        CONSTRAINT product_option_types_product_name_key UNIQUE (product_id, name),
+        CONSTRAINT product_option_types_product_id_fkey FOREIGN KEY (product_id) REFERENCES products (id) ON DELETE CASCADE
+    );
+
+CREATE TABLE
+    IF NOT EXISTS product_option_values (
+        id bigserial NOT NULL,
+        option_type_id int8 NOT NULL,
+        value varchar(100) NOT NULL,
+        position int4 DEFAULT 0 NOT NULL,
+        CONSTRAINT product_option_values_pkey PRIMARY KEY (id),
+        CONSTRAINT product_option_values_type_value_key UNIQUE (option_type_id, value),
+        CONSTRAINT product_option_values_option_type_id_fkey FOREIGN KEY (option_type_id) REFERENCES product_option_types (id) ON DELETE CASCADE
+    );
+
+CREATE TABLE
+    IF NOT EXISTS product_variants (
+        id bigserial NOT NULL,
+        product_id int8 NOT NULL,
+        sku varchar(100) NOT NULL,
+        name varchar(255) NOT NULL,
+        price bigint NOT NULL,
+        discount_price bigint DEFAULT 0 NOT NULL,
+        stock int4 DEFAULT 0 NOT NULL,
+        image_url text NULL,
+        is_active boolean DEFAULT true NOT NULL,
+        position int4 DEFAULT 0 NOT NULL,
+        version int4 DEFAULT 0 NOT NULL,
+        created_at timestamptz (0) DEFAULT now () NOT NULL,
+        updated_at timestamptz (0) DEFAULT now () NOT NULL,
+        CONSTRAINT product_variants_pkey PRIMARY KEY (id),
+        CONSTRAINT product_variants_sku_key UNIQUE (sku),
+        CONSTRAINT product_variants_price_check CHECK (price >= 0 AND discount_price >= 0),
+        CONSTRAINT product_variants_product_id_fkey FOREIGN KEY (product_id) REFERENCES products (id) ON DELETE CASCADE
+    );
+
+CREATE INDEX idx_product_variants_product_id ON product_variants USING btree (product_id);
+
+-- Kombinasi nilai opsi setiap varian, satu nilai per jenis opsi
+CREATE TABLE
+    IF NOT EXISTS product_variant_values (
+        variant_id int8 NOT NULL,
+        option_value_id int8 NOT NULL,
+        CONSTRAINT product_variant_values_pkey PRIMARY KEY (variant_id, option_value_id),
+        CONSTRAINT product_variant_values_variant_id_fkey FOREIGN KEY (variant_id) REFERENCES product_variants (id) ON DELETE CASCADE,
+        CONSTRAINT product_variant_values_option_value_id_fkey FOREIGN KEY (option_value_id) REFERENCES product_option_values (id) ON DELETE CASCADE
+    );
+
+ALTER TABLE cart_items
+ADD COLUMN IF NOT EXISTS variant_id int8 NULL,
+ADD CONSTRAINT fk_variant FOREIGN KEY (variant_id) REFERENCES product_variants (id) ON DELETE CASCADE;
+
+CREATE INDEX idx_cart_items_variant_id ON cart_items USING btree (variant_id);
+
+-- Snapshot varian di order item, variant_id dikosongkan jika varian dihapus
+ALTER TABLE order_items
+ADD COLUMN IF NOT EXISTS variant_id int8 NULL,
+ADD COLUMN IF NOT EXISTS variant_sku varchar(100) NULL,
+ADD COLUMN IF NOT EXISTS variant_name varchar(255) NULL,
+ADD CONSTRAINT order_items_variant_id_fkey FOREIGN KEY (variant_id) REFERENCES product_variants (id) ON DELETE SET NULL;
+
+CREATE OR REPLACE FUNCTION public.create_order_from_cart(p_user_id bigint, p_cart_store_id uuid, p_payment_method_id bigint, p_shipping_method_id bigint, p_shipping_addresses_id uuid, p_notes text DEFAULT NULL::text, p_shipping_cost bigint DEFAULT NULL::bigint, p_discount bigint DEFAULT 0, p_voucher_id bigint DEFAULT NULL::bigint, p_item_prices jsonb DEFAULT NULL::jsonb)
+ RETURNS bigint
+ LANGUAGE plpgsql
+AS $function$
+DECLARE
+    v_order_id bigint;
+    v_shipping_cost bigint;
+    v_total_price bigint := 0;
+    v_final_price bigint;
+    v_order_number varchar(50);
+    v_cart_item record;
+    v_item_price jsonb;
+    v_cart_id bigint;
+    v_address record;
+    v_variant_stock int4;
+BEGIN
+    -- Dapatkan cart_id dan verifikasi kepemilikan user
+    SELECT cs.cart_id INTO v_cart_id 
+    FROM cart_stores cs
+    JOIN carts c ON cs.cart_id = c.id
+    WHERE cs.id = p_cart_store_id AND c.user_id = p_user_id;
+    
+    IF v_cart_id IS NULL THEN
+        RAISE EXCEPTION 'Cart store dengan ID % tidak ditemukan atau bukan milik user %', p_cart_store_id, p_user_id;
+    END IF;
+    
+    -- Snapshot alamat pengiriman, alamat harus milik user dan belum dihapus
+    SELECT
+        sa.recipient_name,
+        sa.recipient_phone,
+        concat_ws(', ', NULLIF(sa.address_line1, ''), NULLIF(sa.sub_district, ''), NULLIF(sa.district, ''),
+            NULLIF(sa.city, ''), NULLIF(sa.province, ''), NULLIF(sa.postal_code, '')) AS address,
+        COALESCE(sa.note_for_courier, '') AS note
+    INTO v_address
+    FROM shipping_addresses sa
+    WHERE sa.id = p_shipping_addresses_id AND sa.user_id = p_user_id AND sa.deleted_at IS NULL;
+
+    IF NOT FOUND THEN
+        RAISE EXCEPTION 'Shipping address % tidak ditemukan atau bukan milik user %', p_shipping_addresses_id, p_user_id;
+    END IF;
+
+    -- Get shipping cost
+    SELECT price INTO v_shipping_cost FROM shipping_methods WHERE id = p_shipping_method_id;
+    IF v_shipping_cost IS NULL THEN
+        RAISE EXCEPTION 'Invalid shipping method ID %', p_shipping_method_id;
+    END IF;
+
+    IF p_shipping_cost IS NOT NULL THEN
+        IF p_shipping_cost < 0 THEN
+            RAISE EXCEPTION 'Invalid shipping cost %', p_shipping_cost;
+        END IF;
+        v_shipping_cost := p_shipping_cost;
+    END IF;
+    
+    -- Generate order number
+    v_order_number := generate_order_number();
+    
+    -- Create order
+    INSERT INTO orders (
+        user_id,
+        order_number,
+        status_id,
+        payment_method_id,
+        shipping_method_id,
+        shipping_addresses_id,
+        shipping_recipient_name,
+        shipping_recipient_phone,
+        shipping_address,
+        shipping_note,
+        shipping_cost,
+        total_price,
+        discount,
+        voucher_id,
+        final_price,
+        notes
+    ) VALUES (
+        p_user_id,
+        v_order_number,
+        1, -- Pending status
+        p_payment_method_id,
+        p_shipping_method_id,
+        p_shipping_addresses_id,
+        v_address.recipient_name,
+        v_address.recipient_phone,
+        v_address.address,
+        v_address.note,
+        v_shipping_cost,
+        0, -- Will be calculated
+        0, -- Will be calculated
+        p_voucher_id,
+        0, -- Will be calculated
+        p_notes
+    ) RETURNING id INTO v_order_id;
+    
+    -- Process cart items
+    FOR v_cart_item IN
+        SELECT ci.id, ci.product_id, ci.variant_id, ci.quantity, cs.toko_id,
+               p.name, p.slug, COALESCE(NULLIF(v.image_url, ''), p.image_urls[1], '') AS image,
+               v.sku AS variant_sku, v.name AS variant_name
+        FROM cart_items ci
+        JOIN cart_stores cs ON ci.cart_store_id = cs.id
+        JOIN products p ON ci.product_id = p.id
+        LEFT JOIN product_variants v ON ci.variant_id = v.id
+        WHERE ci.cart_store_id = p_cart_store_id
+    LOOP
+        -- Harga dihitung oleh pricing service di aplikasi: price = harga sebelum diskon,
+        -- discount_price = harga efektif yang dibayar, discount = persentase potongan
+        -- Harga dikunci per cart item karena satu produk bisa masuk keranjang dengan beberapa varian
+        v_item_price := p_item_prices -> v_cart_item.id::text;
+        IF v_item_price IS NULL THEN
+            RAISE EXCEPTION 'Missing price for cart item % (product %)', v_cart_item.id, v_cart_item.product_id;
+        END IF;
+
+        -- Add order item beserta snapshot produk
+        INSERT INTO order_items (
+            order_id,
+            product_id,
+            variant_id,
+            variant_sku,
+            variant_name,
+            toko_id,
+            product_name,
+            product_slug,
+            product_image,
+            quantity,
+            price,
+            discount_price,
+            discount,
+            subtotal
+        ) VALUES (
+            v_order_id,
+            v_cart_item.product_id,
+            v_cart_item.variant_id,
+            v_cart_item.variant_sku,
+            v_cart_item.variant_name,
+            v_cart_item.toko_id,
+            v_cart_item.name,
+            v_cart_item.slug,
+            v_cart_item.image,
+            v_cart_item.quantity,
+            (v_item_price ->> 'list_price')::bigint,
+            (v_item_price ->> 'price')::bigint,
+            (v_item_price ->> 'discount_percent')::float8,
+            (v_item_price ->> 'price')::bigint * v_cart_item.quantity
+        );
+        
+        -- Update total price
+        v_total_price := v_total_price + (v_item_price ->> 'price')::bigint * v_cart_item.quantity;
+        
+        -- Stok varian dikurangi lebih dulu, stok produk adalah total stok semua variannya
+        IF v_cart_item.variant_id IS NOT NULL THEN
+            UPDATE product_variants
+            SET stock = stock - v_cart_item.quantity,
+                updated_at = now()
+            WHERE id = v_cart_item.variant_id
+            RETURNING stock INTO v_variant_stock;
+
+            IF v_variant_stock < 0 THEN
+                RAISE EXCEPTION 'Insufficient stock for variant %', v_cart_item.variant_sku;
+            END IF;
+        END IF;
+
+        -- Update product stock and sold count
+        UPDATE products 
+        SET stock = stock - v_cart_item.quantity, 
+            sold = sold + v_cart_item.quantity,
+            updated_at = now()
+        WHERE id = v_cart_item.product_id;
+    END LOOP;
+    
+    -- Potongan voucher tidak boleh negatif atau melebihi total + ongkir
+    IF COALESCE(p_discount, 0) < 0 OR COALESCE(p_discount, 0) > v_total_price + v_shipping_cost THEN
+        RAISE EXCEPTION 'Invalid discount % for order total %', p_discount, v_total_price + v_shipping_cost;
+    END IF;
+
+    -- Calculate final price (total + shipping - discount)
+    v_final_price := v_total_price + v_shipping_cost - COALESCE(p_discount, 0);
+    
+    -- Update order with calculated prices
+    UPDATE orders 
+    SET total_price = v_total_price,
+        discount = COALESCE(p_discount, 0),
+        final_price = v_final_price,
+        updated_at = now()
+    WHERE id = v_order_id;
+    
+    -- Add initial order tracking
+    INSERT INTO order_tracking (order_id, status_id, notes)
+    VALUES (v_order_id, 1, 'Order created');
+    
+    -- Clear the cart
+    DELETE FROM cart_items WHERE cart_store_id = p_cart_store_id;
+    DELETE FROM cart_stores WHERE id = p_cart_store_id;
+    
+    RETURN v_order_id;
+END;
+$function$
+;
+
+-- Stok varian yang diambil order juga dikembalikan saat order dibatalkan atau kedaluwarsa
+CREATE OR REPLACE FUNCTION restore_order_stock(p_order_id bigint) RETURNS void AS $$
+BEGIN
+    UPDATE product_variants v
+    SET stock = v.stock + oi.quantity,
+        updated_at = now()
+    FROM (
+        SELECT variant_id, SUM(quantity) AS quantity
+        FROM order_items
+        WHERE order_id = p_order_id AND variant_id IS NOT NULL
+        GROUP BY variant_id
+    ) oi
+    WHERE v.id = oi.variant_id;
+
+    UPDATE products p
+    SET stock = p.stock + oi.quantity,
+        sold = GREATEST(p.sold - oi.quantity, 0),
+        updated_at = now()
+    FROM (
+        SELECT product_id, SUM(quantity) AS quantity
+        FROM order_items
+        WHERE order_id = p_order_id
+        GROUP BY product_id
+    ) oi
+    WHERE p.id = oi.product_id;
+END;
+$$ LANGUAGE plpgsql;
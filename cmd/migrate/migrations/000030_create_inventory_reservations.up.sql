@@ -0,0 +1,27 @@
+-- Stock held by checkout sessions, used instead of Redis inventory locks when Redis is disabled
+CREATE TABLE IF NOT EXISTS
+    inventory_reservations (
+        id bigserial PRIMARY KEY,
+        session_id varchar(36) NOT NULL,
+        user_id bigint NOT NULL,
+        cart_store_id uuid NOT NULL,
+        product_id bigint NOT NULL,
+        variant_id bigint NULL,
+        quantity bigint NOT NULL,
+        created_at timestamptz (0) DEFAULT now () NOT NULL,
+        expires_at timestamptz (0) NOT NULL,
+        CONSTRAINT inventory_reservations_quantity_check CHECK (quantity > 0),
+        CONSTRAINT inventory_reservations_user_id_fkey FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE,
+        -- Cart stores are deleted once their order is created, which releases the hold
+        CONSTRAINT inventory_reservations_cart_store_id_fkey FOREIGN KEY (cart_store_id) REFERENCES cart_stores (id) ON DELETE CASCADE,
+        CONSTRAINT inventory_reservations_product_id_fkey FOREIGN KEY (product_id) REFERENCES products (id) ON DELETE CASCADE,
+        CONSTRAINT inventory_reservations_variant_id_fkey FOREIGN KEY (variant_id) REFERENCES product_variants (id) ON DELETE CASCADE
+    );
+
+CREATE INDEX IF NOT EXISTS idx_inventory_reservations_product ON inventory_reservations (product_id, variant_id);
+
+CREATE INDEX IF NOT EXISTS idx_inventory_reservations_session_id ON inventory_reservations (session_id);
+
+CREATE INDEX IF NOT EXISTS idx_inventory_reservations_user_cart_store ON inventory_reservations (user_id, cart_store_id);
+
+CREATE INDEX IF NOT EXISTS idx_inventory_reservations_expires_at ON inventory_reservations (expires_at);
@@ -0,0 +1,9 @@
+DROP TABLE IF EXISTS feed_snapshot_scores;
+
+DROP TABLE IF EXISTS feed_snapshots;
+
+DROP INDEX IF EXISTS idx_followers_follower_id;
+
+DROP INDEX IF EXISTS idx_order_items_created_at;
+
+DROP TABLE IF EXISTS product_views;
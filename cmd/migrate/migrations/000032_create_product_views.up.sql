@@ -0,0 +1,42 @@
+-- Products recently viewed by each user, one row per user and product, used by the personalized feed
+CREATE TABLE IF NOT EXISTS
+    product_views (
+        user_id bigint NOT NULL,
+        product_id bigint NOT NULL,
+        view_count int4 DEFAULT 1 NOT NULL,
+        viewed_at timestamptz (0) DEFAULT now () NOT NULL,
+        CONSTRAINT product_views_pkey PRIMARY KEY (user_id, product_id),
+        CONSTRAINT product_views_user_id_fkey FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE,
+        CONSTRAINT product_views_product_id_fkey FOREIGN KEY (product_id) REFERENCES products (id) ON DELETE CASCADE
+    );
+
+CREATE INDEX IF NOT EXISTS idx_product_views_user_viewed_at ON product_views (user_id, viewed_at DESC);
+
+-- Trending items are summed from recent order items
+CREATE INDEX IF NOT EXISTS idx_order_items_created_at ON order_items (created_at);
+
+CREATE INDEX IF NOT EXISTS idx_followers_follower_id ON followers (follower_id);
+
+-- Feed scores frozen when the first page is read, so later pages of the same cursor keep the same order
+CREATE TABLE IF NOT EXISTS
+    feed_snapshots (
+        id uuid PRIMARY KEY,
+        user_id bigint NOT NULL, -- 0 for anonymous visitors
+        filter_key varchar(40) NOT NULL, -- sha1 of the feed filter, first pages with the same user and filter share a snapshot
+        created_at timestamptz (0) DEFAULT now () NOT NULL,
+        expires_at timestamptz (0) NOT NULL
+    );
+
+CREATE INDEX IF NOT EXISTS idx_feed_snapshots_expires_at ON feed_snapshots (expires_at);
+
+CREATE INDEX IF NOT EXISTS idx_feed_snapshots_user_filter ON feed_snapshots (user_id, filter_key, created_at DESC);
+
+CREATE TABLE IF NOT EXISTS
+    feed_snapshot_scores (
+        snapshot_id uuid NOT NULL,
+        product_id bigint NOT NULL,
+        score float8 NOT NULL,
+        CONSTRAINT feed_snapshot_scores_pkey PRIMARY KEY (snapshot_id, product_id),
+        CONSTRAINT feed_snapshot_scores_snapshot_id_fkey FOREIGN KEY (snapshot_id) REFERENCES feed_snapshots (id) ON DELETE CASCADE,
+        CONSTRAINT feed_snapshot_scores_product_id_fkey FOREIGN KEY (product_id) REFERENCES products (id) ON DELETE CASCADE
+    );
@@ -0,0 +1,15 @@
+-- Checkout sessions stored in Postgres when Redis is disabled, the whole session is kept as JSON
+CREATE TABLE IF NOT EXISTS
+    checkout_sessions (
+        session_id varchar(36) PRIMARY KEY,
+        user_id bigint NOT NULL,
+        data jsonb NOT NULL,
+        created_at timestamptz (0) DEFAULT now () NOT NULL,
+        updated_at timestamptz (0) DEFAULT now () NOT NULL,
+        expires_at timestamptz (0) NOT NULL,
+        CONSTRAINT checkout_sessions_user_id_fkey FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+    );
+
+CREATE INDEX IF NOT EXISTS idx_checkout_sessions_user_id ON checkout_sessions (user_id);
+
+CREATE INDEX IF NOT EXISTS idx_checkout_sessions_expires_at ON checkout_sessions (expires_at);
@@ -42,7 +42,7 @@ func TestCartHandler(t *testing.T) {
 
 	for _, userID := range userIDs {
 		t.Run("AddToCartTransaction", func(t *testing.T) {
-			cart, err := storeTest.Carts.AddToCartTransaction(ctx, userID, productIDs[0], 2)
+			cart, err := storeTest.Carts.AddToCartTransaction(ctx, userID, productIDs[0], nil, 2)
 			require.NoError(t, err)
 			require.NotNil(t, cart)
 		})
@@ -0,0 +1,84 @@
+package test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+	"github.com/yogaprasetya22/api-gotokopedia/internal/store"
+	"github.com/yogaprasetya22/api-gotokopedia/internal/store/cache"
+)
+
+func TestRedisCheckoutSessionIndex(t *testing.T) {
+	rdb := NewTestRedis(t)
+	checkout := cache.NewRedisStore(rdb).Checkout
+	ctx := context.Background()
+
+	userID := time.Now().UnixNano()
+	userKey := fmt.Sprintf("checkout:user:%d:sessions", userID)
+	t.Cleanup(func() {
+		rdb.Del(ctx, userKey)
+	})
+
+	cartStore := func(tokoID, quantity int64) store.CartStores {
+		return store.CartStores{
+			ID:     uuid.New(),
+			TokoID: tokoID,
+			Items: []store.CartItem{
+				{ProductID: tokoID * 10, Quantity: quantity, Product: &store.Product{Price: store.IDR(10000), Stock: 100}},
+			},
+		}
+	}
+
+	first, err := checkout.StartCheckoutSession(ctx, userID, []store.CartStores{cartStore(1, 1)})
+	require.NoError(t, err)
+
+	t.Run("session is read from its primary key", func(t *testing.T) {
+		session, err := checkout.GetCheckoutSession(ctx, first.SessionID)
+		require.NoError(t, err)
+		require.Equal(t, userID, session.UserID)
+		require.Equal(t, store.IDR(10000), session.TotalPrice)
+
+		members, err := rdb.SMembers(ctx, userKey).Result()
+		require.NoError(t, err)
+		require.Equal(t, []string{first.SessionID}, members)
+	})
+
+	t.Run("overlapping session replaces the old one", func(t *testing.T) {
+		second, err := checkout.StartCheckoutSession(ctx, userID, []store.CartStores{cartStore(1, 2), cartStore(2, 1)})
+		require.NoError(t, err)
+
+		_, err = checkout.GetCheckoutSession(ctx, first.SessionID)
+		require.ErrorIs(t, err, store.ErrNotFound)
+
+		members, err := rdb.SMembers(ctx, userKey).Result()
+		require.NoError(t, err)
+		require.Equal(t, []string{second.SessionID}, members)
+	})
+
+	t.Run("expired sessions are removed from the index", func(t *testing.T) {
+		third, err := checkout.StartCheckoutSession(ctx, userID, []store.CartStores{cartStore(3, 1)})
+		require.NoError(t, err)
+		require.NoError(t, rdb.Del(ctx, "checkout:session:"+third.SessionID).Err())
+
+		_, err = checkout.StartCheckoutSession(ctx, userID, []store.CartStores{cartStore(4, 1)})
+		require.NoError(t, err)
+
+		isMember, err := rdb.SIsMember(ctx, userKey, third.SessionID).Result()
+		require.NoError(t, err)
+		require.False(t, isMember)
+	})
+
+	t.Run("complete removes the session from the index", func(t *testing.T) {
+		session, err := checkout.StartCheckoutSession(ctx, userID, []store.CartStores{cartStore(5, 1)})
+		require.NoError(t, err)
+		require.NoError(t, checkout.CompleteCheckout(ctx, session.SessionID))
+
+		isMember, err := rdb.SIsMember(ctx, userKey, session.SessionID).Result()
+		require.NoError(t, err)
+		require.False(t, isMember)
+	})
+}
@@ -0,0 +1,144 @@
+package test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+	"github.com/yogaprasetya22/api-gotokopedia/internal/store"
+)
+
+func TestCheckoutSessionSelections(t *testing.T) {
+	storeA, storeB := uuid.New(), uuid.New()
+	regular := &store.ShippingMethod{ID: 1, Name: "Reguler"}
+	express := &store.ShippingMethod{ID: 2, Name: "Express"}
+
+	session := &store.CheckoutSession{
+		CartStore: []store.CartStores{
+			{ID: storeA, Items: []store.CartItem{{Quantity: 2, Product: &store.Product{Price: store.IDR(50000)}}}},
+			{ID: storeB, Items: []store.CartItem{{Quantity: 1, Product: &store.Product{Price: store.IDR(120000), Discount: 10}}}},
+		},
+		ShippingMethod:  regular,
+		ShippingMethods: map[uuid.UUID]*store.ShippingMethod{storeB: express},
+		Notes:           "titip di satpam",
+		StoreNotes:      map[uuid.UUID]string{storeA: "bungkus kado"},
+	}
+
+	t.Run("per store choice falls back to the session choice", func(t *testing.T) {
+		require.Equal(t, regular, session.ShippingMethodFor(storeA))
+		require.Equal(t, express, session.ShippingMethodFor(storeB))
+		require.Equal(t, "bungkus kado", session.NotesFor(storeA))
+		require.Equal(t, "titip di satpam", session.NotesFor(storeB))
+	})
+
+	t.Run("totals include shipping and discount", func(t *testing.T) {
+		session.ShippingCosts = map[uuid.UUID]store.Money{storeA: store.IDR(9000), storeB: store.IDR(25000)}
+		session.Discounts = map[uuid.UUID]store.Money{storeA: store.IDR(10000)}
+		session.Recalculate()
+
+		require.Equal(t, store.IDR(208000), session.Totals.Items)
+		require.Equal(t, store.IDR(34000), session.Totals.Shipping)
+		require.Equal(t, store.IDR(10000), session.Totals.Discount)
+		require.Equal(t, store.IDR(232000), session.Totals.GrandTotal)
+		require.Equal(t, session.Totals.Items, session.TotalPrice)
+	})
+
+	t.Run("grand total never goes negative", func(t *testing.T) {
+		session.ShippingCosts = nil
+		session.Discounts = map[uuid.UUID]store.Money{storeA: store.IDR(500000)}
+		session.Recalculate()
+
+		require.Equal(t, store.IDR(0), session.Totals.GrandTotal)
+	})
+}
+
+func TestSameCartStores(t *testing.T) {
+	black, blue := int64(1), int64(2)
+	cartStore := func(variantID *int64, quantity int64) []store.CartStores {
+		return []store.CartStores{{TokoID: 1, Items: []store.CartItem{{ProductID: 10, VariantID: variantID, Quantity: quantity}}}}
+	}
+
+	require.True(t, store.SameCartStores(cartStore(&black, 1), cartStore(&black, 1)))
+	require.False(t, store.SameCartStores(cartStore(&black, 1), cartStore(&black, 2)))
+
+	// Varian yang diganti membuat session baru agar stok varian yang benar ditahan
+	require.False(t, store.SameCartStores(cartStore(&black, 1), cartStore(&blue, 1)))
+	require.False(t, store.SameCartStores(cartStore(nil, 1), cartStore(&blue, 1)))
+}
+
+func TestPostgresCheckoutSessionStore(t *testing.T) {
+	ctx := context.Background()
+	storeTest, db, _ := NewTestStorage(t)
+	checkout := storeTest.CheckoutSessions
+
+	var userID int64
+	require.NoError(t, db.QueryRow("SELECT id FROM users LIMIT 1").Scan(&userID))
+	t.Cleanup(func() {
+		db.Exec("DELETE FROM checkout_sessions WHERE user_id = $1", userID)
+	})
+
+	cartStore := func(tokoID, quantity int64) store.CartStores {
+		return store.CartStores{
+			ID:     uuid.New(),
+			TokoID: tokoID,
+			Items: []store.CartItem{
+				{ProductID: tokoID * 10, Quantity: quantity, Product: &store.Product{Price: store.IDR(10000), Stock: 100}},
+			},
+		}
+	}
+
+	first, err := checkout.StartCheckoutSession(ctx, userID, []store.CartStores{cartStore(1, 1)})
+	require.NoError(t, err)
+
+	t.Run("same cart reuses the active session", func(t *testing.T) {
+		session, err := checkout.StartCheckoutSession(ctx, userID, first.CartStore)
+		require.NoError(t, err)
+		require.Equal(t, first.SessionID, session.SessionID)
+		require.Equal(t, store.IDR(10000), session.TotalPrice)
+	})
+
+	t.Run("update keeps the expiry", func(t *testing.T) {
+		first.Notes = "titip di satpam"
+		require.NoError(t, checkout.UpdateCheckoutSession(ctx, first))
+
+		session, err := checkout.GetCheckoutSession(ctx, first.SessionID)
+		require.NoError(t, err)
+		require.Equal(t, "titip di satpam", session.Notes)
+		require.WithinDuration(t, first.ExpiresAt, session.ExpiresAt, time.Second)
+	})
+
+	t.Run("overlapping session replaces the old one", func(t *testing.T) {
+		_, err := checkout.StartCheckoutSession(ctx, userID, []store.CartStores{cartStore(1, 2), cartStore(2, 1)})
+		require.NoError(t, err)
+
+		_, err = checkout.GetCheckoutSession(ctx, first.SessionID)
+		require.ErrorIs(t, err, store.ErrNotFound)
+	})
+
+	t.Run("expired session cannot be read or updated", func(t *testing.T) {
+		session, err := checkout.StartCheckoutSession(ctx, userID, []store.CartStores{cartStore(3, 1)})
+		require.NoError(t, err)
+		_, err = db.Exec("UPDATE checkout_sessions SET expires_at = now() - interval '1 minute' WHERE session_id = $1", session.SessionID)
+		require.NoError(t, err)
+
+		_, err = checkout.GetCheckoutSession(ctx, session.SessionID)
+		require.ErrorIs(t, err, store.ErrSessionExpired)
+		require.ErrorIs(t, checkout.UpdateCheckoutSession(ctx, session), store.ErrSessionExpired)
+
+		deleted, err := checkout.DeleteExpired(ctx)
+		require.NoError(t, err)
+		require.GreaterOrEqual(t, deleted, int64(1))
+	})
+
+	t.Run("complete removes the session", func(t *testing.T) {
+		session, err := checkout.StartCheckoutSession(ctx, userID, []store.CartStores{cartStore(4, 1)})
+		require.NoError(t, err)
+		require.NoError(t, checkout.CompleteCheckout(ctx, session.SessionID))
+
+		_, err = checkout.GetCheckoutSession(ctx, session.SessionID)
+		require.ErrorIs(t, err, store.ErrNotFound)
+		require.ErrorIs(t, checkout.CompleteCheckout(ctx, session.SessionID), store.ErrNotFound)
+	})
+}
@@ -0,0 +1,117 @@
+package test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/yogaprasetya22/api-gotokopedia/internal/store"
+)
+
+func TestCursorCodec(t *testing.T) {
+	codec := store.NewCursorCodec([]byte("secret"))
+	cur := &store.Cursor{Scope: "products:newest:desc", Keys: []string{"2024-01-02T03:04:05.123456Z", "42"}, Before: true}
+
+	t.Run("round trip", func(t *testing.T) {
+		got, err := codec.Decode(codec.Encode(cur))
+		require.NoError(t, err)
+		require.Equal(t, cur, got)
+	})
+
+	t.Run("tampered payload is rejected", func(t *testing.T) {
+		token := codec.Encode(cur)
+		other := codec.Encode(&store.Cursor{Scope: cur.Scope, Keys: []string{"2030-01-01T00:00:00Z", "1"}})
+
+		payload, _, _ := strings.Cut(other, ".")
+		_, sig, _ := strings.Cut(token, ".")
+
+		_, err := codec.Decode(payload + "." + sig)
+		require.ErrorIs(t, err, store.ErrInvalidCursor)
+	})
+
+	t.Run("other secret is rejected", func(t *testing.T) {
+		_, err := store.NewCursorCodec([]byte("other")).Decode(codec.Encode(cur))
+		require.ErrorIs(t, err, store.ErrInvalidCursor)
+	})
+
+	t.Run("garbage is rejected", func(t *testing.T) {
+		for _, token := range []string{"", "abc", "abc.def", "!!.??"} {
+			_, err := codec.Decode(token)
+			require.ErrorIs(t, err, store.ErrInvalidCursor, token)
+		}
+	})
+}
+
+func TestProductCursorPagination(t *testing.T) {
+	ctx := context.Background()
+	storeTest, _, _ := NewTestStorage(t)
+	codec := store.NewCursorCodec([]byte("secret"))
+
+	for _, sort := range []string{"desc", string(store.SortPriceAsc), string(store.SortNewest)} {
+		t.Run(sort, func(t *testing.T) {
+			fq := store.PaginatedFeedQuery{Limit: 15, Sort: sort}
+			all, err := storeTest.Products.GetProductCategoryFeed(ctx, fq)
+			require.NoError(t, err)
+
+			// Tiga halaman berukuran 5 lewat cursor harus sama dengan satu halaman berukuran 15
+			fq.Limit = 5
+			var (
+				ids   []int64
+				pages []*store.Paginated[*store.Product]
+			)
+			for i := 0; i < 3; i++ {
+				page, err := storeTest.Products.GetProductCategoryFeed(ctx, fq)
+				require.NoError(t, err)
+				page.Pagination.Sign(codec)
+				pages = append(pages, page)
+
+				for _, p := range page.Data {
+					ids = append(ids, p.ID)
+				}
+
+				if page.Pagination.NextCursor == "" {
+					break
+				}
+				fq.Position, err = codec.Decode(page.Pagination.NextCursor)
+				require.NoError(t, err)
+			}
+
+			var want []int64
+			for _, p := range all.Data {
+				want = append(want, p.ID)
+			}
+			require.Equal(t, want, ids)
+
+			// prev_cursor halaman kedua kembali ke halaman pertama
+			if len(pages) > 1 {
+				require.Empty(t, pages[0].Pagination.PrevCursor)
+				require.NotEmpty(t, pages[1].Pagination.PrevCursor)
+
+				fq.Position, err = codec.Decode(pages[1].Pagination.PrevCursor)
+				require.NoError(t, err)
+
+				prev, err := storeTest.Products.GetProductCategoryFeed(ctx, fq)
+				require.NoError(t, err)
+				require.Equal(t, pages[0].Data, prev.Data)
+			}
+		})
+	}
+
+	t.Run("cursor from another order is rejected", func(t *testing.T) {
+		fq := store.PaginatedFeedQuery{Limit: 5, Sort: string(store.SortPriceAsc)}
+		page, err := storeTest.Products.GetProductCategoryFeed(ctx, fq)
+		require.NoError(t, err)
+		page.Pagination.Sign(codec)
+		if page.Pagination.NextCursor == "" {
+			t.Skip("not enough products")
+		}
+
+		fq.Sort = string(store.SortRating)
+		fq.Position, err = codec.Decode(page.Pagination.NextCursor)
+		require.NoError(t, err)
+
+		_, err = storeTest.Products.GetProductCategoryFeed(ctx, fq)
+		require.ErrorIs(t, err, store.ErrInvalidCursor)
+	})
+}
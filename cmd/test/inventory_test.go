@@ -0,0 +1,152 @@
+package test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+	"github.com/yogaprasetya22/api-gotokopedia/internal/store"
+	"github.com/yogaprasetya22/api-gotokopedia/internal/store/cache"
+)
+
+func TestReservationItems(t *testing.T) {
+	variantID := int64(7)
+	storeA, storeB := uuid.New(), uuid.New()
+
+	session := &store.CheckoutSession{
+		CartStore: []store.CartStores{
+			{ID: storeA, Items: []store.CartItem{
+				{ProductID: 20, Quantity: 1, Product: &store.Product{Stock: 5}},
+				{ProductID: 10, VariantID: &variantID, Quantity: 2, Product: &store.Product{Stock: 9}, Variant: &store.ProductVariant{Stock: 3}},
+			}},
+			{ID: storeB, Items: []store.CartItem{
+				{ProductID: 10, Quantity: 4, Product: &store.Product{Stock: 9}},
+			}},
+		},
+	}
+
+	items := store.ReservationItems(session)
+	require.Len(t, items, 3)
+
+	t.Run("sorted by product then variant", func(t *testing.T) {
+		require.Equal(t, int64(10), items[0].ProductID)
+		require.Nil(t, items[0].VariantID)
+		require.Equal(t, &variantID, items[1].VariantID)
+		require.Equal(t, int64(20), items[2].ProductID)
+	})
+
+	t.Run("variant stock is used for variant items", func(t *testing.T) {
+		require.Equal(t, int64(9), items[0].Stock)
+		require.Equal(t, int64(3), items[1].Stock)
+		require.Equal(t, storeA, items[1].CartStoreID)
+		require.Equal(t, storeB, items[0].CartStoreID)
+	})
+}
+
+// newReservationSession membuat checkout session satu item dengan key produk unik per test
+func newReservationSession(productID, stock, quantity int64, ttl time.Duration) *store.CheckoutSession {
+	return &store.CheckoutSession{
+		SessionID: uuid.NewString(),
+		UserID:    1,
+		ExpiresAt: time.Now().Add(ttl),
+		CartStore: []store.CartStores{{
+			ID: uuid.New(),
+			Items: []store.CartItem{
+				{ProductID: productID, Quantity: quantity, Product: &store.Product{Stock: int(stock)}},
+			},
+		}},
+	}
+}
+
+func TestRedisInventoryReservationConcurrency(t *testing.T) {
+	rdb := NewTestRedis(t)
+	reservations := cache.NewRedisStore(rdb).InventoryReservations
+	ctx := context.Background()
+
+	productID := time.Now().UnixNano()
+	t.Cleanup(func() {
+		key := fmt.Sprintf("inventory_lock:product:%d", productID)
+		rdb.Del(ctx, key, key+":expiry")
+	})
+
+	const stock, buyers = 10, 50
+
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		reserved []string
+		rejected int
+	)
+
+	// Banyak pembeli berebut stok yang sama secara bersamaan
+	for range buyers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			session := newReservationSession(productID, stock, 1, time.Minute)
+			err := reservations.Reserve(ctx, session)
+
+			mu.Lock()
+			defer mu.Unlock()
+
+			var stockErr *store.StockError
+			switch {
+			case err == nil:
+				reserved = append(reserved, session.SessionID)
+			case errors.As(err, &stockErr):
+				rejected++
+			default:
+				t.Errorf("unexpected error: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	require.Len(t, reserved, stock)
+	require.Equal(t, buyers-stock, rejected)
+
+	t.Run("release frees only the session quantity", func(t *testing.T) {
+		require.NoError(t, reservations.Release(ctx, reserved[0]))
+
+		require.NoError(t, reservations.Reserve(ctx, newReservationSession(productID, stock, 1, time.Minute)))
+
+		var stockErr *store.StockError
+		err := reservations.Reserve(ctx, newReservationSession(productID, stock, 1, time.Minute))
+		require.ErrorAs(t, err, &stockErr)
+	})
+
+	t.Run("reserving the same session again does not double count", func(t *testing.T) {
+		require.NoError(t, reservations.Release(ctx, reserved[1]))
+
+		session := newReservationSession(productID, stock, 1, time.Minute)
+		require.NoError(t, reservations.Reserve(ctx, session))
+		require.NoError(t, reservations.Reserve(ctx, session))
+	})
+}
+
+func TestRedisInventoryReservationExpiry(t *testing.T) {
+	rdb := NewTestRedis(t)
+	reservations := cache.NewRedisStore(rdb).InventoryReservations
+	ctx := context.Background()
+
+	productID := time.Now().UnixNano()
+	t.Cleanup(func() {
+		key := fmt.Sprintf("inventory_lock:product:%d", productID)
+		rdb.Del(ctx, key, key+":expiry")
+	})
+
+	// Session yang ditinggalkan melepas stoknya sendiri setelah kadaluarsa
+	require.NoError(t, reservations.Reserve(ctx, newReservationSession(productID, 1, 1, 200*time.Millisecond)))
+
+	var stockErr *store.StockError
+	require.ErrorAs(t, reservations.Reserve(ctx, newReservationSession(productID, 1, 1, time.Minute)), &stockErr)
+
+	time.Sleep(300 * time.Millisecond)
+	require.NoError(t, reservations.Reserve(ctx, newReservationSession(productID, 1, 1, time.Minute)))
+}
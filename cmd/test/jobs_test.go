@@ -0,0 +1,56 @@
+package test
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/yogaprasetya22/api-gotokopedia/internal/jobs"
+	"go.uber.org/zap"
+)
+
+func TestJobRunner(t *testing.T) {
+	runner := jobs.NewRunner(zap.NewNop().Sugar())
+
+	var runs, failures atomic.Int32
+	runner.Add(jobs.Job{
+		Name:     "counter",
+		Interval: 10 * time.Millisecond,
+		Run: func(ctx context.Context) error {
+			runs.Add(1)
+			return nil
+		},
+	})
+	runner.Add(jobs.Job{
+		Name:     "failing",
+		Interval: 10 * time.Millisecond,
+		Run: func(ctx context.Context) error {
+			failures.Add(1)
+			return errors.New("job gagal")
+		},
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	runner.Start(ctx)
+
+	require.Eventually(t, func() bool {
+		return runs.Load() >= 3 && failures.Load() >= 3
+	}, time.Second, 5*time.Millisecond)
+
+	cancel()
+
+	done := make(chan struct{})
+	go func() {
+		runner.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("runner tidak berhenti setelah context dibatalkan")
+	}
+}
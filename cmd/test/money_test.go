@@ -0,0 +1,53 @@
+package test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/yogaprasetya22/api-gotokopedia/internal/store"
+)
+
+func TestMoney(t *testing.T) {
+	t.Run("json stays a plain number", func(t *testing.T) {
+		data, err := json.Marshal(struct {
+			Price store.Money `json:"price"`
+		}{Price: store.IDR(13899000)})
+		require.NoError(t, err)
+		require.JSONEq(t, `{"price":13899000}`, string(data))
+	})
+
+	t.Run("json accepts numbers and strings", func(t *testing.T) {
+		var payload struct {
+			Price    store.Money `json:"price"`
+			Discount store.Money `json:"discount"`
+		}
+		require.NoError(t, json.Unmarshal([]byte(`{"price":149999.6,"discount":"5000"}`), &payload))
+		require.Equal(t, store.IDR(150000), payload.Price)
+		require.Equal(t, store.IDR(5000), payload.Discount)
+	})
+
+	t.Run("arithmetic", func(t *testing.T) {
+		total := store.IDR(100000).Mul(3).Add(store.IDR(15000)).Sub(store.IDR(5000))
+		require.Equal(t, store.IDR(310000), total)
+		require.Equal(t, store.IDR(33333), store.IDR(333335).Percent(10))
+		require.Equal(t, store.IDR(25000), store.IDR(30000).Min(store.IDR(25000)))
+	})
+
+	t.Run("share never overflows", func(t *testing.T) {
+		require.Equal(t, store.IDR(8333), store.IDR(25000).Share(10000, 30000))
+		require.Equal(t, store.IDR(4_000_000_000_000), store.IDR(8_000_000_000_000).Share(5_000_000_000_000, 10_000_000_000_000))
+	})
+
+	t.Run("scan database values", func(t *testing.T) {
+		var m store.Money
+		require.NoError(t, m.Scan(int64(799000)))
+		require.Equal(t, store.IDR(799000), m)
+		require.NoError(t, m.Scan([]byte("799000.4")))
+		require.Equal(t, store.IDR(799000), m)
+	})
+
+	t.Run("different currency panics", func(t *testing.T) {
+		require.Panics(t, func() { store.IDR(1).Add(store.Money{Amount: 1, Currency: "USD"}) })
+	})
+}
@@ -0,0 +1,44 @@
+package test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/yogaprasetya22/api-gotokopedia/internal/store"
+)
+
+func TestOrderStatusTransitions(t *testing.T) {
+	cases := []struct {
+		name     string
+		from, to int64
+		allowed  bool
+	}{
+		{"pending to processing", store.OrderStatusPending, store.OrderStatusProcessing, true},
+		{"pending to cancelled", store.OrderStatusPending, store.OrderStatusCancelled, true},
+		{"processing to cancelled", store.OrderStatusProcessing, store.OrderStatusCancelled, true},
+		{"shipped to cancelled", store.OrderStatusShipped, store.OrderStatusCancelled, false},
+		{"delivered to refunded", store.OrderStatusDelivered, store.OrderStatusRefunded, true},
+		{"delivered to pending", store.OrderStatusDelivered, store.OrderStatusPending, false},
+		{"cancelled to processing", store.OrderStatusCancelled, store.OrderStatusProcessing, false},
+		{"refunded to delivered", store.OrderStatusRefunded, store.OrderStatusDelivered, false},
+		{"same status", store.OrderStatusShipped, store.OrderStatusShipped, true},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			require.Equal(t, tc.allowed, store.CanTransitionOrderStatus(tc.from, tc.to))
+		})
+	}
+}
+
+func TestOrderTransitionError(t *testing.T) {
+	err := error(&store.OrderTransitionError{OrderID: 1, From: store.OrderStatusDelivered, To: store.OrderStatusPending})
+
+	require.True(t, errors.Is(err, store.ErrInvalidOrderTransition))
+	require.False(t, errors.Is(err, store.ErrNotFound))
+
+	var transitionErr *store.OrderTransitionError
+	require.True(t, errors.As(err, &transitionErr))
+	require.Equal(t, store.OrderStatusPending, transitionErr.To)
+}
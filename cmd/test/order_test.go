@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"testing"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/stretchr/testify/require"
@@ -97,14 +98,138 @@ func TestOrderHandler(t *testing.T) {
 				order, err := storeTest.Orders.GetByID(ctx, orderID)
 				require.NoError(t, err)
 				require.NotNil(t, order)
+
+				// Snapshot alamat dan produk tersimpan di order
+				require.Equal(t, address.RecipientName, order.ShippingSnapshot.RecipientName)
+				require.Equal(t, address.RecipientPhone, order.ShippingSnapshot.RecipientPhone)
+				require.Contains(t, order.ShippingSnapshot.Address, address.AddressLine1)
+				require.NotEmpty(t, order.Items)
+				require.Equal(t, order.Items[0].Product.Name, order.Items[0].ProductName)
+				require.Equal(t, order.Items[0].Product.Slug, order.Items[0].ProductSlug)
+			})
+
+			// Ambil order dari sisi seller
+			t.Run("GetByTokoID", func(t *testing.T) {
+				require.NotEmpty(t, orders[0].Items)
+				tokoID := orders[0].Items[0].TokoID
+
+				tokoOrders, err := storeTest.Orders.GetByTokoID(ctx, tokoID, store.SellerOrderFilter{
+					StatusID: orders[0].StatusID,
+				}, store.PaginatedFeedQuery{
+					Limit:  10,
+					Offset: 0,
+					Sort:   "desc",
+				})
+				require.NoError(t, err)
+				require.NotEmpty(t, tokoOrders)
+
+				err = storeTest.Orders.ShipBySeller(ctx, tokoID, orderID, " ", "", store.OrderActor{Role: store.OrderActorSeller})
+				require.ErrorIs(t, err, store.ErrTrackingNumberRequired)
+
+				// Pesanan yang belum dibayar tidak bisa diterima seller
+				err = storeTest.Orders.AcceptBySeller(ctx, tokoID, orderID, "", store.OrderActor{Role: store.OrderActorSeller})
+				require.ErrorIs(t, err, store.ErrOrderNotPaid)
+
+				// Pesanan lunas tetap Pending sampai diterima seller, dan hanya bisa diterima sekali
+				err = storeTest.Payments.Create(ctx, &store.Payment{
+					OrderID:         orderID,
+					Amount:          orders[0].FinalPrice,
+					PaymentMethodID: orders[0].PaymentMethodID,
+					TransactionID:   uuid.NewString(),
+					Status:          store.PaymentStatusPaid,
+				})
+				require.NoError(t, err)
+
+				err = storeTest.Orders.AcceptBySeller(ctx, tokoID, orderID, "", store.OrderActor{Role: store.OrderActorSeller})
+				require.NoError(t, err)
+
+				err = storeTest.Orders.AcceptBySeller(ctx, tokoID, orderID, "", store.OrderActor{Role: store.OrderActorSeller})
+				require.ErrorIs(t, err, store.ErrInvalidOrderTransition)
 			})
 
 			// Update status order
 			t.Run("UpdateStatus", func(t *testing.T) {
-				statusID := orders[0].StatusID
-				err := storeTest.Orders.UpdateStatus(ctx, orderID, statusID, "update status test")
+				order, err := storeTest.Orders.GetByID(ctx, orderID)
+				require.NoError(t, err)
+
+				statusID := order.StatusID
+				err = storeTest.Orders.UpdateStatus(ctx, orderID, statusID, "update status test", store.OrderActor{Role: store.OrderActorSystem})
 				require.NoError(t, err)
 			})
+
+			// Batalkan order dan pastikan pembatalan kedua ditolak
+			t.Run("Cancel", func(t *testing.T) {
+				// Pemakaian voucher oleh order ini dikembalikan saat order dibatalkan
+				var voucherID, usageID int64
+				err := db.QueryRow(`
+					INSERT INTO vouchers (code, name, type, value, used_count, starts_at, ends_at)
+					VALUES ($1, 'Voucher test', 'fixed', 1000, 1, now() - interval '1 day', now() + interval '1 day')
+					RETURNING id`, "TEST-"+uuid.NewString()[:8],
+				).Scan(&voucherID)
+				require.NoError(t, err)
+				t.Cleanup(func() { db.Exec("DELETE FROM vouchers WHERE id = $1", voucherID) })
+
+				err = db.QueryRow(`
+					INSERT INTO voucher_usages (voucher_id, user_id, session_id, discount)
+					VALUES ($1, $2, $3, 1000) RETURNING id`, voucherID, userID, uuid.NewString(),
+				).Scan(&usageID)
+				require.NoError(t, err)
+
+				_, err = db.Exec("UPDATE orders SET voucher_usage_id = $1 WHERE id = $2", usageID, orderID)
+				require.NoError(t, err)
+
+				err = storeTest.Orders.Cancel(ctx, orderID, userID, "cancel order test")
+				require.NoError(t, err)
+
+				order, err := storeTest.Orders.GetByID(ctx, orderID)
+				require.NoError(t, err)
+				require.Equal(t, store.OrderStatusCancelled, order.StatusID)
+
+				var usedCount, usages int
+				err = db.QueryRow(`
+					SELECT v.used_count, (SELECT COUNT(*) FROM voucher_usages WHERE voucher_id = v.id)
+					FROM vouchers v WHERE v.id = $1`, voucherID,
+				).Scan(&usedCount, &usages)
+				require.NoError(t, err)
+				require.Zero(t, usedCount)
+				require.Zero(t, usages)
+
+				err = storeTest.Orders.Cancel(ctx, orderID, userID, "cancel order test")
+				require.ErrorIs(t, err, store.ErrInvalidOrderTransition)
+
+				// Pelunasan yang terlambat untuk order yang dibatalkan ditandai untuk dikembalikan
+				p := &store.Payment{
+					OrderID:         orderID,
+					Amount:          order.FinalPrice,
+					PaymentMethodID: order.PaymentMethodID,
+					TransactionID:   uuid.NewString(),
+					Status:          store.PaymentStatusExpired,
+				}
+				require.NoError(t, storeTest.Payments.Create(ctx, p))
+
+				p, err = storeTest.Payments.UpdateStatus(ctx, p.TransactionID, store.PaymentStatusPaid)
+				require.NoError(t, err)
+				require.Equal(t, store.PaymentStatusRefundRequired, p.Status)
+				require.NotNil(t, p.PaymentDate)
+
+				order, err = storeTest.Orders.GetByID(ctx, orderID)
+				require.NoError(t, err)
+				require.Equal(t, store.OrderStatusCancelled, order.StatusID)
+			})
+		})
+
+		// Alamat yang sudah dipakai order hanya di-soft delete
+		t.Run("DeleteUsedShippingAddress", func(t *testing.T) {
+			err := storeTest.ShippingAddresses.Delete(ctx, address.ID, userID)
+			require.NoError(t, err)
+
+			_, err = storeTest.ShippingAddresses.GetByID(ctx, address.ID, userID)
+			require.ErrorIs(t, err, store.ErrNotFound)
+
+			var deleted bool
+			err = db.QueryRow("SELECT deleted_at IS NOT NULL FROM shipping_addresses WHERE id = $1", address.ID).Scan(&deleted)
+			require.NoError(t, err)
+			require.True(t, deleted)
 		})
 	}
 
@@ -120,4 +245,11 @@ func TestOrderHandler(t *testing.T) {
 		require.NoError(t, err)
 		require.NotEmpty(t, methods)
 	})
+
+	// Deadline jauh di masa lalu tidak boleh membatalkan order apa pun
+	t.Run("ExpireUnpaid", func(t *testing.T) {
+		orderIDs, err := storeTest.Orders.ExpireUnpaid(ctx, time.Now().AddDate(-100, 0, 0), 10)
+		require.NoError(t, err)
+		require.Empty(t, orderIDs)
+	})
 }
@@ -0,0 +1,79 @@
+package test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/yogaprasetya22/api-gotokopedia/internal/payment"
+	"github.com/yogaprasetya22/api-gotokopedia/internal/store"
+)
+
+func TestFakeGatewayCreateCharge(t *testing.T) {
+	ctx := context.Background()
+	gateway := payment.NewFakeGateway("test-server-key", time.Hour)
+
+	cases := []struct {
+		code        string
+		paymentType string
+	}{
+		{"bank_transfer.bca", "bank_transfer"},
+		{"gopay", "gopay"},
+		{"qris", "qris"},
+		{"cstore.alfamart", "cstore"},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.code, func(t *testing.T) {
+			orderNumber := "ORD-TEST-" + tc.code
+			charge, err := gateway.CreateCharge(ctx, payment.ChargeRequest{
+				OrderNumber: orderNumber,
+				Amount:      store.IDR(150000),
+				MethodCode:  tc.code,
+			})
+			require.NoError(t, err)
+			require.NotEmpty(t, charge.TransactionID)
+			require.Equal(t, payment.StatusPending, charge.Status)
+			require.Equal(t, tc.paymentType, charge.Instructions.PaymentType)
+
+			status, err := gateway.GetStatus(ctx, orderNumber)
+			require.NoError(t, err)
+			require.Equal(t, charge.TransactionID, status.TransactionID)
+		})
+	}
+
+	t.Run("unsupported method", func(t *testing.T) {
+		_, err := gateway.CreateCharge(ctx, payment.ChargeRequest{OrderNumber: "ORD-TEST-X", MethodCode: ""})
+		require.ErrorIs(t, err, payment.ErrUnsupportedMethod)
+
+		// Checkout menolak metode ini sebelum order dibuat
+		require.ErrorIs(t, gateway.ValidateMethod(""), payment.ErrUnsupportedMethod)
+		require.NoError(t, gateway.ValidateMethod("bank_transfer.bca"))
+	})
+}
+
+func TestFakeGatewayCancelCharge(t *testing.T) {
+	ctx := context.Background()
+	gateway := payment.NewFakeGateway("test-server-key", time.Hour)
+
+	_, err := gateway.CreateCharge(ctx, payment.ChargeRequest{OrderNumber: "ORD-CANCEL", Amount: store.IDR(150000), MethodCode: "qris"})
+	require.NoError(t, err)
+
+	require.NoError(t, gateway.CancelCharge(ctx, "ORD-CANCEL"))
+	status, err := gateway.GetStatus(ctx, "ORD-CANCEL")
+	require.NoError(t, err)
+	require.Equal(t, payment.StatusCancel, status.Status)
+
+	// Tagihan yang sudah tidak pending tidak bisa dibatalkan lagi
+	require.ErrorIs(t, gateway.CancelCharge(ctx, "ORD-CANCEL"), payment.ErrChargeNotPending)
+	require.ErrorIs(t, gateway.CancelCharge(ctx, "ORD-UNKNOWN"), payment.ErrChargeNotFound)
+}
+
+func TestPaymentGatewayRequiresServerKey(t *testing.T) {
+	_, err := payment.New(payment.Config{Gateway: "fake"})
+	require.ErrorIs(t, err, payment.ErrMissingServerKey)
+
+	_, err = payment.New(payment.Config{Gateway: "fake", ServerKey: "test-server-key"})
+	require.NoError(t, err)
+}
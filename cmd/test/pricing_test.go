@@ -0,0 +1,47 @@
+package test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/yogaprasetya22/api-gotokopedia/internal/pricing"
+)
+
+func TestPricingLine(t *testing.T) {
+	t.Run("no discount", func(t *testing.T) {
+		b := pricing.Line(799000, 0, 0, 2)
+		require.Equal(t, int64(799000), b.ListPrice)
+		require.Equal(t, int64(799000), b.Price)
+		require.Zero(t, b.Discount)
+		require.Equal(t, int64(1598000), b.Subtotal)
+	})
+
+	t.Run("discount price lower than price", func(t *testing.T) {
+		b := pricing.Line(100000, 80000, 20, 3)
+		require.Equal(t, int64(100000), b.ListPrice)
+		require.Equal(t, int64(80000), b.Price)
+		require.Equal(t, int64(20000), b.Discount)
+		require.Equal(t, 20.0, b.DiscountPercent)
+		require.Equal(t, int64(240000), b.Subtotal)
+	})
+
+	t.Run("discount price stored as the crossed out price", func(t *testing.T) {
+		// Data seed menyimpan harga promo di price dan harga coret di discount_price
+		b := pricing.Line(35000, 70000, 50, 1)
+		require.Equal(t, int64(70000), b.ListPrice)
+		require.Equal(t, int64(35000), b.Price)
+		require.Equal(t, 50.0, b.DiscountPercent)
+	})
+
+	t.Run("only discount percent", func(t *testing.T) {
+		b := pricing.Line(50000, 0, 10, 2)
+		require.Equal(t, int64(45000), b.Price)
+		require.Equal(t, int64(5000), b.Discount)
+		require.Equal(t, int64(90000), b.Subtotal)
+	})
+
+	t.Run("total of lines", func(t *testing.T) {
+		lines := []pricing.Breakdown{pricing.Line(100000, 80000, 20, 1), pricing.Line(50000, 0, 0, 2)}
+		require.Equal(t, int64(180000), pricing.Total(lines))
+	})
+}
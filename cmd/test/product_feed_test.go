@@ -0,0 +1,165 @@
+package test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/yogaprasetya22/api-gotokopedia/internal/store"
+	"github.com/yogaprasetya22/api-gotokopedia/internal/store/cache"
+)
+
+func TestPersonalizedFeed(t *testing.T) {
+	ctx := context.Background()
+	storeTest, db, _ := NewTestStorage(t)
+	codec := store.NewCursorCodec([]byte("secret"))
+
+	all, err := storeTest.Products.GetAllProduct(ctx, store.PaginatedFeedQuery{Limit: 1})
+	require.NoError(t, err)
+	if len(all.Data) == 0 {
+		t.Skip("no products")
+	}
+	product := all.Data[0]
+	userID := product.Toko.UserID
+
+	t.Cleanup(func() {
+		db.Exec("DELETE FROM product_views WHERE user_id = $1 AND product_id = $2", userID, product.ID)
+	})
+
+	t.Run("view is counted once per user and product", func(t *testing.T) {
+		require.NoError(t, storeTest.Products.RecordView(ctx, userID, product.ID))
+		require.NoError(t, storeTest.Products.RecordView(ctx, userID, product.ID))
+
+		var count int
+		err := db.QueryRow("SELECT view_count FROM product_views WHERE user_id = $1 AND product_id = $2", userID, product.ID).Scan(&count)
+		require.NoError(t, err)
+		require.Equal(t, 2, count)
+	})
+
+	for name, id := range map[string]int64{"user": userID, "anonymous": 0} {
+		t.Run(name+" feed pages do not repeat products", func(t *testing.T) {
+			fq := store.PaginatedFeedQuery{Limit: 5, Sort: "desc"}
+			seen := map[int64]bool{}
+
+			for i := 0; i < 3; i++ {
+				page, err := storeTest.Products.GetPersonalizedFeed(ctx, id, fq)
+				require.NoError(t, err)
+				page.Pagination.Sign(codec)
+
+				for _, p := range page.Data {
+					require.False(t, seen[p.ID], "product %d repeated", p.ID)
+					seen[p.ID] = true
+				}
+
+				// Lihat produk di antara halaman mengubah skor, tapi tidak urutan cursor yang sedang berjalan
+				if id != 0 {
+					require.NoError(t, storeTest.Products.RecordView(ctx, id, product.ID))
+				}
+
+				if page.Pagination.NextCursor == "" {
+					break
+				}
+				fq.Position, err = codec.Decode(page.Pagination.NextCursor)
+				require.NoError(t, err)
+			}
+		})
+	}
+
+	t.Run("first pages with the same filter share a snapshot", func(t *testing.T) {
+		countSnapshots := func() int {
+			var count int
+			err := db.QueryRow("SELECT COUNT(*) FROM feed_snapshots WHERE user_id = 0").Scan(&count)
+			require.NoError(t, err)
+			return count
+		}
+
+		fq := store.PaginatedFeedQuery{Limit: 5, Sort: "desc", Search: "shared-snapshot"}
+		_, err := storeTest.Products.GetPersonalizedFeed(ctx, 0, fq)
+		require.NoError(t, err)
+		before := countSnapshots()
+
+		_, err = storeTest.Products.GetPersonalizedFeed(ctx, 0, fq)
+		require.NoError(t, err)
+		require.Equal(t, before, countSnapshots())
+
+		fq.Search = "other-snapshot"
+		_, err = storeTest.Products.GetPersonalizedFeed(ctx, 0, fq)
+		require.NoError(t, err)
+		require.Equal(t, before+1, countSnapshots())
+	})
+
+	t.Run("cursor from another user is rejected", func(t *testing.T) {
+		fq := store.PaginatedFeedQuery{Limit: 5, Sort: "desc"}
+		page, err := storeTest.Products.GetPersonalizedFeed(ctx, userID, fq)
+		require.NoError(t, err)
+		page.Pagination.Sign(codec)
+		if page.Pagination.NextCursor == "" {
+			t.Skip("not enough products")
+		}
+
+		fq.Position, err = codec.Decode(page.Pagination.NextCursor)
+		require.NoError(t, err)
+
+		_, err = storeTest.Products.GetPersonalizedFeed(ctx, 0, fq)
+		require.ErrorIs(t, err, store.ErrInvalidCursor)
+	})
+
+	t.Run("expired snapshot invalidates its cursor", func(t *testing.T) {
+		fq := store.PaginatedFeedQuery{Limit: 5, Sort: "desc"}
+		page, err := storeTest.Products.GetPersonalizedFeed(ctx, userID, fq)
+		require.NoError(t, err)
+		page.Pagination.Sign(codec)
+		if page.Pagination.NextCursor == "" {
+			t.Skip("not enough products")
+		}
+
+		fq.Position, err = codec.Decode(page.Pagination.NextCursor)
+		require.NoError(t, err)
+
+		_, err = db.Exec("UPDATE feed_snapshots SET expires_at = now() - interval '1 minute' WHERE user_id = $1", userID)
+		require.NoError(t, err)
+		_, err = storeTest.Products.DeleteExpiredFeedSnapshots(ctx)
+		require.NoError(t, err)
+
+		_, err = storeTest.Products.GetPersonalizedFeed(ctx, userID, fq)
+		require.ErrorIs(t, err, store.ErrInvalidCursor)
+	})
+}
+
+func TestRedisFeedCache(t *testing.T) {
+	rdb := NewTestRedis(t)
+	feeds := cache.NewRedisStore(rdb).Feeds
+	ctx := context.Background()
+
+	userID := time.Now().UnixNano()
+	fq := store.PaginatedFeedQuery{Limit: 24, Sort: "desc"}
+	t.Cleanup(func() {
+		keys, _ := rdb.Keys(ctx, fmt.Sprintf("feed:user:%d:*", userID)).Result()
+		if len(keys) > 0 {
+			rdb.Del(ctx, keys...)
+		}
+	})
+
+	page, err := feeds.Get(ctx, userID, fq)
+	require.NoError(t, err)
+	require.Nil(t, page)
+
+	want := &store.Paginated[*store.Product]{
+		Data:       []*store.Product{{ID: 1, Name: "produk", Price: store.IDR(10000)}},
+		Pagination: store.CursorPage{Limit: 24, NextCursor: "next.sig"},
+	}
+	require.NoError(t, feeds.Set(ctx, userID, fq, want))
+
+	got, err := feeds.Get(ctx, userID, fq)
+	require.NoError(t, err)
+	require.Equal(t, want.Pagination.NextCursor, got.Pagination.NextCursor)
+	require.Equal(t, want.Data[0].ID, got.Data[0].ID)
+
+	// Halaman lain punya key sendiri
+	fq.Cursor = "next.sig"
+	page, err = feeds.Get(ctx, userID, fq)
+	require.NoError(t, err)
+	require.Nil(t, page)
+}
@@ -0,0 +1,96 @@
+package test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/yogaprasetya22/api-gotokopedia/internal/store"
+)
+
+func TestProductFilterAndFacets(t *testing.T) {
+	ctx := context.Background()
+	storeTest, _, _ := NewTestStorage(t)
+
+	fq := store.PaginatedFeedQuery{
+		Limit:    24,
+		Sort:     "desc",
+		MinPrice: 10_000,
+		MaxPrice: 5_000_000,
+		InStock:  true,
+	}
+
+	page, err := storeTest.Products.GetProductCategoryFeed(ctx, fq)
+	require.NoError(t, err)
+	products := page.Data
+
+	for _, p := range products {
+		price := p.Pricing(1).Price
+		require.GreaterOrEqual(t, price, int64(10_000), "product %d", p.ID)
+		require.LessOrEqual(t, price, int64(5_000_000), "product %d", p.ID)
+		require.Positive(t, p.Stock, "product %d", p.ID)
+	}
+
+	facets, err := storeTest.Products.GetProductFacets(ctx, store.NewProductFilter(fq))
+	require.NoError(t, err)
+	require.Len(t, facets.PriceBuckets, len(store.PriceBucketBounds)+1)
+	require.Len(t, facets.Ratings, 5)
+
+	// Facet harga dihitung tanpa filter harga, jadi totalnya minimal sebanyak produk yang lolos filter
+	var inPriceBuckets int64
+	for _, b := range facets.PriceBuckets {
+		inPriceBuckets += b.Count
+	}
+	require.GreaterOrEqual(t, inPriceBuckets, int64(len(products)))
+
+	// Memilih satu negara toko hanya mengembalikan produk dari negara tersebut
+	if len(facets.Countries) > 0 {
+		country := facets.Countries[0]
+		fq.Country = country.Value
+
+		page, err := storeTest.Products.GetProductCategoryFeed(ctx, fq)
+		require.NoError(t, err)
+		for _, p := range page.Data {
+			require.Equal(t, country.Value, p.Toko.Country)
+		}
+	}
+}
+
+func TestProductSortOrders(t *testing.T) {
+	ctx := context.Background()
+	storeTest, _, _ := NewTestStorage(t)
+
+	fq := store.PaginatedFeedQuery{Limit: 24}
+
+	fq.Sort = string(store.SortPriceAsc)
+	page, err := storeTest.Products.GetAllProduct(ctx, fq)
+	require.NoError(t, err)
+	products := page.Data
+	for i := 1; i < len(products); i++ {
+		require.LessOrEqual(t, products[i-1].Pricing(1).Price, products[i].Pricing(1).Price)
+	}
+
+	fq.Sort = string(store.SortPriceDesc)
+	page, err = storeTest.Products.GetProductCategoryFeed(ctx, fq)
+	require.NoError(t, err)
+	products = page.Data
+	for i := 1; i < len(products); i++ {
+		require.GreaterOrEqual(t, products[i-1].Pricing(1).Price, products[i].Pricing(1).Price)
+	}
+
+	fq.Sort = string(store.SortBestSelling)
+	page, err = storeTest.Products.GetProductCategoryFeed(ctx, fq)
+	require.NoError(t, err)
+	products = page.Data
+	for i := 1; i < len(products); i++ {
+		require.GreaterOrEqual(t, products[i-1].Sold, products[i].Sold)
+	}
+
+	fq.Sort = string(store.SortNewest)
+	page, err = storeTest.Products.GetProductCategoryFeed(ctx, fq)
+	require.NoError(t, err)
+	products = page.Data
+	for i := 1; i < len(products); i++ {
+		require.False(t, products[i].CreatedAt.After(products[i-1].CreatedAt))
+	}
+}
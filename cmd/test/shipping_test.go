@@ -0,0 +1,72 @@
+package test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/yogaprasetya22/api-gotokopedia/internal/shipping"
+)
+
+func TestShippingCalculate(t *testing.T) {
+	fifty := 50.0
+	rates := []shipping.Rate{
+		{MinDistanceKm: 0, MaxDistanceKm: &fifty, BasePrice: 5000, PricePerKg: 4000, EtdMinDays: 1, EtdMaxDays: 2},
+		{MinDistanceKm: 50, BasePrice: 9000, PricePerKg: 15000, EtdMinDays: 3, EtdMaxDays: 5},
+	}
+
+	jakarta := &shipping.Point{Latitude: -6.2088, Longitude: 106.8456}
+	bekasi := &shipping.Point{Latitude: -6.2383, Longitude: 106.9756}
+	surabaya := &shipping.Point{Latitude: -7.2575, Longitude: 112.7521}
+
+	t.Run("actual weight is rounded up per kg", func(t *testing.T) {
+		quote, err := shipping.Calculate(rates, shipping.Shipment{
+			Origin:      jakarta,
+			Destination: bekasi,
+			Items:       []shipping.Item{{WeightGram: 700, Quantity: 2}},
+		})
+		require.NoError(t, err)
+		require.EqualValues(t, 1400, quote.WeightGram)
+		require.EqualValues(t, 2, quote.ChargeableWeightKg)
+		require.Equal(t, int64(5000+4000*2), quote.Cost)
+		require.Equal(t, 1, quote.EtdMinDays)
+	})
+
+	t.Run("volumetric weight wins for bulky items", func(t *testing.T) {
+		// 40x30x25 cm = 5 kg volumetrik, lebih berat dari 1 kg aktual
+		quote, err := shipping.Calculate(rates, shipping.Shipment{
+			Origin:      jakarta,
+			Destination: bekasi,
+			Items:       []shipping.Item{{WeightGram: 1000, LengthCm: 40, WidthCm: 30, HeightCm: 25, Quantity: 1}},
+		})
+		require.NoError(t, err)
+		require.EqualValues(t, 5, quote.ChargeableWeightKg)
+	})
+
+	t.Run("distance selects the rate bracket", func(t *testing.T) {
+		quote, err := shipping.Calculate(rates, shipping.Shipment{
+			Origin:      jakarta,
+			Destination: surabaya,
+			Items:       []shipping.Item{{WeightGram: 300, Quantity: 1}},
+		})
+		require.NoError(t, err)
+		require.Greater(t, quote.DistanceKm, 600.0)
+		require.Equal(t, int64(9000+15000*1), quote.Cost)
+	})
+
+	t.Run("no rate covers the distance", func(t *testing.T) {
+		_, err := shipping.Calculate(rates[:1], shipping.Shipment{
+			Origin:      jakarta,
+			Destination: surabaya,
+			Items:       []shipping.Item{{WeightGram: 300, Quantity: 1}},
+		})
+		require.ErrorIs(t, err, shipping.ErrNoRate)
+	})
+
+	t.Run("unknown destination", func(t *testing.T) {
+		_, err := shipping.Calculate(rates, shipping.Shipment{
+			Origin: jakarta,
+			Items:  []shipping.Item{{WeightGram: 300, Quantity: 1}},
+		})
+		require.ErrorIs(t, err, shipping.ErrUnknownLocation)
+	})
+}
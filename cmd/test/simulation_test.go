@@ -77,7 +77,7 @@ func TestUserHandler(t *testing.T) {
 			for i := 0; i < 20; i++ {
 				var cart *store.Cart
 				var err error
-				cart, err = storeTest.Carts.AddToCartTransaction(ctx, user.ID, RandomInt(1, 400), 1)
+				cart, err = storeTest.Carts.AddToCartTransaction(ctx, user.ID, RandomInt(1, 400), nil, 1)
 				require.NoError(t, err)
 				require.NotNil(t, cart)
 				result_cart = append(result_cart, cart)
@@ -4,6 +4,7 @@ import (
 	"log"
 	"testing"
 
+	"github.com/redis/go-redis/v9"
 	"github.com/yogaprasetya22/api-gotokopedia/internal/db"
 	"github.com/yogaprasetya22/api-gotokopedia/internal/env"
 	"github.com/yogaprasetya22/api-gotokopedia/internal/store"
@@ -63,6 +64,23 @@ func NewTestDB(t *testing.T) *sql.DB {
 	return conn
 }
 
+func NewTestRedis(t *testing.T) *redis.Client {
+	rdb := cache.NewRedisClient(env.GetString("REDIS_ADDR", "localhost:6379"), env.GetString("REDIS_PW", ""), env.GetInt("REDIS_DB", 0))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := rdb.Ping(ctx).Err(); err != nil {
+		t.Fatalf("Failed to connect to test Redis: %v", err)
+	}
+
+	t.Cleanup(func() {
+		rdb.Close()
+	})
+
+	return rdb
+}
+
 func NewTestStorage(t *testing.T) (*store.Storage, *sql.DB, *cache.Storage) {
 	mockCacheStore := cache.NewMockStore()
 	db := NewTestDB(t)
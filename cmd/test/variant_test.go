@@ -0,0 +1,145 @@
+package test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+	"github.com/yogaprasetya22/api-gotokopedia/internal/store"
+)
+
+func newVariantMatrix() *store.VariantMatrix {
+	return &store.VariantMatrix{
+		Options: []*store.ProductOptionType{
+			{Name: "Warna", Values: []*store.ProductOptionValue{{Value: "Hitam"}, {Value: "Biru"}}},
+			{Name: "Penyimpanan", Values: []*store.ProductOptionValue{{Value: "128GB"}, {Value: "256GB"}}},
+		},
+		Variants: []*store.ProductVariant{
+			{SKU: "VIVO-Y20S-HTM-128", Options: map[string]string{"Warna": "Hitam", "Penyimpanan": "128GB"}, Price: store.IDR(799000)},
+			{SKU: "VIVO-Y20S-BRU-256", Options: map[string]string{"Warna": "Biru", "Penyimpanan": "256GB"}, Price: store.IDR(999000)},
+		},
+	}
+}
+
+func TestVariantMatrixValidate(t *testing.T) {
+	t.Run("valid matrix names every variant", func(t *testing.T) {
+		m := newVariantMatrix()
+		require.NoError(t, m.Validate())
+		require.Equal(t, "Hitam / 128GB", m.Variants[0].Name)
+		require.Equal(t, "Biru / 256GB", m.Variants[1].Name)
+	})
+
+	t.Run("variant missing an option", func(t *testing.T) {
+		m := newVariantMatrix()
+		m.Variants[0].Options = map[string]string{"Warna": "Hitam"}
+		require.ErrorIs(t, m.Validate(), store.ErrInvalidVariant)
+	})
+
+	t.Run("unknown option value", func(t *testing.T) {
+		m := newVariantMatrix()
+		m.Variants[0].Options["Warna"] = "Merah"
+		require.ErrorIs(t, m.Validate(), store.ErrInvalidVariant)
+	})
+
+	t.Run("duplicate sku", func(t *testing.T) {
+		m := newVariantMatrix()
+		m.Variants[1].SKU = m.Variants[0].SKU
+		require.ErrorIs(t, m.Validate(), store.ErrInvalidVariant)
+	})
+
+	t.Run("duplicate combination", func(t *testing.T) {
+		m := newVariantMatrix()
+		m.Variants[1].Options = map[string]string{"Warna": "Hitam", "Penyimpanan": "128GB"}
+		require.ErrorIs(t, m.Validate(), store.ErrInvalidVariant)
+	})
+}
+
+func TestCartItemPricing(t *testing.T) {
+	product := &store.Product{Price: store.IDR(799000), Discount: 10}
+
+	t.Run("product without variant", func(t *testing.T) {
+		item := store.CartItem{Quantity: 2, Product: product}
+		require.Equal(t, int64(719100*2), item.Pricing().Subtotal)
+	})
+
+	t.Run("variant price replaces product price", func(t *testing.T) {
+		variant := &store.ProductVariant{Price: store.IDR(999000), DiscountPrice: store.IDR(1099000)}
+		item := store.CartItem{Quantity: 2, Product: product, Variant: variant}
+
+		b := item.Pricing()
+		require.Equal(t, int64(1099000), b.ListPrice)
+		require.Equal(t, int64(999000), b.Price)
+		require.Equal(t, int64(1998000), b.Subtotal)
+	})
+}
+
+func TestCancelVariantOrderRestoresStock(t *testing.T) {
+	ctx := context.Background()
+	storeTest, db, _ := NewTestStorage(t)
+
+	all, err := storeTest.Products.GetAllProduct(ctx, store.PaginatedFeedQuery{Limit: 1})
+	require.NoError(t, err)
+	if len(all.Data) == 0 {
+		t.Skip("no products")
+	}
+	product := all.Data[0]
+	userID := product.Toko.UserID
+
+	paymentMethods, err := storeTest.Orders.GetPaymentMethods(ctx)
+	require.NoError(t, err)
+	require.NotEmpty(t, paymentMethods)
+
+	shippingMethods, err := storeTest.Orders.GetShippingMethods(ctx)
+	require.NoError(t, err)
+	require.NotEmpty(t, shippingMethods)
+
+	var variantID int64
+	sku := fmt.Sprintf("TEST-CANCEL-%d", time.Now().UnixNano())
+	err = db.QueryRow(
+		`INSERT INTO product_variants (product_id, sku, name, price, stock) VALUES ($1, $2, 'Hitam / 128GB', 799000, 10) RETURNING id`,
+		product.ID, sku,
+	).Scan(&variantID)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		db.Exec("DELETE FROM product_variants WHERE id = $1", variantID)
+	})
+
+	address := &store.ShippingAddresses{
+		UserID:         userID,
+		Label:          "Varian",
+		RecipientName:  "Varian Test",
+		RecipientPhone: "081234567890",
+		AddressLine1:   "Jl. Varian No.1",
+	}
+	require.NoError(t, storeTest.ShippingAddresses.Create(ctx, address))
+	t.Cleanup(func() {
+		storeTest.ShippingAddresses.Delete(ctx, address.ID, userID)
+	})
+
+	_, err = storeTest.Carts.AddToCartTransaction(ctx, userID, product.ID, &variantID, 3)
+	require.NoError(t, err)
+
+	var cartStoreID uuid.UUID
+	err = db.QueryRow("SELECT cart_store_id FROM cart_items WHERE variant_id = $1", variantID).Scan(&cartStoreID)
+	require.NoError(t, err)
+
+	err = storeTest.Orders.CreateFromCart(ctx, cartStoreID, userID, paymentMethods[0].ID, shippingMethods[0].ID, address.ID, "variant cancel test")
+	require.NoError(t, err)
+
+	stock := func() int {
+		var n int
+		require.NoError(t, db.QueryRow("SELECT stock FROM product_variants WHERE id = $1", variantID).Scan(&n))
+		return n
+	}
+	require.Equal(t, 7, stock())
+
+	var orderID int64
+	err = db.QueryRow("SELECT order_id FROM order_items WHERE variant_id = $1", variantID).Scan(&orderID)
+	require.NoError(t, err)
+
+	require.NoError(t, storeTest.Orders.Cancel(ctx, orderID, userID, "variant cancel test"))
+	require.Equal(t, 10, stock())
+}
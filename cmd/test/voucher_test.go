@@ -0,0 +1,97 @@
+package test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+	"github.com/yogaprasetya22/api-gotokopedia/internal/store"
+)
+
+func TestVoucherCalculate(t *testing.T) {
+	storeA, storeB := uuid.New(), uuid.New()
+	lines := []store.VoucherLine{
+		{CartStoreID: storeA, TokoID: 1, Subtotal: store.IDR(300000), ShippingCost: store.IDR(10000)},
+		{CartStoreID: storeB, TokoID: 2, Subtotal: store.IDR(100000), ShippingCost: store.IDR(20000)},
+	}
+
+	t.Run("percentage is capped and split by subtotal", func(t *testing.T) {
+		maxDiscount := store.IDR(30000)
+		v := &store.Voucher{Type: store.VoucherTypePercentage, Percent: 10, MaxDiscount: &maxDiscount}
+
+		discounts, err := v.Calculate(lines)
+		require.NoError(t, err)
+		require.Equal(t, store.IDR(22500), discounts[storeA])
+		require.Equal(t, store.IDR(7500), discounts[storeB])
+	})
+
+	t.Run("fixed discount never exceeds spend", func(t *testing.T) {
+		v := &store.Voucher{Type: store.VoucherTypeFixed, Amount: store.IDR(1000000)}
+
+		discounts, err := v.Calculate(lines)
+		require.NoError(t, err)
+		require.Equal(t, store.IDR(300000), discounts[storeA])
+		require.Equal(t, store.IDR(100000), discounts[storeB])
+	})
+
+	t.Run("free shipping covers the shipping cost", func(t *testing.T) {
+		maxDiscount := store.IDR(25000)
+		v := &store.Voucher{Type: store.VoucherTypeFreeShipping, MaxDiscount: &maxDiscount}
+
+		discounts, err := v.Calculate(lines)
+		require.NoError(t, err)
+		require.Equal(t, store.IDR(8333), discounts[storeA])
+		require.Equal(t, store.IDR(16667), discounts[storeB])
+	})
+
+	t.Run("toko voucher only discounts its own toko", func(t *testing.T) {
+		tokoID := int64(2)
+		v := &store.Voucher{Type: store.VoucherTypeFixed, Amount: store.IDR(15000), TokoID: &tokoID}
+
+		discounts, err := v.Calculate(lines)
+		require.NoError(t, err)
+		require.NotContains(t, discounts, storeA)
+		require.Equal(t, store.IDR(15000), discounts[storeB])
+	})
+
+	t.Run("toko voucher without the toko in checkout", func(t *testing.T) {
+		tokoID := int64(3)
+		v := &store.Voucher{Type: store.VoucherTypeFixed, Amount: store.IDR(15000), TokoID: &tokoID}
+
+		_, err := v.Calculate(lines)
+		require.ErrorIs(t, err, store.ErrVoucherNotApplicable)
+	})
+
+	t.Run("minimum spend", func(t *testing.T) {
+		v := &store.Voucher{Type: store.VoucherTypeFixed, Amount: store.IDR(15000), MinSpend: store.IDR(500000)}
+
+		_, err := v.Calculate(lines)
+		require.ErrorIs(t, err, store.ErrVoucherMinSpend)
+	})
+}
+
+func TestVoucherValidate(t *testing.T) {
+	now := time.Now()
+	limit := 1
+
+	t.Run("active voucher", func(t *testing.T) {
+		v := &store.Voucher{IsActive: true, StartsAt: now.Add(-time.Hour), EndsAt: now.Add(time.Hour)}
+		require.NoError(t, v.Validate(now, 0))
+	})
+
+	t.Run("outside the validity window", func(t *testing.T) {
+		v := &store.Voucher{IsActive: true, StartsAt: now.Add(time.Hour), EndsAt: now.Add(2 * time.Hour)}
+		require.ErrorIs(t, v.Validate(now, 0), store.ErrVoucherInactive)
+	})
+
+	t.Run("global usage limit reached", func(t *testing.T) {
+		v := &store.Voucher{IsActive: true, StartsAt: now.Add(-time.Hour), EndsAt: now.Add(time.Hour), UsageLimit: &limit, UsedCount: 1}
+		require.ErrorIs(t, v.Validate(now, 0), store.ErrVoucherUsageLimit)
+	})
+
+	t.Run("per user usage limit reached", func(t *testing.T) {
+		v := &store.Voucher{IsActive: true, StartsAt: now.Add(-time.Hour), EndsAt: now.Add(time.Hour), UsageLimitPerUser: &limit}
+		require.ErrorIs(t, v.Validate(now, 1), store.ErrVoucherUsageLimit)
+	})
+}
@@ -287,8 +287,8 @@ func generateProducts(storage store.Storage, tokos []*store.Toko) []*store.Produ
 			Slug:          product.Slug,
 			Description:   product.Description,
 			Country:       product.Country,
-			Price:         parsePrice(product.Price),
-			DiscountPrice: parsePrice(product.DiscountPrice),
+			Price:         store.MoneyFromMajor(parsePrice(product.Price)),
+			DiscountPrice: store.MoneyFromMajor(parsePrice(product.DiscountPrice)),
 			Discount:      parseDiscount(product.Discount),
 			// Rating:        parseFloat(product.Rating),
 			Estimation: product.Estimation,
@@ -297,6 +297,7 @@ func generateProducts(storage store.Storage, tokos []*store.Toko) []*store.Produ
 			IsForSale:  product.Discount != "" && product.Discount != "null",
 			IsApproved: true,
 			ImageUrls:  product.ImageURL,
+			WeightGram: 1000,
 			Category:   category,
 			Toko:       toko,
 		}
@@ -0,0 +1,80 @@
+package jobs
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Job adalah pekerjaan latar belakang yang dijalankan berulang setiap Interval
+type Job struct {
+	Name     string
+	Interval time.Duration
+	Run      func(ctx context.Context) error
+}
+
+// Runner menjalankan job di goroutine terpisah sampai context dibatalkan
+type Runner struct {
+	logger *zap.SugaredLogger
+	jobs   []Job
+	wg     sync.WaitGroup
+}
+
+func NewRunner(logger *zap.SugaredLogger) *Runner {
+	return &Runner{logger: logger}
+}
+
+func (r *Runner) Add(job Job) {
+	if job.Interval <= 0 {
+		job.Interval = time.Minute
+	}
+
+	r.jobs = append(r.jobs, job)
+}
+
+// Start menjalankan semua job, masing-masing langsung sekali lalu setiap Interval
+func (r *Runner) Start(ctx context.Context) {
+	for _, job := range r.jobs {
+		r.wg.Add(1)
+		go r.loop(ctx, job)
+	}
+}
+
+// Wait menunggu semua job berhenti setelah context dibatalkan
+func (r *Runner) Wait() {
+	r.wg.Wait()
+}
+
+func (r *Runner) loop(ctx context.Context, job Job) {
+	defer r.wg.Done()
+
+	ticker := time.NewTicker(job.Interval)
+	defer ticker.Stop()
+
+	r.logger.Infow("job has started", "job", job.Name, "interval", job.Interval.String())
+
+	for {
+		r.runOnce(ctx, job)
+
+		select {
+		case <-ctx.Done():
+			r.logger.Infow("job has stopped", "job", job.Name)
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (r *Runner) runOnce(ctx context.Context, job Job) {
+	defer func() {
+		if p := recover(); p != nil {
+			r.logger.Errorw("job panicked", "job", job.Name, "panic", p)
+		}
+	}()
+
+	if err := job.Run(ctx); err != nil && ctx.Err() == nil {
+		r.logger.Errorw("job failed", "job", job.Name, "error", err.Error())
+	}
+}
@@ -0,0 +1,166 @@
+package payment
+
+import (
+	"context"
+	"crypto/sha512"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// FakeGateway adalah gateway lokal untuk development, semua tagihan disimpan di memori
+type FakeGateway struct {
+	serverKey string
+	expiresIn time.Duration
+
+	mu      sync.Mutex
+	charges map[string]*Charge
+}
+
+func NewFakeGateway(serverKey string, expiresIn time.Duration) *FakeGateway {
+	if expiresIn <= 0 {
+		expiresIn = 24 * time.Hour
+	}
+
+	return &FakeGateway{
+		serverKey: serverKey,
+		expiresIn: expiresIn,
+		charges:   make(map[string]*Charge),
+	}
+}
+
+// ValidateMethod mengecek midtrans_code metode pembayaran sebelum order dibuat
+func (g *FakeGateway) ValidateMethod(methodCode string) error {
+	_, err := fakeInstructions(methodCode)
+	return err
+}
+
+func (g *FakeGateway) CreateCharge(ctx context.Context, req ChargeRequest) (*Charge, error) {
+	instructions, err := fakeInstructions(req.MethodCode)
+	if err != nil {
+		return nil, err
+	}
+
+	charge := &Charge{
+		TransactionID: uuid.New().String(),
+		OrderNumber:   req.OrderNumber,
+		Status:        StatusPending,
+		Amount:        req.Amount,
+		Instructions:  instructions,
+		ExpiresAt:     time.Now().Add(g.expiresIn),
+	}
+
+	g.mu.Lock()
+	g.charges[req.OrderNumber] = charge
+	g.mu.Unlock()
+
+	return charge, nil
+}
+
+func (g *FakeGateway) GetStatus(ctx context.Context, orderNumber string) (*Charge, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	charge, ok := g.charges[orderNumber]
+	if !ok {
+		return nil, ErrChargeNotFound
+	}
+
+	if charge.Status == StatusPending && time.Now().After(charge.ExpiresAt) {
+		charge.Status = StatusExpire
+	}
+
+	c := *charge
+	return &c, nil
+}
+
+// CancelCharge membatalkan tagihan yang belum dibayar agar pembeli tidak bisa membayar order yang sudah dibatalkan
+func (g *FakeGateway) CancelCharge(ctx context.Context, orderNumber string) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	charge, ok := g.charges[orderNumber]
+	if !ok {
+		return ErrChargeNotFound
+	}
+
+	if charge.Status != StatusPending {
+		return ErrChargeNotPending
+	}
+
+	charge.Status = StatusCancel
+	return nil
+}
+
+func (g *FakeGateway) VerifyCallback(payload []byte) (*Notification, error) {
+	var n Notification
+	if err := json.Unmarshal(payload, &n); err != nil {
+		return nil, err
+	}
+
+	expected := Signature(n.OrderNumber, n.StatusCode, n.GrossAmount, g.serverKey)
+	if !strings.EqualFold(expected, n.SignatureKey) {
+		return nil, ErrInvalidSignature
+	}
+
+	g.mu.Lock()
+	if charge, ok := g.charges[n.OrderNumber]; ok {
+		charge.Status = n.TransactionStatus
+	}
+	g.mu.Unlock()
+
+	return &n, nil
+}
+
+// Signature menghitung signature_key dengan format Midtrans: SHA512(order_id + status_code + gross_amount + server_key)
+func Signature(orderNumber, statusCode, grossAmount, serverKey string) string {
+	sum := sha512.Sum512([]byte(orderNumber + statusCode + grossAmount + serverKey))
+	return hex.EncodeToString(sum[:])
+}
+
+// fakeInstructions membuat instruksi pembayaran tiruan berdasarkan midtrans_code metode pembayaran
+func fakeInstructions(methodCode string) (Instructions, error) {
+	paymentType, channel, _ := strings.Cut(methodCode, ".")
+
+	switch paymentType {
+	case "bank_transfer":
+		return Instructions{
+			PaymentType: paymentType,
+			Bank:        channel,
+			VANumber:    randomDigits(16),
+		}, nil
+	case "gopay":
+		return Instructions{
+			PaymentType: paymentType,
+			DeepLink:    fmt.Sprintf("gojek://gopay/merchanttransfer?tref=%s", randomDigits(12)),
+		}, nil
+	case "qris":
+		return Instructions{
+			PaymentType: paymentType,
+			QRString:    "00020101021226" + randomDigits(24),
+		}, nil
+	case "cstore":
+		return Instructions{
+			PaymentType: paymentType,
+			Store:       channel,
+			PaymentCode: randomDigits(12),
+		}, nil
+	default:
+		return Instructions{}, ErrUnsupportedMethod
+	}
+}
+
+func randomDigits(n int) string {
+	var sb strings.Builder
+	for i := 0; i < n; i++ {
+		sb.WriteByte(byte('0' + rand.Intn(10)))
+	}
+
+	return sb.String()
+}
@@ -0,0 +1,114 @@
+package payment
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/yogaprasetya22/api-gotokopedia/internal/store"
+)
+
+// Status transaksi mengikuti transaction_status dari Midtrans
+const (
+	StatusPending    = "pending"
+	StatusSettlement = "settlement"
+	StatusCapture    = "capture"
+	StatusDeny       = "deny"
+	StatusCancel     = "cancel"
+	StatusExpire     = "expire"
+	StatusRefund     = "refund"
+)
+
+var (
+	ErrUnsupportedMethod = errors.New("metode pembayaran tidak didukung gateway")
+	ErrChargeNotFound    = errors.New("transaksi pembayaran tidak ditemukan")
+	ErrChargeNotPending  = errors.New("transaksi pembayaran sudah tidak menunggu pembayaran")
+	ErrInvalidSignature  = errors.New("signature callback pembayaran tidak valid")
+	ErrMissingServerKey  = errors.New("server key payment gateway belum diatur")
+)
+
+// DevServerKey adalah server key gateway fake yang hanya boleh dipakai di development
+const DevServerKey = "SB-Mid-server-local"
+
+type Gateway interface {
+	ValidateMethod(methodCode string) error
+	CreateCharge(ctx context.Context, req ChargeRequest) (*Charge, error)
+	GetStatus(ctx context.Context, orderNumber string) (*Charge, error)
+	CancelCharge(ctx context.Context, orderNumber string) error
+	VerifyCallback(payload []byte) (*Notification, error)
+}
+
+type Config struct {
+	Gateway   string
+	ServerKey string
+	ExpiresIn time.Duration
+}
+
+// New membuat gateway sesuai konfigurasi, saat ini hanya gateway "fake" untuk development
+func New(cfg Config) (Gateway, error) {
+	if cfg.ServerKey == "" {
+		return nil, ErrMissingServerKey
+	}
+
+	switch cfg.Gateway {
+	case "", "fake":
+		return NewFakeGateway(cfg.ServerKey, cfg.ExpiresIn), nil
+	default:
+		return nil, fmt.Errorf("payment gateway %q tidak dikenal", cfg.Gateway)
+	}
+}
+
+// ChargeRequest adalah data yang dikirim ke gateway untuk membuat tagihan satu order
+type ChargeRequest struct {
+	OrderNumber   string
+	Amount        store.Money
+	MethodCode    string
+	CustomerName  string
+	CustomerEmail string
+}
+
+// Charge adalah tagihan yang dibuat gateway beserta instruksi pembayarannya
+type Charge struct {
+	TransactionID string       `json:"transaction_id"`
+	OrderNumber   string       `json:"order_number"`
+	Status        string       `json:"status"`
+	Amount        store.Money  `json:"amount"`
+	Instructions  Instructions `json:"instructions"`
+	ExpiresAt     time.Time    `json:"expires_at"`
+}
+
+// Instructions berisi cara pembayaran yang ditampilkan ke pembeli, hanya field yang relevan yang terisi
+type Instructions struct {
+	PaymentType string `json:"payment_type"`
+	Bank        string `json:"bank,omitempty"`
+	VANumber    string `json:"va_number,omitempty"`
+	QRString    string `json:"qr_string,omitempty"`
+	PaymentCode string `json:"payment_code,omitempty"`
+	Store       string `json:"store,omitempty"`
+	DeepLink    string `json:"deeplink,omitempty"`
+}
+
+// Notification adalah isi callback dari gateway yang sudah diverifikasi
+type Notification struct {
+	TransactionID     string `json:"transaction_id"`
+	OrderNumber       string `json:"order_id"`
+	TransactionStatus string `json:"transaction_status"`
+	StatusCode        string `json:"status_code"`
+	GrossAmount       string `json:"gross_amount"`
+	PaymentType       string `json:"payment_type"`
+	FraudStatus       string `json:"fraud_status,omitempty"`
+	SignatureKey      string `json:"signature_key"`
+}
+
+// IsPaid mengembalikan true jika notifikasi menandakan pembayaran berhasil
+func (n *Notification) IsPaid() bool {
+	switch n.TransactionStatus {
+	case StatusSettlement:
+		return true
+	case StatusCapture:
+		return n.FraudStatus == "" || n.FraudStatus == "accept"
+	default:
+		return false
+	}
+}
@@ -0,0 +1,60 @@
+package pricing
+
+import "math"
+
+// Breakdown adalah rincian harga sebuah produk untuk sejumlah unit.
+// Semua nominal dalam satuan terkecil mata uang (rupiah).
+type Breakdown struct {
+	ListPrice       int64   `json:"list_price"`       // harga per unit sebelum diskon
+	Discount        int64   `json:"discount"`         // potongan per unit
+	DiscountPercent float64 `json:"discount_percent"` // persentase potongan terhadap ListPrice
+	Price           int64   `json:"price"`            // harga efektif per unit yang dibayar pembeli
+	Quantity        int64   `json:"quantity"`
+	Subtotal        int64   `json:"subtotal"` // Price * Quantity
+}
+
+// Unit menghitung harga efektif satu unit dari kolom price, discount_price dan discount (persen) produk.
+//
+// Data produk menyimpan harga coret dan harga promo dengan urutan yang tidak selalu sama,
+// jadi harga yang lebih rendah dari price dan discount_price selalu dianggap harga efektif
+// dan yang lebih tinggi dianggap harga sebelum diskon. Jika hanya persentase diskon yang diisi,
+// harga efektif dihitung dari price.
+func Unit(price, discountPrice int64, discountPercent float64) Breakdown {
+	b := Breakdown{ListPrice: price, Price: price, Quantity: 1}
+
+	switch {
+	case discountPrice > 0 && discountPrice != price:
+		b.ListPrice = max(price, discountPrice)
+		b.Price = min(price, discountPrice)
+		if b.Price <= 0 {
+			b.Price = b.ListPrice
+		}
+	case discountPercent > 0 && discountPercent < 100:
+		b.Price = int64(math.Round(float64(price) * (100 - discountPercent) / 100))
+	}
+
+	b.Discount = b.ListPrice - b.Price
+	if b.ListPrice > 0 {
+		b.DiscountPercent = math.Round(float64(b.Discount) / float64(b.ListPrice) * 100)
+	}
+	b.Subtotal = b.Price
+
+	return b
+}
+
+// Line menghitung rincian harga produk untuk quantity unit
+func Line(price, discountPrice int64, discountPercent float64, quantity int64) Breakdown {
+	b := Unit(price, discountPrice, discountPercent)
+	b.Quantity = quantity
+	b.Subtotal = b.Price * quantity
+	return b
+}
+
+// Total menjumlahkan subtotal semua baris
+func Total(lines []Breakdown) int64 {
+	var total int64
+	for _, l := range lines {
+		total += l.Subtotal
+	}
+	return total
+}
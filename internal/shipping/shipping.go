@@ -0,0 +1,134 @@
+package shipping
+
+import (
+	"errors"
+	"math"
+)
+
+const (
+	// VolumetricDivisor adalah pembagi standar kurir untuk berat volumetrik (cm3 per kg)
+	VolumetricDivisor = 6000
+
+	earthRadiusKm = 6371.0
+)
+
+var (
+	ErrNoRate          = errors.New("tidak ada tarif untuk jarak pengiriman ini")
+	ErrUnknownLocation = errors.New("lokasi asal atau tujuan pengiriman belum diketahui")
+)
+
+// Point adalah koordinat lokasi asal toko atau tujuan alamat
+type Point struct {
+	Latitude  float64 `json:"latitude"`
+	Longitude float64 `json:"longitude"`
+}
+
+// Rate adalah satu baris tabel tarif shipping method untuk rentang jarak tertentu.
+// Harga dalam satuan terkecil mata uang (rupiah).
+type Rate struct {
+	MinDistanceKm float64
+	MaxDistanceKm *float64 // nil berarti tanpa batas atas
+	BasePrice     int64
+	PricePerKg    int64
+	EtdMinDays    int
+	EtdMaxDays    int
+}
+
+// Item adalah satu baris produk di paket beserta berat dan dimensi per unit
+type Item struct {
+	WeightGram int
+	LengthCm   int
+	WidthCm    int
+	HeightCm   int
+	Quantity   int64
+}
+
+// Shipment adalah paket dari satu toko ke satu alamat tujuan
+type Shipment struct {
+	Origin      *Point
+	Destination *Point
+	Items       []Item
+}
+
+// Quote adalah hasil perhitungan ongkir untuk satu paket
+type Quote struct {
+	DistanceKm         float64 `json:"distance_km"`
+	WeightGram         int64   `json:"weight_gram"`
+	ChargeableWeightKg int64   `json:"chargeable_weight_kg"`
+	Cost               int64   `json:"cost"`
+	EtdMinDays         int     `json:"etd_min_days"`
+	EtdMaxDays         int     `json:"etd_max_days"`
+}
+
+// Calculate menghitung ongkir paket memakai tarif yang rentang jaraknya cocok.
+// Berat yang ditagih adalah yang lebih besar antara berat aktual dan volumetrik, dibulatkan ke atas per kg
+func Calculate(rates []Rate, s Shipment) (*Quote, error) {
+	if s.Origin == nil || s.Destination == nil {
+		return nil, ErrUnknownLocation
+	}
+
+	distance := DistanceKm(*s.Origin, *s.Destination)
+
+	rate, ok := findRate(rates, distance)
+	if !ok {
+		return nil, ErrNoRate
+	}
+
+	weight, chargeable := ChargeableWeight(s.Items)
+
+	return &Quote{
+		DistanceKm:         math.Round(distance*10) / 10,
+		WeightGram:         weight,
+		ChargeableWeightKg: chargeable,
+		Cost:               rate.BasePrice + rate.PricePerKg*chargeable,
+		EtdMinDays:         rate.EtdMinDays,
+		EtdMaxDays:         rate.EtdMaxDays,
+	}, nil
+}
+
+// ChargeableWeight mengembalikan total berat aktual dalam gram dan berat tagihan dalam kg (minimal 1 kg)
+func ChargeableWeight(items []Item) (int64, int64) {
+	var actual, chargeable float64
+	for _, item := range items {
+		qty := float64(item.Quantity)
+		weight := float64(item.WeightGram)
+		volumetric := float64(item.LengthCm*item.WidthCm*item.HeightCm) / VolumetricDivisor * 1000
+
+		actual += weight * qty
+		chargeable += math.Max(weight, volumetric) * qty
+	}
+
+	kg := int64(math.Ceil(chargeable / 1000))
+	if kg < 1 {
+		kg = 1
+	}
+
+	return int64(actual), kg
+}
+
+// DistanceKm menghitung jarak garis lurus dua titik dengan rumus haversine
+func DistanceKm(a, b Point) float64 {
+	lat1 := a.Latitude * math.Pi / 180
+	lat2 := b.Latitude * math.Pi / 180
+	dLat := (b.Latitude - a.Latitude) * math.Pi / 180
+	dLng := (b.Longitude - a.Longitude) * math.Pi / 180
+
+	h := math.Sin(dLat/2)*math.Sin(dLat/2) +
+		math.Cos(lat1)*math.Cos(lat2)*math.Sin(dLng/2)*math.Sin(dLng/2)
+
+	return 2 * earthRadiusKm * math.Asin(math.Min(1, math.Sqrt(h)))
+}
+
+func findRate(rates []Rate, distance float64) (Rate, bool) {
+	for _, r := range rates {
+		if distance < r.MinDistanceKm {
+			continue
+		}
+		if r.MaxDistanceKm != nil && distance >= *r.MaxDistanceKm {
+			continue
+		}
+		return r, true
+	}
+
+	return Rate{}, false
+}
@@ -0,0 +1,54 @@
+package cache
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/yogaprasetya22/api-gotokopedia/internal/store"
+)
+
+// feedExpTime cukup pendek agar produk yang baru dilihat atau dibeli segera mengubah feed,
+// dan lebih pendek dari store.FeedSnapshotTTL agar cursor di halaman cache masih berlaku
+const feedExpTime = 5 * time.Minute
+
+type FeedStore struct {
+	rdb *redis.Client
+}
+
+// key membedakan halaman feed per user dan per query, termasuk cursor halaman
+func (s *FeedStore) key(userID int64, fq store.PaginatedFeedQuery) string {
+	data, _ := json.Marshal(fq)
+	sum := sha1.Sum(data)
+	return fmt.Sprintf("feed:user:%d:%s", userID, hex.EncodeToString(sum[:]))
+}
+
+func (s *FeedStore) Get(ctx context.Context, userID int64, fq store.PaginatedFeedQuery) (*store.Paginated[*store.Product], error) {
+	data, err := s.rdb.Get(ctx, s.key(userID, fq)).Bytes()
+	if err == redis.Nil {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	var page store.Paginated[*store.Product]
+	if err := json.Unmarshal(data, &page); err != nil {
+		return nil, err
+	}
+
+	return &page, nil
+}
+
+// Set menyimpan halaman feed yang cursor-nya sudah ditandatangani
+func (s *FeedStore) Set(ctx context.Context, userID int64, fq store.PaginatedFeedQuery, page *store.Paginated[*store.Product]) error {
+	data, err := json.Marshal(page)
+	if err != nil {
+		return err
+	}
+
+	return s.rdb.Set(ctx, s.key(userID, fq), data, feedExpTime).Err()
+}
@@ -0,0 +1,59 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/yogaprasetya22/api-gotokopedia/internal/store"
+)
+
+type IdempotencyStore struct {
+	rdb *redis.Client
+}
+
+func (s *IdempotencyStore) key(userID int64, key string) string {
+	return fmt.Sprintf("idempotency:user:%d:%s", userID, key)
+}
+
+func (s *IdempotencyStore) Get(ctx context.Context, userID int64, key string) (*store.IdempotencyRecord, error) {
+	data, err := s.rdb.Get(ctx, s.key(userID, key)).Bytes()
+	if err == redis.Nil {
+		return nil, store.ErrNotFound
+	} else if err != nil {
+		return nil, err
+	}
+
+	var record store.IdempotencyRecord
+	if err := json.Unmarshal(data, &record); err != nil {
+		return nil, err
+	}
+
+	return &record, nil
+}
+
+func (s *IdempotencyStore) Reserve(ctx context.Context, record *store.IdempotencyRecord) (bool, error) {
+	record.CreatedAt = time.Now()
+
+	data, err := json.Marshal(record)
+	if err != nil {
+		return false, err
+	}
+
+	return s.rdb.SetNX(ctx, s.key(record.UserID, record.Key), data, store.IdempotencyKeyTTL).Result()
+}
+
+func (s *IdempotencyStore) Save(ctx context.Context, record *store.IdempotencyRecord) error {
+	data, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+
+	return s.rdb.Set(ctx, s.key(record.UserID, record.Key), data, redis.KeepTTL).Err()
+}
+
+func (s *IdempotencyStore) Delete(ctx context.Context, userID int64, key string) error {
+	return s.rdb.Del(ctx, s.key(userID, key)).Err()
+}
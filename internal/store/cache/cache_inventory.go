@@ -0,0 +1,190 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+	"github.com/yogaprasetya22/api-gotokopedia/internal/store"
+)
+
+// reserveScript memeriksa dan menahan stok semua item secara atomik.
+// Setiap produk atau varian punya hash session -> jumlah dan sorted set session -> waktu kadaluarsa,
+// tahanan session yang sudah kadaluarsa dibuang sebelum stok dihitung.
+//
+// KEYS: pasangan hash tahanan dan sorted set kadaluarsa per item
+// ARGV: session id, sekarang (ms), kadaluarsa session (ms), lalu pasangan jumlah dan stok per item
+// Mengembalikan 0 jika berhasil atau urutan item (mulai 1) yang stoknya tidak cukup
+var reserveScript = redis.NewScript(`
+local session = ARGV[1]
+local now = tonumber(ARGV[2])
+local expiresAt = tonumber(ARGV[3])
+local n = #KEYS / 2
+
+for i = 1, n do
+	local holds, expiry = KEYS[2 * i - 1], KEYS[2 * i]
+
+	local expired = redis.call('ZRANGEBYSCORE', expiry, '-inf', now)
+	for _, member in ipairs(expired) do
+		redis.call('HDEL', holds, member)
+	end
+	redis.call('ZREMRANGEBYSCORE', expiry, '-inf', now)
+
+	local held = 0
+	local entries = redis.call('HGETALL', holds)
+	for j = 1, #entries, 2 do
+		if entries[j] ~= session then
+			held = held + tonumber(entries[j + 1])
+		end
+	end
+
+	if tonumber(ARGV[2 + 2 * i]) > tonumber(ARGV[3 + 2 * i]) - held then
+		return i
+	end
+end
+
+for i = 1, n do
+	local holds, expiry = KEYS[2 * i - 1], KEYS[2 * i]
+
+	redis.call('HSET', holds, session, ARGV[2 + 2 * i])
+	redis.call('ZADD', expiry, expiresAt, session)
+
+	-- Key hidup sampai tahanan terakhir kadaluarsa, bukan diperpanjang setiap reserve
+	local last = redis.call('ZRANGE', expiry, -1, -1, 'WITHSCORES')
+	redis.call('PEXPIREAT', holds, last[2])
+	redis.call('PEXPIREAT', expiry, last[2])
+end
+
+return 0
+`)
+
+// InventoryReservationStore menahan stok checkout per session di Redis
+type InventoryReservationStore struct {
+	rdb *redis.Client
+}
+
+// stockKey adalah key tahanan satu produk, atau satu varian jika item memakai varian
+func (s *InventoryReservationStore) stockKey(item store.ReservationItem) string {
+	if item.VariantID != nil {
+		return fmt.Sprintf("inventory_lock:variant:%d", *item.VariantID)
+	}
+	return fmt.Sprintf("inventory_lock:product:%d", item.ProductID)
+}
+
+func (s *InventoryReservationStore) expiryKey(stockKey string) string {
+	return stockKey + ":expiry"
+}
+
+// sessionKey menyimpan daftar key stok yang ditahan session agar bisa dilepas tanpa membaca session checkout
+func (s *InventoryReservationStore) sessionKey(sessionID string) string {
+	return fmt.Sprintf("inventory_lock:session:%s", sessionID)
+}
+
+// cartStoreKey menunjuk session terakhir yang menahan stok cart store
+func (s *InventoryReservationStore) cartStoreKey(cartStoreID uuid.UUID) string {
+	return fmt.Sprintf("inventory_lock:cart_store:%s", cartStoreID)
+}
+
+// Reserve menahan stok semua item session sampai session kadaluarsa. Memanggil ulang untuk session
+// yang sama menggantikan jumlah sebelumnya, session lama pada cart store yang sama dilepas.
+func (s *InventoryReservationStore) Reserve(ctx context.Context, session *store.CheckoutSession) error {
+	ttl := time.Until(session.ExpiresAt)
+	if ttl <= 0 {
+		return store.ErrSessionExpired
+	}
+
+	// Lepas tahanan session lama yang sudah digantikan session ini
+	for _, cs := range session.CartStore {
+		previous, err := s.rdb.Get(ctx, s.cartStoreKey(cs.ID)).Result()
+		if err == redis.Nil {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+
+		if previous != session.SessionID {
+			if err := s.Release(ctx, previous); err != nil {
+				return err
+			}
+		}
+	}
+
+	// Gabungkan item dengan key stok yang sama
+	var (
+		stockKeys []string
+		items     []store.ReservationItem
+	)
+	index := make(map[string]int)
+	for _, item := range store.ReservationItems(session) {
+		key := s.stockKey(item)
+		if i, ok := index[key]; ok {
+			items[i].Quantity += item.Quantity
+			continue
+		}
+
+		index[key] = len(items)
+		stockKeys = append(stockKeys, key)
+		items = append(items, item)
+	}
+
+	keys := make([]string, 0, len(items)*2)
+	args := []any{session.SessionID, time.Now().UnixMilli(), session.ExpiresAt.UnixMilli()}
+	for i, item := range items {
+		keys = append(keys, stockKeys[i], s.expiryKey(stockKeys[i]))
+		args = append(args, item.Quantity, item.Stock)
+	}
+
+	failed, err := reserveScript.Run(ctx, s.rdb, keys, args...).Int()
+	if err != nil {
+		return err
+	}
+	if failed > 0 {
+		return &store.StockError{ProductID: items[failed-1].ProductID}
+	}
+
+	data, err := json.Marshal(stockKeys)
+	if err != nil {
+		return err
+	}
+
+	pipe := s.rdb.TxPipeline()
+	pipe.Set(ctx, s.sessionKey(session.SessionID), data, ttl)
+	for _, cs := range session.CartStore {
+		pipe.Set(ctx, s.cartStoreKey(cs.ID), session.SessionID, ttl)
+	}
+
+	_, err = pipe.Exec(ctx)
+	return err
+}
+
+// Release melepas hanya jumlah yang ditahan session ini, tahanan session lain pada produk yang sama tetap ada
+func (s *InventoryReservationStore) Release(ctx context.Context, sessionID string) error {
+	sessionKey := s.sessionKey(sessionID)
+
+	data, err := s.rdb.Get(ctx, sessionKey).Bytes()
+	if err == redis.Nil {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	var stockKeys []string
+	if err := json.Unmarshal(data, &stockKeys); err != nil {
+		return err
+	}
+
+	pipe := s.rdb.TxPipeline()
+	for _, key := range stockKeys {
+		pipe.HDel(ctx, key, sessionID)
+		pipe.ZRem(ctx, s.expiryKey(key), sessionID)
+	}
+	pipe.Del(ctx, sessionKey)
+
+	_, err = pipe.Exec(ctx)
+	return err
+}
@@ -2,15 +2,13 @@ package cache
 
 import (
 	"context"
-	"time"
 
 	"github.com/redis/go-redis/v9"
 	"github.com/yogaprasetya22/api-gotokopedia/internal/store"
 )
 
 const (
-	checkoutExpTime = 60 * time.Minute
-	lockExpTime     = 60 * time.Minute
+	checkoutExpTime = store.CheckoutSessionTTL
 )
 
 type Storage struct {
@@ -29,20 +27,37 @@ type Storage struct {
 		Set(context.Context, *store.Cart) error
 		Delete(context.Context, int64)
 	}
+	Idempotency interface {
+		Get(ctx context.Context, userID int64, key string) (*store.IdempotencyRecord, error)
+		Reserve(context.Context, *store.IdempotencyRecord) (bool, error)
+		Save(context.Context, *store.IdempotencyRecord) error
+		Delete(ctx context.Context, userID int64, key string) error
+	}
 	Checkout interface {
 		StartCheckoutSession(ctx context.Context, userID int64, cartStore []store.CartStores) (*store.CheckoutSession, error)
 		GetCheckoutSession(ctx context.Context, sessionID string) (*store.CheckoutSession, error)
+		UpdateCheckoutSession(ctx context.Context, session *store.CheckoutSession) error
 		CompleteCheckout(ctx context.Context, sessionID string) error
 		sessionKey(sessionID string) string
-		inventoryLockKey(productID int64) string
+	}
+	Feeds interface {
+		Get(ctx context.Context, userID int64, fq store.PaginatedFeedQuery) (*store.Paginated[*store.Product], error)
+		Set(ctx context.Context, userID int64, fq store.PaginatedFeedQuery, page *store.Paginated[*store.Product]) error
+	}
+	InventoryReservations interface {
+		Reserve(context.Context, *store.CheckoutSession) error
+		Release(ctx context.Context, sessionID string) error
 	}
 }
 
 func NewRedisStore(rbd *redis.Client) Storage {
 	return Storage{
-		Users:    &UserStore{rdb: rbd},
-		Products: &ProductStore{rdb: rbd},
-		Carts:    &CartStore{rdb: rbd},
-		Checkout: &CheckoutStore{rdb: rbd},
+		Users:                 &UserStore{rdb: rbd},
+		Products:              &ProductStore{rdb: rbd},
+		Carts:                 &CartStore{rdb: rbd},
+		Checkout:              &CheckoutStore{rdb: rbd},
+		Idempotency:           &IdempotencyStore{rdb: rbd},
+		InventoryReservations: &InventoryReservationStore{rdb: rbd},
+		Feeds:                 &FeedStore{rdb: rbd},
 	}
 }
@@ -0,0 +1,193 @@
+package store
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"slices"
+	"strings"
+	"time"
+)
+
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// DevCursorSecret adalah secret cursor yang hanya boleh dipakai di development
+const DevCursorSecret = "dev-cursor-secret"
+
+// Cursor adalah posisi keyset sebuah halaman: nilai sort key dan id baris batas halaman.
+// Nilai disimpan sebagai teks dengan presisi penuh agar perbandingan di query berikutnya persis sama.
+type Cursor struct {
+	Scope  string   `json:"s"`           // daftar dan urutan tempat cursor dibuat
+	Keys   []string `json:"k"`           // nilai setiap order key, id selalu terakhir
+	Before bool     `json:"b,omitempty"` // true untuk halaman sebelumnya
+}
+
+// CursorCodec menandatangani cursor dengan HMAC-SHA256 agar klien tidak bisa mengubah posisinya
+type CursorCodec struct {
+	secret []byte
+}
+
+func NewCursorCodec(secret []byte) *CursorCodec {
+	return &CursorCodec{secret: secret}
+}
+
+func (c *CursorCodec) sign(payload []byte) []byte {
+	mac := hmac.New(sha256.New, c.secret)
+	mac.Write(payload)
+	return mac.Sum(nil)
+}
+
+// Encode mengubah cursor menjadi token opaque "payload.signature"
+func (c *CursorCodec) Encode(cur *Cursor) string {
+	payload, err := json.Marshal(cur)
+	if err != nil {
+		return ""
+	}
+
+	enc := base64.RawURLEncoding
+	return enc.EncodeToString(payload) + "." + enc.EncodeToString(c.sign(payload))
+}
+
+// Decode memeriksa tanda tangan token dan mengembalikan cursor-nya
+func (c *CursorCodec) Decode(token string) (*Cursor, error) {
+	enc := base64.RawURLEncoding
+
+	data, sig, ok := strings.Cut(token, ".")
+	if !ok {
+		return nil, ErrInvalidCursor
+	}
+
+	payload, err := enc.DecodeString(data)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	signature, err := enc.DecodeString(sig)
+	if err != nil || !hmac.Equal(signature, c.sign(payload)) {
+		return nil, ErrInvalidCursor
+	}
+
+	var cur Cursor
+	if err := json.Unmarshal(payload, &cur); err != nil || len(cur.Keys) == 0 {
+		return nil, ErrInvalidCursor
+	}
+
+	return &cur, nil
+}
+
+// CursorPage adalah metadata halaman berbasis cursor yang dipakai bersama semua daftar
+type CursorPage struct {
+	Limit      int    `json:"limit"`
+	NextCursor string `json:"next_cursor,omitempty"`
+	PrevCursor string `json:"prev_cursor,omitempty"`
+
+	next, prev *Cursor
+}
+
+// Sign mengisi NextCursor dan PrevCursor dengan token yang ditandatangani codec
+func (p *CursorPage) Sign(codec *CursorCodec) {
+	if p.next != nil {
+		p.NextCursor = codec.Encode(p.next)
+	}
+	if p.prev != nil {
+		p.PrevCursor = codec.Encode(p.prev)
+	}
+}
+
+// Paginated adalah envelope daftar berbasis cursor
+type Paginated[T any] struct {
+	Data       []T        `json:"data"`
+	Pagination CursorPage `json:"pagination"`
+}
+
+// timeKey memformat waktu sebagai nilai cursor tanpa kehilangan presisi mikrodetik
+func timeKey(t time.Time) string {
+	return t.Format(time.RFC3339Nano)
+}
+
+// keysetSelect mengembalikan kolom SELECT yang membaca nilai setiap order key sebagai teks
+func keysetSelect(order []orderKey) string {
+	cols := make([]string, len(order))
+	for i, k := range order {
+		cols[i] = "(" + k.expr + ")::text"
+	}
+	return strings.Join(cols, ", ")
+}
+
+// keyset menambahkan kondisi posisi cursor ke builder dan mengembalikan urutan query. Halaman
+// sebelumnya dibaca dengan urutan terbalik dari cursor, hasilnya dibalik lagi oleh newCursorPage.
+func (b *sqlFilter) keyset(scope string, order []orderKey, cur *Cursor) ([]orderKey, error) {
+	if cur == nil {
+		return order, nil
+	}
+
+	if cur.Scope != scope || len(cur.Keys) != len(order) {
+		return nil, ErrInvalidCursor
+	}
+
+	if cur.Before {
+		reversed := make([]orderKey, len(order))
+		for i, k := range order {
+			reversed[i] = orderKey{expr: k.expr, desc: !k.desc}
+		}
+		order = reversed
+	}
+
+	// (k1 > v1) OR (k1 = v1 AND k2 > v2) OR ... dengan < untuk kolom DESC
+	params := make([]string, len(order))
+	for i, v := range cur.Keys {
+		params[i] = b.arg(v)
+	}
+
+	ors := make([]string, len(order))
+	for i, k := range order {
+		var ands []string
+		for j := 0; j < i; j++ {
+			ands = append(ands, "("+order[j].expr+") = "+params[j])
+		}
+
+		op := " > "
+		if k.desc {
+			op = " < "
+		}
+		ands = append(ands, "("+k.expr+")"+op+params[i])
+
+		ors[i] = "(" + strings.Join(ands, " AND ") + ")"
+	}
+	b.where("(" + strings.Join(ors, " OR ") + ")")
+
+	return order, nil
+}
+
+// newCursorPage memotong baris tambahan dari query LIMIT limit+1 dan menentukan cursor halaman
+// berikutnya dan sebelumnya. keys adalah nilai order key setiap baris dengan urutan yang sama.
+func newCursorPage[T any](rows []T, keys [][]string, scope string, fq PaginatedFeedQuery) ([]T, CursorPage) {
+	page := CursorPage{Limit: fq.Limit}
+
+	backward := fq.Position != nil && fq.Position.Before
+	hasMore := len(rows) > fq.Limit
+	if hasMore {
+		rows, keys = rows[:fq.Limit], keys[:fq.Limit]
+	}
+
+	if backward {
+		slices.Reverse(rows)
+		slices.Reverse(keys)
+	}
+
+	if len(rows) == 0 {
+		return rows, page
+	}
+
+	if hasMore || backward {
+		page.next = &Cursor{Scope: scope, Keys: keys[len(keys)-1]}
+	}
+
+	if (backward && hasMore) || (!backward && (fq.Position != nil || fq.Offset > 0)) {
+		page.prev = &Cursor{Scope: scope, Keys: keys[0], Before: true}
+	}
+
+	return rows, page
+}
@@ -11,10 +11,21 @@ type PaginatedFeedQuery struct {
 	Offset   int    `json:"offset" validate:"gte=0"`
 	Category string `json:"category" validate:"max=100"`
 	Rating   int    `json:"rating" validate:"omitempty,oneof=1 2 3 4 5"`
-	Sort     string `json:"sort" validate:"oneof=asc desc"`
+	Sort     string `json:"sort" validate:"oneof=asc desc relevance newest price_asc price_desc rating best_selling"`
 	Search   string `json:"search" validate:"max=100"`
 	Since    string `json:"since"`
 	Until    string `json:"until"`
+
+	// Filter katalog produk, nilai 0 atau kosong berarti tidak difilter
+	MinPrice float64 `json:"min_price" validate:"gte=0"`
+	MaxPrice float64 `json:"max_price" validate:"gte=0"`
+	Country  string  `json:"country" validate:"max=100"`
+	Discount float64 `json:"discount" validate:"gte=0,lte=100"`
+	InStock  bool    `json:"in_stock"`
+
+	// Token cursor dari next_cursor atau prev_cursor, jika diisi offset diabaikan
+	Cursor   string  `json:"cursor" validate:"max=1024"`
+	Position *Cursor `json:"-"`
 }
 
 func (fq PaginatedFeedQuery) Parse(r *http.Request) (PaginatedFeedQuery, error) {
@@ -70,6 +81,56 @@ func (fq PaginatedFeedQuery) Parse(r *http.Request) (PaginatedFeedQuery, error)
 		fq.Search = search
 	}
 
+	minPrice := qs.Get("min_price")
+	if minPrice != "" {
+		p, err := strconv.ParseFloat(minPrice, 64)
+		if err != nil {
+			return fq, nil
+		}
+
+		fq.MinPrice = p
+	}
+
+	maxPrice := qs.Get("max_price")
+	if maxPrice != "" {
+		p, err := strconv.ParseFloat(maxPrice, 64)
+		if err != nil {
+			return fq, nil
+		}
+
+		fq.MaxPrice = p
+	}
+
+	country := qs.Get("country")
+	if country != "" {
+		fq.Country = country
+	}
+
+	discount := qs.Get("discount")
+	if discount != "" {
+		d, err := strconv.ParseFloat(discount, 64)
+		if err != nil {
+			return fq, nil
+		}
+
+		fq.Discount = d
+	}
+
+	inStock := qs.Get("in_stock")
+	if inStock != "" {
+		b, err := strconv.ParseBool(inStock)
+		if err != nil {
+			return fq, nil
+		}
+
+		fq.InStock = b
+	}
+
+	cursor := qs.Get("cursor")
+	if cursor != "" {
+		fq.Cursor = cursor
+	}
+
 	since := qs.Get("since")
 	if since != "" {
 		fq.Since = parseTime(since)
@@ -0,0 +1,201 @@
+package store
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/bits"
+	"strconv"
+	"strings"
+)
+
+// Currency adalah kode mata uang ISO 4217
+type Currency string
+
+const CurrencyIDR Currency = "IDR"
+
+// DefaultCurrency adalah mata uang semua kolom uang di database
+const DefaultCurrency = CurrencyIDR
+
+// currencyExponent adalah jumlah digit satuan terkecil tiap mata uang, rupiah tidak memakai sen
+var currencyExponent = map[Currency]int{
+	CurrencyIDR: 0,
+}
+
+// Money adalah nominal uang dalam satuan terkecil mata uangnya.
+// Di JSON nominal ditulis sebagai angka dalam satuan utama seperti field float sebelumnya,
+// di database disimpan sebagai bigint satuan terkecil DefaultCurrency.
+type Money struct {
+	Amount   int64
+	Currency Currency
+}
+
+// IDR membuat nominal rupiah
+func IDR(amount int64) Money {
+	return Money{Amount: amount, Currency: CurrencyIDR}
+}
+
+// MoneyFromMajor membuat nominal DefaultCurrency dari angka satuan utama, dibulatkan ke satuan terkecil
+func MoneyFromMajor(value float64) Money {
+	scale := math.Pow10(currencyExponent[DefaultCurrency])
+	return Money{Amount: int64(math.Round(value * scale)), Currency: DefaultCurrency}
+}
+
+func (m Money) currency() Currency {
+	if m.Currency == "" {
+		return DefaultCurrency
+	}
+	return m.Currency
+}
+
+// mustMatch memastikan dua nominal bermata uang sama, menjumlahkan mata uang berbeda adalah bug
+func (m Money) mustMatch(o Money) Currency {
+	if m.currency() != o.currency() {
+		panic(fmt.Sprintf("money: mata uang berbeda %s dan %s", m.currency(), o.currency()))
+	}
+	return m.currency()
+}
+
+// Add menjumlahkan dua nominal
+func (m Money) Add(o Money) Money {
+	return Money{Amount: m.Amount + o.Amount, Currency: m.mustMatch(o)}
+}
+
+// Sub mengurangi nominal dengan o
+func (m Money) Sub(o Money) Money {
+	return Money{Amount: m.Amount - o.Amount, Currency: m.mustMatch(o)}
+}
+
+// Mul mengalikan nominal dengan jumlah unit
+func (m Money) Mul(n int64) Money {
+	return Money{Amount: m.Amount * n, Currency: m.currency()}
+}
+
+// Percent mengembalikan pct persen dari nominal, dibulatkan ke bawah
+func (m Money) Percent(pct float64) Money {
+	return Money{Amount: int64(math.Floor(float64(m.Amount) * pct / 100)), Currency: m.currency()}
+}
+
+// Share mengembalikan bagian num/den dari nominal, dibulatkan ke bawah
+func (m Money) Share(num, den int64) Money {
+	if den == 0 {
+		return Money{Currency: m.currency()}
+	}
+
+	// Perkalian 128-bit agar nominal besar tidak overflow
+	if m.Amount >= 0 && num >= 0 && den > 0 {
+		hi, lo := bits.Mul64(uint64(m.Amount), uint64(num))
+		if hi < uint64(den) {
+			q, _ := bits.Div64(hi, lo, uint64(den))
+			return Money{Amount: int64(q), Currency: m.currency()}
+		}
+	}
+
+	return Money{Amount: int64(math.Floor(float64(m.Amount) * float64(num) / float64(den))), Currency: m.currency()}
+}
+
+// Cmp mengembalikan -1, 0 atau 1 jika m lebih kecil, sama atau lebih besar dari o
+func (m Money) Cmp(o Money) int {
+	m.mustMatch(o)
+	switch {
+	case m.Amount < o.Amount:
+		return -1
+	case m.Amount > o.Amount:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// Min mengembalikan nominal yang lebih kecil
+func (m Money) Min(o Money) Money {
+	if m.Cmp(o) <= 0 {
+		return m
+	}
+	return o
+}
+
+func (m Money) IsZero() bool {
+	return m.Amount == 0
+}
+
+func (m Money) IsNegative() bool {
+	return m.Amount < 0
+}
+
+// Major mengembalikan nominal dalam satuan utama, misalnya untuk gross amount payment gateway
+func (m Money) Major() float64 {
+	return float64(m.Amount) / math.Pow10(currencyExponent[m.currency()])
+}
+
+// String menulis nominal dalam satuan utama beserta kode mata uangnya, contoh "IDR 13899000"
+func (m Money) String() string {
+	return string(m.currency()) + " " + m.majorString()
+}
+
+func (m Money) majorString() string {
+	exp := currencyExponent[m.currency()]
+	if exp == 0 {
+		return strconv.FormatInt(m.Amount, 10)
+	}
+	return strconv.FormatFloat(m.Major(), 'f', exp, 64)
+}
+
+// MarshalJSON menulis nominal sebagai angka satuan utama agar tetap kompatibel dengan field float sebelumnya
+func (m Money) MarshalJSON() ([]byte, error) {
+	return []byte(m.majorString()), nil
+}
+
+// UnmarshalJSON menerima angka atau string angka dalam satuan utama DefaultCurrency
+func (m *Money) UnmarshalJSON(data []byte) error {
+	raw := strings.Trim(string(data), `"`)
+	if raw == "null" || raw == "" {
+		*m = Money{Currency: DefaultCurrency}
+		return nil
+	}
+
+	var value float64
+	if err := json.Unmarshal([]byte(raw), &value); err != nil {
+		return fmt.Errorf("money: nominal %s tidak valid", data)
+	}
+
+	*m = MoneyFromMajor(value)
+	return nil
+}
+
+// Scan membaca kolom uang bigint, kolom numeric atau float lama dibulatkan
+func (m *Money) Scan(src any) error {
+	switch v := src.(type) {
+	case nil:
+		*m = Money{Currency: DefaultCurrency}
+	case int64:
+		*m = Money{Amount: v, Currency: DefaultCurrency}
+	case float64:
+		*m = Money{Amount: int64(math.Round(v)), Currency: DefaultCurrency}
+	case []byte:
+		return m.scanString(string(v))
+	case string:
+		return m.scanString(v)
+	default:
+		return fmt.Errorf("money: tidak bisa membaca %T", src)
+	}
+	return nil
+}
+
+func (m *Money) scanString(s string) error {
+	value, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return fmt.Errorf("money: nominal %q tidak valid", s)
+	}
+	*m = Money{Amount: int64(math.Round(value)), Currency: DefaultCurrency}
+	return nil
+}
+
+// Value menyimpan nominal sebagai bigint satuan terkecil
+func (m Money) Value() (driver.Value, error) {
+	if m.currency() != DefaultCurrency {
+		return nil, fmt.Errorf("money: mata uang %s tidak bisa disimpan", m.currency())
+	}
+	return m.Amount, nil
+}
@@ -8,6 +8,7 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/yogaprasetya22/api-gotokopedia/internal/shipping"
 )
 
 type StockError struct {
@@ -30,16 +31,25 @@ var (
 type Storage struct {
 	Products interface {
 		GetProduct(ctx context.Context, slug_toko, slug_product string) (*DetailProduct, error)
-		GetProductByTokos(ctx context.Context, slug_toko string, query PaginatedFeedQuery) ([]*Product, error)
+		GetProductByTokos(ctx context.Context, slug_toko string, query PaginatedFeedQuery) (*Paginated[*Product], error)
 		GetByID(context.Context, int64) (*Product, error)
 		GetByTokoID(context.Context, int64) ([]*Product, error)
-		GetProductFeed(context.Context, []int64, PaginatedFeedQuery) ([]*Product, error)
-		GetProductCategoryFeed(context.Context, PaginatedFeedQuery) ([]*Product, error)
-		GetAllProduct(context.Context, PaginatedFeedQuery) ([]*Product, error)
+		GetProductFeed(context.Context, []int64, PaginatedFeedQuery) (*Paginated[*Product], error)
+		GetProductCategoryFeed(context.Context, PaginatedFeedQuery) (*Paginated[*Product], error)
+		GetAllProduct(context.Context, PaginatedFeedQuery) (*Paginated[*Product], error)
+		GetProductFacets(context.Context, ProductFilter) (*ProductFacets, error)
+		GetPersonalizedFeed(context.Context, int64, PaginatedFeedQuery) (*Paginated[*Product], error)
+		RecordView(ctx context.Context, userID, productID int64) error
+		DeleteExpiredFeedSnapshots(context.Context) (int64, error)
 		Create(context.Context, *Product) error
 		Update(context.Context, *Product) error
 		Delete(context.Context, int64) error
 	}
+	ProductVariants interface {
+		GetByProductID(ctx context.Context, productID int64) (*VariantMatrix, error)
+		GetByID(ctx context.Context, id int64) (*ProductVariant, error)
+		Replace(ctx context.Context, productID int64, matrix *VariantMatrix) error
+	}
 	Categoris interface {
 		GetAll(context.Context) ([]*Category, error)
 		GetByID(context.Context, int64) (*Category, error)
@@ -81,7 +91,7 @@ type Storage struct {
 		GetCartByUserID(ctx context.Context, userID int64) (*Cart, error)
 		GetCartByUserIDPQ(ctx context.Context, userID int64, query PaginatedFeedQuery) (*MetaCart, error)
 		GetCartStoresByID(ctx context.Context, cartStoreID []uuid.UUID) ([]CartStores, error)
-		AddToCartTransaction(ctx context.Context, userID, productID, quantity int64) (*Cart, error)
+		AddToCartTransaction(ctx context.Context, userID, productID int64, variantID *int64, quantity int64) (*Cart, error)
 		IncreaseQuantityCartStoreItemTransaction(ctx context.Context, cartStoreItemID uuid.UUID, userID int64) error
 		DecreaseQuantityCartStoreItemTransaction(ctx context.Context, cartStoreItemID uuid.UUID, userID int64) error
 		GetDetailCartByCartStoreID(ctx context.Context, cartStoreID uuid.UUID, userID int64) (*CartDetailResponse, error)
@@ -92,9 +102,17 @@ type Storage struct {
 	}
 	Orders interface {
 		CreateFromCart(ctx context.Context, cartStoreID uuid.UUID, userID, paymentMethodID, shippingMethodID int64, shippingAddressesID uuid.UUID, notes string) error
-		UpdateStatus(ctx context.Context, orderID, statusID int64, notes string) error
+		UpdateStatus(ctx context.Context, orderID, statusID int64, notes string, actor OrderActor) error
+		Cancel(ctx context.Context, orderID, userID int64, notes string) error
+		Refund(ctx context.Context, orderID int64, notes string, actor OrderActor) error
 		GetByID(ctx context.Context, id int64) (*Order, error)
 		GetByUserID(ctx context.Context, userID int64, fq PaginatedFeedQuery) ([]*Order, error)
+		ListByUserID(ctx context.Context, userID int64, fq PaginatedFeedQuery) (*Paginated[*Order], error)
+		GetByTokoID(ctx context.Context, tokoID int64, filter SellerOrderFilter, fq PaginatedFeedQuery) ([]*Order, error)
+		AcceptBySeller(ctx context.Context, tokoID, orderID int64, notes string, actor OrderActor) error
+		ShipBySeller(ctx context.Context, tokoID, orderID int64, trackingNumber, notes string, actor OrderActor) error
+		DeliverBySeller(ctx context.Context, tokoID, orderID int64, notes string, actor OrderActor) error
+		ExpireUnpaid(ctx context.Context, deadline time.Time, limit int) ([]ExpiredOrder, error)
 		GetShippingMethods(ctx context.Context) ([]*ShippingMethod, error)
 		GetShippingMethodByID(ctx context.Context, id int64) (*ShippingMethod, error)
 		GetPaymentMethods(ctx context.Context) ([]*PaymentMethod, error)
@@ -110,7 +128,7 @@ type Storage struct {
 		Delete(context.Context, uuid.UUID, int64) error
 	}
 	Checkout interface {
-		CreateOrderFromCheckout(ctx context.Context, checkout *CheckoutSession) error
+		CreateOrderFromCheckout(ctx context.Context, checkout *CheckoutSession) ([]*Order, error)
 	}
 	PaymentMethods interface {
 		GetAll(context.Context) ([]*PaymentMethod, error)
@@ -119,6 +137,34 @@ type Storage struct {
 		Update(context.Context, *PaymentMethod) error
 		Delete(context.Context, int64) error
 	}
+	Payments interface {
+		Create(context.Context, *Payment) error
+		GetByOrderID(context.Context, int64) (*Payment, error)
+		GetByOrderNumber(context.Context, string) (*Payment, error)
+		GetByTransactionID(context.Context, string) (*Payment, error)
+		AttachCharge(ctx context.Context, orderID int64, transactionID string) (*Payment, error)
+		FailCharge(ctx context.Context, orderID int64, notes string) error
+		UpdateStatus(ctx context.Context, transactionID, status string) (*Payment, error)
+	}
+	IdempotencyKeys interface {
+		Get(ctx context.Context, userID int64, key string) (*IdempotencyRecord, error)
+		Reserve(context.Context, *IdempotencyRecord) (bool, error)
+		Save(context.Context, *IdempotencyRecord) error
+		Delete(ctx context.Context, userID int64, key string) error
+		DeleteExpired(context.Context) (int64, error)
+	}
+	CheckoutSessions interface {
+		StartCheckoutSession(ctx context.Context, userID int64, cartStore []CartStores) (*CheckoutSession, error)
+		GetCheckoutSession(ctx context.Context, sessionID string) (*CheckoutSession, error)
+		UpdateCheckoutSession(ctx context.Context, session *CheckoutSession) error
+		CompleteCheckout(ctx context.Context, sessionID string) error
+		DeleteExpired(context.Context) (int64, error)
+	}
+	InventoryReservations interface {
+		Reserve(context.Context, *CheckoutSession) error
+		Release(ctx context.Context, sessionID string) error
+		DeleteExpired(context.Context) (int64, error)
+	}
 	ShippingMethods interface {
 		GetAll(context.Context) ([]*ShippingMethod, error)
 		GetByID(context.Context, int64) (*ShippingMethod, error)
@@ -126,23 +172,49 @@ type Storage struct {
 		Update(context.Context, *ShippingMethod) error
 		Delete(context.Context, int64) error
 	}
+	Regions interface {
+		GetByID(ctx context.Context, id string) (*Region, error)
+		GetChildren(ctx context.Context, parentID string) ([]*Region, error)
+		GetPath(ctx context.Context, id string) ([]*Region, error)
+	}
+	ShippingRates interface {
+		GetByMethodIDs(ctx context.Context, methodIDs []int64) (map[int64][]shipping.Rate, error)
+		GetCartStoreParcels(ctx context.Context, cartStoreIDs []uuid.UUID) ([]*CartStoreParcel, error)
+	}
+	Vouchers interface {
+		Create(context.Context, *Voucher) error
+		Update(context.Context, *Voucher) error
+		GetByID(context.Context, int64) (*Voucher, error)
+		GetByCode(context.Context, string) (*Voucher, error)
+		List(context.Context) ([]*Voucher, error)
+		Delete(context.Context, int64) error
+		CountUserUsage(ctx context.Context, voucherID, userID int64) (int, error)
+	}
 }
 
 func NewStorage(db *sql.DB) Storage {
 	return Storage{
-		Users:             &UserStore{db},
-		Roles:             &RoleStore{db},
-		Follow:            &FollowerStore{db},
-		Categoris:         &CategoryStore{db},
-		Products:          &ProductStore{db},
-		Tokos:             &TokoStore{db},
-		Comments:          &CommentStore{db},
-		Carts:             &CartStore{db},
-		Orders:            &OrderStore{db},
-		ShippingAddresses: &ShippingAddresStore{db},
-		Checkout:          &CheckoutStore{db},
-		PaymentMethods:    &PaymentMethodStore{db},
-		ShippingMethods:   &ShippingMethodStore{db},
+		Users:                 &UserStore{db},
+		Roles:                 &RoleStore{db},
+		Follow:                &FollowerStore{db},
+		Categoris:             &CategoryStore{db},
+		Products:              &ProductStore{db},
+		ProductVariants:       &ProductVariantStore{db},
+		Tokos:                 &TokoStore{db},
+		Comments:              &CommentStore{db},
+		Carts:                 &CartStore{db},
+		Orders:                &OrderStore{db},
+		ShippingAddresses:     &ShippingAddresStore{db},
+		Checkout:              &CheckoutStore{db},
+		PaymentMethods:        &PaymentMethodStore{db},
+		Payments:              &PaymentStore{db},
+		IdempotencyKeys:       &IdempotencyStore{db},
+		CheckoutSessions:      &CheckoutSessionStore{db},
+		InventoryReservations: &InventoryReservationStore{db},
+		ShippingMethods:       &ShippingMethodStore{db},
+		ShippingRates:         &ShippingRateStore{db},
+		Regions:               &RegionStore{db},
+		Vouchers:              &VoucherStore{db},
 	}
 }
 
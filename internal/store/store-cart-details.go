@@ -8,6 +8,7 @@ import (
 
 	"github.com/google/uuid"
 	"github.com/lib/pq"
+	"github.com/yogaprasetya22/api-gotokopedia/internal/pricing"
 )
 
 type CartDetailResponse struct {
@@ -22,19 +23,22 @@ type CartStoreDetail struct {
 	ID       uuid.UUID        `json:"id"`
 	Toko     TokoResponse     `json:"toko"`
 	Items    []CartItemDetail `json:"items"`
-	Subtotal float64          `json:"subtotal"`
+	Subtotal Money            `json:"subtotal"`
 }
 
 type CartItemDetail struct {
-	ID            uuid.UUID `json:"id"`
-	ProductID     int64     `json:"product_id"`
-	Name          string    `json:"name"`
-	ImageURL      []string  `json:"image_url"`
-	Price         float64   `json:"price"`
-	Discount      float64   `json:"discount"`
-	DiscountPrice float64   `json:"discount_price" `
-	Quantity      int       `json:"quantity"`
-	TotalPrice    float64   `json:"total_price"`
+	ID            uuid.UUID         `json:"id"`
+	ProductID     int64             `json:"product_id"`
+	VariantID     *int64            `json:"variant_id,omitempty"`
+	VariantName   string            `json:"variant_name,omitempty"`
+	Name          string            `json:"name"`
+	ImageURL      []string          `json:"image_url"`
+	Price         Money             `json:"price"`
+	Discount      float64           `json:"discount"`
+	DiscountPrice Money             `json:"discount_price" `
+	Quantity      int               `json:"quantity"`
+	TotalPrice    Money             `json:"total_price"`
+	Pricing       pricing.Breakdown `json:"pricing"`
 }
 
 type TokoResponse struct {
@@ -108,14 +112,15 @@ func (cs *CartStore) GetDetailCartByCartStoreID(ctx context.Context, cartStoreID
         SELECT 
             ci.id, 
             ci.product_id, 
+            ci.variant_id,
+            COALESCE(v.name, ''),
             p.name, 
             p.image_urls, 
-            p.price,
-            p.discount_price,
-            p.discount, 
+            ` + cartItemPriceColumns + `, 
             ci.quantity
         FROM cart_items ci
         JOIN products p ON p.id = ci.product_id
+        LEFT JOIN product_variants v ON v.id = ci.variant_id
         WHERE ci.cart_store_id = $1
     `
 	itemRows, err := tx.QueryContext(ctx, itemQuery, cartStore.ID)
@@ -125,15 +130,19 @@ func (cs *CartStore) GetDetailCartByCartStoreID(ctx context.Context, cartStoreID
 	defer itemRows.Close()
 
 	var items []CartItemDetail
-	var subtotal float64
+	subtotal := IDR(0)
 
 	for itemRows.Next() {
 		var item CartItemDetail
-		var price, discountPrice, discount float64
+		var price, discountPrice Money
+		var discount float64
+		var variantID sql.NullInt64
 
 		if err := itemRows.Scan(
 			&item.ID,
 			&item.ProductID,
+			&variantID,
+			&item.VariantName,
 			&item.Name,
 			pq.Array(&item.ImageURL),
 			&price,
@@ -144,13 +153,17 @@ func (cs *CartStore) GetDetailCartByCartStoreID(ctx context.Context, cartStoreID
 			return nil, err
 		}
 
-		// Determine which price to use
+		if variantID.Valid {
+			item.VariantID = &variantID.Int64
+		}
+
 		item.Price = price
 		item.DiscountPrice = discountPrice
 		item.Discount = discount
 
-		item.TotalPrice = price * float64(item.Quantity)
-		subtotal += item.TotalPrice
+		item.Pricing = pricing.Line(price.Amount, discountPrice.Amount, discount, int64(item.Quantity))
+		item.TotalPrice = IDR(item.Pricing.Subtotal)
+		subtotal = subtotal.Add(item.TotalPrice)
 
 		items = append(items, item)
 	}
@@ -9,6 +9,7 @@ import (
 
 	"github.com/google/uuid"
 	"github.com/lib/pq"
+	"github.com/yogaprasetya22/api-gotokopedia/internal/pricing"
 )
 
 // ==============================================
@@ -44,19 +45,30 @@ type CartItem struct {
 	CartID      int64     `json:"cart_id"`
 	CartStoreID uuid.UUID `json:"cart_store_id,omitempty"` // Nullable di database
 	ProductID   int64     `json:"product_id"`
+	VariantID   *int64    `json:"variant_id,omitempty"`
 	Quantity    int64     `json:"quantity"`
 	CreatedAt   time.Time `json:"created_at"`
 	UpdatedAt   time.Time `json:"updated_at"`
 
 	// Relasi
-	Product *Product `json:"product,omitempty"`
+	Product *Product        `json:"product,omitempty"`
+	Variant *ProductVariant `json:"variant,omitempty"`
+}
+
+// Pricing mengembalikan rincian harga item, memakai harga varian jika item memakai varian
+func (i *CartItem) Pricing() pricing.Breakdown {
+	if i.Variant != nil {
+		return i.Variant.Pricing(i.Quantity)
+	}
+	return i.Product.Pricing(i.Quantity)
 }
 
 // MetaCart berisi informasi lengkap keranjang + total
 type MetaCart struct {
-	Cart       *Cart   `json:"cart"`
-	TotalItems int64   `json:"total_items"`
-	TotalPrice float64 `json:"total_price"`
+	Cart       *Cart      `json:"cart"`
+	TotalItems int64      `json:"total_items"`
+	TotalPrice Money      `json:"total_price"`
+	Pagination CursorPage `json:"pagination"`
 }
 
 // CartStore menyediakan operasi database untuk keranjang
@@ -175,23 +187,43 @@ func (s *CartStore) FindOrCreateCart(ctx context.Context, tx *sql.Tx, userID int
 	}
 }
 
-// GetCartTotals menghitung total item dan harga dalam cart
-func (s *CartStore) GetCartTotals(ctx context.Context, cartID int64) (totalItems int64, totalPrice float64, err error) {
+// GetCartTotals menghitung total item dan harga dalam cart dengan harga efektif dari pricing
+func (s *CartStore) GetCartTotals(ctx context.Context, cartID int64) (totalItems int64, totalPrice Money, err error) {
 	query := `
 		SELECT 
-			COALESCE(SUM(ci.quantity), 0) AS total_items,
-			COALESCE(SUM(ci.quantity * p.price), 0) AS total_price
+			ci.quantity, ` + cartItemPriceColumns + `
 		FROM 
 			cart_items ci
 		JOIN 
 			products p ON ci.product_id = p.id
+		LEFT JOIN 
+			product_variants v ON ci.variant_id = v.id
 		WHERE 
 			ci.cart_id = $1`
 
-	err = s.db.QueryRowContext(ctx, query, cartID).Scan(&totalItems, &totalPrice)
+	rows, err := s.db.QueryContext(ctx, query, cartID)
 	if err != nil {
-		return 0, 0, err
+		return 0, Money{}, err
 	}
+	defer rows.Close()
+
+	totalPrice = IDR(0)
+	for rows.Next() {
+		var quantity int64
+		var price, discountPrice Money
+		var discount float64
+		if err := rows.Scan(&quantity, &price, &discountPrice, &discount); err != nil {
+			return 0, Money{}, err
+		}
+
+		totalItems += quantity
+		totalPrice = totalPrice.Add(IDR(pricing.Line(price.Amount, discountPrice.Amount, discount, quantity).Subtotal))
+	}
+
+	if err := rows.Err(); err != nil {
+		return 0, Money{}, err
+	}
+
 	return totalItems, totalPrice, nil
 }
 
@@ -240,32 +272,49 @@ func (s *CartStore) findOrCreateCartStore(ctx context.Context, tx *sql.Tx, cartI
 	}
 }
 
-// getCartStores mendapatkan semua store dalam cart
-func (s *CartStore) getCartStores(ctx context.Context, tx *sql.Tx, cartID int64, fq PaginatedFeedQuery) ([]CartStores, error) {
+// getCartStores mendapatkan satu halaman store dalam cart
+func (s *CartStore) getCartStores(ctx context.Context, tx *sql.Tx, cartID int64, fq PaginatedFeedQuery) ([]CartStores, CursorPage, error) {
+	desc := fq.Sort != "asc"
+
+	b := &sqlFilter{}
+	b.where("cs.cart_id = " + b.arg(cartID))
+
+	scope := "cart_stores:" + sortDirection(fq.Sort)
+	order, err := b.keyset(scope, []orderKey{{expr: "cs.created_at", desc: desc}, {expr: "cs.id", desc: desc}}, fq.Position)
+	if err != nil {
+		return nil, CursorPage{}, err
+	}
+
+	offset := fq.Offset
+	if fq.Offset > 0 && fq.Limit > 0 {
+		offset = fq.Offset * fq.Limit
+	}
+	if fq.Position != nil {
+		offset = 0
+	}
+
 	query := `
 		SELECT cs.id, cs.cart_id, cs.toko_id, cs.created_at,
                t.id, t.user_id, t.slug, t.name, t.image_profile, t.country, t.created_at
         FROM cart_stores cs
         JOIN tokos t ON cs.toko_id = t.id
-        WHERE cs.cart_id = $1
-        ORDER BY cs.created_at ` + fq.Sort + `
-        LIMIT $2 OFFSET $3`
+        ` + b.clause() + `
+        ORDER BY ` + orderByClause(order) + `
+        LIMIT ` + b.arg(fq.Limit+1) + ` OFFSET ` + b.arg(offset)
 
 	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
 	defer cancel()
 
-	offset := fq.Offset
-	if fq.Offset > 0 && fq.Limit > 0 {
-		offset = fq.Offset * fq.Limit
-	}
-
-	rows, err := tx.QueryContext(ctx, query, cartID, fq.Limit, offset)
+	rows, err := tx.QueryContext(ctx, query, b.args...)
 	if err != nil {
-		return nil, err
+		return nil, CursorPage{}, err
 	}
 	defer rows.Close()
 
-	var stores []CartStores
+	var (
+		stores []CartStores
+		keys   [][]string
+	)
 	for rows.Next() {
 		var store CartStores
 		var toko Toko
@@ -283,17 +332,19 @@ func (s *CartStore) getCartStores(ctx context.Context, tx *sql.Tx, cartID int64,
 			&toko.CreatedAt,
 		)
 		if err != nil {
-			return nil, err
+			return nil, CursorPage{}, err
 		}
 		store.Toko = &toko
 		stores = append(stores, store)
+		keys = append(keys, []string{timeKey(store.CreatedAt), store.ID.String()})
 	}
 
 	if err := rows.Err(); err != nil {
-		return nil, err
+		return nil, CursorPage{}, err
 	}
 
-	return stores, nil
+	stores, page := newCursorPage(stores, keys, scope, fq)
+	return stores, page, nil
 }
 
 // GetCartStoresByID mendapatkan cart store berdasarkan []ID
@@ -414,6 +465,7 @@ func (s *CartStore) getCartItemsByStore(ctx context.Context, tx *sql.Tx, cartID
         SELECT 
             ci.id, ci.cart_id, ci.cart_store_id, ci.product_id, ci.quantity, 
             ci.created_at, ci.updated_at,
+            v.id, v.sku, v.name, v.price, v.discount_price, v.stock, COALESCE(v.image_url, ''), v.is_active, v.version,
             p.id, p.name, p.slug, p.description, p.country, 
             p.price, p.discount_price, p.discount, p.estimation, 
             p.stock, p.sold, p.is_for_sale, p.is_approved, 
@@ -426,6 +478,7 @@ func (s *CartStore) getCartItemsByStore(ctx context.Context, tx *sql.Tx, cartID
 		JOIN category c ON p.category_id = c.id
 		JOIN tokos t ON p.toko_id = t.id
 		JOIN users u ON t.user_id = u.id
+		LEFT JOIN product_variants v ON ci.variant_id = v.id
 		WHERE ci.cart_id = $1 AND ci.cart_store_id = $2
 	`
 
@@ -448,6 +501,11 @@ func (s *CartStore) getCartItemsByStore(ctx context.Context, tx *sql.Tx, cartID
 		var category Category
 		var toko Toko
 		var user SingleUser
+		var variantID sql.NullInt64
+		var variantSKU, variantName, variantImage sql.NullString
+		var variantPrice, variantDiscountPrice Money
+		var variantStock, variantVersion sql.NullInt64
+		var variantActive sql.NullBool
 
 		err := rows.Scan(
 			// Cart Item fields
@@ -459,6 +517,17 @@ func (s *CartStore) getCartItemsByStore(ctx context.Context, tx *sql.Tx, cartID
 			&item.CreatedAt,
 			&item.UpdatedAt,
 
+			// Variant fields, NULL jika item tanpa varian
+			&variantID,
+			&variantSKU,
+			&variantName,
+			&variantPrice,
+			&variantDiscountPrice,
+			&variantStock,
+			&variantImage,
+			&variantActive,
+			&variantVersion,
+
 			// Product fields
 			&product.ID,
 			&product.Name,
@@ -513,6 +582,22 @@ func (s *CartStore) getCartItemsByStore(ctx context.Context, tx *sql.Tx, cartID
 		product.Toko = &toko
 		item.Product = &product
 
+		if variantID.Valid {
+			item.VariantID = &variantID.Int64
+			item.Variant = &ProductVariant{
+				ID:            variantID.Int64,
+				ProductID:     product.ID,
+				SKU:           variantSKU.String,
+				Name:          variantName.String,
+				Price:         variantPrice,
+				DiscountPrice: variantDiscountPrice,
+				Stock:         int(variantStock.Int64),
+				ImageURL:      variantImage.String,
+				IsActive:      variantActive.Bool,
+				Version:       int(variantVersion.Int64),
+			}
+		}
+
 		items = append(items, item)
 	}
 
@@ -523,14 +608,19 @@ func (s *CartStore) getCartItemsByStore(ctx context.Context, tx *sql.Tx, cartID
 	return items, nil
 }
 
-// AddItem menambahkan item ke keranjang dengan grouping by toko
-func (s *CartStore) AddItem(ctx context.Context, tx *sql.Tx, cartID, productID, quantity int64) error {
+// AddItem menambahkan item ke keranjang dengan grouping by toko.
+// Produk yang memiliki varian wajib ditambahkan dengan varian aktif milik produk tersebut.
+func (s *CartStore) AddItem(ctx context.Context, tx *sql.Tx, cartID, productID int64, variantID *int64, quantity int64) error {
 	// Dapatkan info produk untuk mengetahui toko_id
 	product, err := s.getProduct(ctx, tx, productID)
 	if err != nil {
 		return err
 	}
 
+	if err := s.checkVariant(ctx, tx, productID, variantID); err != nil {
+		return err
+	}
+
 	// Cari atau buat cart store berdasarkan toko produk
 	cartStore, err := s.findOrCreateCartStore(ctx, tx, cartID, product.Toko.ID)
 	if err != nil {
@@ -539,17 +629,17 @@ func (s *CartStore) AddItem(ctx context.Context, tx *sql.Tx, cartID, productID,
 
 	// Cek apakah item sudah ada di cart
 	query := `SELECT id, quantity FROM cart_items 
-              WHERE cart_id = $1 AND product_id = $2 AND cart_store_id = $3`
+              WHERE cart_id = $1 AND product_id = $2 AND cart_store_id = $3 AND variant_id IS NOT DISTINCT FROM $4`
 
 	var itemID uuid.UUID
 	var currentQty int64
-	err = tx.QueryRowContext(ctx, query, cartID, productID, cartStore.ID).Scan(&itemID, &currentQty)
+	err = tx.QueryRowContext(ctx, query, cartID, productID, cartStore.ID, variantID).Scan(&itemID, &currentQty)
 
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			// Item belum ada, tambahkan item baru
-			insertQuery := `INSERT INTO cart_items (id, cart_id, cart_store_id, product_id, quantity, created_at, updated_at)
-                          VALUES ($1, $2, $3, $4, $5, $6, $6)
+			insertQuery := `INSERT INTO cart_items (id, cart_id, cart_store_id, product_id, variant_id, quantity, created_at, updated_at)
+                          VALUES ($1, $2, $3, $4, $5, $6, $7, $7)
                           RETURNING id`
 			newID := uuid.New()
 			return tx.QueryRowContext(ctx, insertQuery,
@@ -557,6 +647,7 @@ func (s *CartStore) AddItem(ctx context.Context, tx *sql.Tx, cartID, productID,
 				cartID,
 				cartStore.ID,
 				productID,
+				variantID,
 				quantity,
 				time.Now(),
 			).Scan(&itemID)
@@ -571,6 +662,38 @@ func (s *CartStore) AddItem(ctx context.Context, tx *sql.Tx, cartID, productID,
 	return err
 }
 
+// checkVariant memastikan varian milik produk dan masih aktif, atau produk memang tidak memiliki varian
+func (s *CartStore) checkVariant(ctx context.Context, tx *sql.Tx, productID int64, variantID *int64) error {
+	if variantID == nil {
+		var hasVariants bool
+		err := tx.QueryRowContext(ctx,
+			`SELECT EXISTS (SELECT 1 FROM product_variants WHERE product_id = $1 AND is_active)`,
+			productID,
+		).Scan(&hasVariants)
+		if err != nil {
+			return err
+		}
+		if hasVariants {
+			return ErrVariantRequired
+		}
+		return nil
+	}
+
+	variant, err := getVariantTx(ctx, tx, *variantID)
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return ErrInvalidVariant
+		}
+		return err
+	}
+
+	if variant.ProductID != productID || !variant.IsActive {
+		return ErrInvalidVariant
+	}
+
+	return nil
+}
+
 // getProduct mendapatkan informasi produk (helper function)
 func (s *CartStore) getProduct(ctx context.Context, tx *sql.Tx, id int64) (*Product, error) {
 	const query = `SELECT id, name, slug, country, description, price, discount_price, 
@@ -755,10 +878,11 @@ func (s *CartStore) IncreaseQuantityCartStoreItemTransaction(ctx context.Context
 
 	// 1. Dapatkan informasi produk dari cart item
 	var productID int64
+	var variantID sql.NullInt64
 	var currentQuantity int64
-	query := `SELECT product_id, quantity FROM cart_items 
+	query := `SELECT product_id, variant_id, quantity FROM cart_items 
               WHERE id = $1 AND cart_id = (SELECT id FROM carts WHERE user_id = $2)`
-	err = tx.QueryRowContext(ctx, query, cartstoreItemID, userID).Scan(&productID, &currentQuantity)
+	err = tx.QueryRowContext(ctx, query, cartstoreItemID, userID).Scan(&productID, &variantID, &currentQuantity)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return ErrNotFound
@@ -766,9 +890,13 @@ func (s *CartStore) IncreaseQuantityCartStoreItemTransaction(ctx context.Context
 		return err
 	}
 
-	// 2. Dapatkan stok produk saat ini
+	// 2. Dapatkan stok saat ini, stok varian jika item memakai varian
 	var stock int64
-	err = tx.QueryRowContext(ctx, "SELECT stock FROM products WHERE id = $1", productID).Scan(&stock)
+	if variantID.Valid {
+		err = tx.QueryRowContext(ctx, "SELECT stock FROM product_variants WHERE id = $1", variantID.Int64).Scan(&stock)
+	} else {
+		err = tx.QueryRowContext(ctx, "SELECT stock FROM products WHERE id = $1", productID).Scan(&stock)
+	}
 	if err != nil {
 		return err
 	}
@@ -864,7 +992,7 @@ func (s *CartStore) GetCartByUserIDPQ(ctx context.Context, userID int64, query P
 	}
 
 	// 3. Dapatkan stores dengan pagination
-	stores, err := s.getCartStores(ctx, tx, cart.ID, query)
+	stores, page, err := s.getCartStores(ctx, tx, cart.ID, query)
 	if err != nil {
 		return nil, err
 	}
@@ -888,11 +1016,12 @@ func (s *CartStore) GetCartByUserIDPQ(ctx context.Context, userID int64, query P
 		Cart:       cart,
 		TotalItems: totalItems,
 		TotalPrice: totalPrice,
+		Pagination: page,
 	}, nil
 }
 
 // AddToCartTransaction menambahkan item ke cart dengan transaksi
-func (s *CartStore) AddToCartTransaction(ctx context.Context, userID, productID, quantity int64) (*Cart, error) {
+func (s *CartStore) AddToCartTransaction(ctx context.Context, userID, productID int64, variantID *int64, quantity int64) (*Cart, error) {
 	tx, err := s.db.BeginTx(ctx, nil)
 	if err != nil {
 		return nil, err
@@ -906,7 +1035,7 @@ func (s *CartStore) AddToCartTransaction(ctx context.Context, userID, productID,
 	}
 
 	// Tambahkan item ke cart
-	err = s.AddItem(ctx, tx, cart.ID, productID, quantity)
+	err = s.AddItem(ctx, tx, cart.ID, productID, variantID, quantity)
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,235 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// CheckoutSessionTTL adalah masa berlaku checkout session sejak dibuat
+const CheckoutSessionTTL = 60 * time.Minute
+
+// SameCartStores mengembalikan true jika kedua daftar cart store berisi toko, produk, varian dan quantity yang sama
+func SameCartStores(a, b []CartStores) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for i := range a {
+		if a[i].TokoID != b[i].TokoID {
+			return false
+		}
+
+		if len(a[i].Items) != len(b[i].Items) {
+			return false
+		}
+
+		for j := range a[i].Items {
+			if a[i].Items[j].ProductID != b[i].Items[j].ProductID ||
+				variantKey(a[i].Items[j].VariantID) != variantKey(b[i].Items[j].VariantID) ||
+				a[i].Items[j].Quantity != b[i].Items[j].Quantity {
+				return false
+			}
+		}
+	}
+
+	return true
+}
+
+// OverlappingCartStores mengembalikan true jika kedua daftar cart store memiliki toko yang sama
+func OverlappingCartStores(a, b []CartStores) bool {
+	tokoMap := make(map[int64]bool)
+	for _, cs := range a {
+		tokoMap[cs.TokoID] = true
+	}
+
+	for _, cs := range b {
+		if tokoMap[cs.TokoID] {
+			return true
+		}
+	}
+
+	return false
+}
+
+// CheckoutSessionStore menyimpan checkout session di tabel checkout_sessions, dipakai jika Redis tidak aktif
+type CheckoutSessionStore struct {
+	db *sql.DB
+}
+
+// StartCheckoutSession mengembalikan session aktif user yang isinya sama dengan cartStore. Jika tidak ada,
+// session yang memakai toko yang sama dihapus lalu session baru dibuat.
+func (s *CheckoutSessionStore) StartCheckoutSession(ctx context.Context, userID int64, cartStore []CartStores) (*CheckoutSession, error) {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	var checkout *CheckoutSession
+	err := withTx(s.db, ctx, func(tx *sql.Tx) error {
+		// Kunci session aktif user agar dua request checkout tidak membuat session ganda
+		rows, err := tx.QueryContext(ctx, `
+			SELECT data FROM checkout_sessions
+			WHERE user_id = $1 AND expires_at > now()
+			ORDER BY created_at
+			FOR UPDATE`, userID)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		var sessions []*CheckoutSession
+		for rows.Next() {
+			var data []byte
+			if err := rows.Scan(&data); err != nil {
+				return err
+			}
+
+			var session CheckoutSession
+			if err := json.Unmarshal(data, &session); err != nil {
+				continue
+			}
+			sessions = append(sessions, &session)
+		}
+		if err := rows.Err(); err != nil {
+			return err
+		}
+
+		for _, session := range sessions {
+			if SameCartStores(session.CartStore, cartStore) {
+				checkout = session
+				return nil
+			}
+		}
+
+		for _, session := range sessions {
+			if OverlappingCartStores(session.CartStore, cartStore) {
+				_, err := tx.ExecContext(ctx, `DELETE FROM checkout_sessions WHERE session_id = $1`, session.SessionID)
+				if err != nil {
+					return err
+				}
+			}
+		}
+
+		now := time.Now()
+		checkout = &CheckoutSession{
+			SessionID: uuid.New().String(),
+			UserID:    userID,
+			CartStore: cartStore,
+			CreatedAt: now,
+			ExpiresAt: now.Add(CheckoutSessionTTL),
+		}
+
+		// Total harga memakai harga efektif yang sama dengan order
+		checkout.Recalculate()
+
+		data, err := json.Marshal(checkout)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.ExecContext(ctx, `
+			INSERT INTO checkout_sessions (session_id, user_id, data, created_at, expires_at)
+			VALUES ($1, $2, $3, $4, $5)`,
+			checkout.SessionID, userID, data, checkout.CreatedAt, checkout.ExpiresAt)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return checkout, nil
+}
+
+func (s *CheckoutSessionStore) GetCheckoutSession(ctx context.Context, sessionID string) (*CheckoutSession, error) {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	var data []byte
+	var expired bool
+	err := s.db.QueryRowContext(ctx,
+		`SELECT data, expires_at <= now() FROM checkout_sessions WHERE session_id = $1`, sessionID,
+	).Scan(&data, &expired)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+
+	if expired {
+		return nil, ErrSessionExpired
+	}
+
+	var session CheckoutSession
+	if err := json.Unmarshal(data, &session); err != nil {
+		return nil, err
+	}
+
+	return &session, nil
+}
+
+// UpdateCheckoutSession menyimpan perubahan session tanpa memperpanjang masa berlakunya
+func (s *CheckoutSessionStore) UpdateCheckoutSession(ctx context.Context, session *CheckoutSession) error {
+	data, err := json.Marshal(session)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	res, err := s.db.ExecContext(ctx, `
+		UPDATE checkout_sessions SET data = $1, updated_at = now()
+		WHERE session_id = $2 AND expires_at > now()`,
+		data, session.SessionID)
+	if err != nil {
+		return err
+	}
+
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return ErrSessionExpired
+	}
+
+	return nil
+}
+
+// CompleteCheckout menghapus session setelah order dibuat atau checkout dibatalkan
+func (s *CheckoutSessionStore) CompleteCheckout(ctx context.Context, sessionID string) error {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	res, err := s.db.ExecContext(ctx, `DELETE FROM checkout_sessions WHERE session_id = $1`, sessionID)
+	if err != nil {
+		return err
+	}
+
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+// DeleteExpired menghapus session yang sudah kadaluarsa
+func (s *CheckoutSessionStore) DeleteExpired(ctx context.Context) (int64, error) {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	res, err := s.db.ExecContext(ctx, `DELETE FROM checkout_sessions WHERE expires_at <= now()`)
+	if err != nil {
+		return 0, err
+	}
+
+	return res.RowsAffected()
+}
@@ -10,53 +10,179 @@ import (
 )
 
 type CheckoutSession struct {
-	SessionID       string             `json:"session_id"`
-	UserID          int64              `json:"user_id"`
-	ID              uuid.UUID          `json:"id"`
-	CartStore       []CartStores       `json:"cart_store"`
-	TotalPrice      float64            `json:"total_price"`
-	ShippingMethod  *ShippingMethod    `json:"shipping_method,omitempty"`
-	PaymentMethod   *PaymentMethod     `json:"payment_method,omitempty"`
-	ShippingAddress *ShippingAddresses `json:"shipping_address,omitempty"`
-	Notes           string             `json:"notes,omitempty"`
-	CreatedAt       time.Time          `json:"created_at"`
-	ExpiresAt       time.Time          `json:"expires_at"`
+	SessionID       string                        `json:"session_id"`
+	UserID          int64                         `json:"user_id"`
+	ID              uuid.UUID                     `json:"id"`
+	CartStore       []CartStores                  `json:"cart_store"`
+	TotalPrice      Money                         `json:"total_price"`
+	ShippingMethod  *ShippingMethod               `json:"shipping_method,omitempty"`
+	ShippingMethods map[uuid.UUID]*ShippingMethod `json:"shipping_methods,omitempty"` // shipping method pilihan per cart store
+	PaymentMethod   *PaymentMethod                `json:"payment_method,omitempty"`
+	ShippingAddress *ShippingAddresses            `json:"shipping_address,omitempty"`
+	Notes           string                        `json:"notes,omitempty"`
+	StoreNotes      map[uuid.UUID]string          `json:"store_notes,omitempty"`    // catatan untuk penjual per cart store
+	ShippingCosts   map[uuid.UUID]Money           `json:"shipping_costs,omitempty"` // ongkir per cart store dari rate engine
+	Voucher         *Voucher                      `json:"voucher,omitempty"`
+	Discounts       map[uuid.UUID]Money           `json:"discounts,omitempty"` // potongan voucher per cart store
+	Totals          CheckoutTotals                `json:"totals"`
+	CreatedAt       time.Time                     `json:"created_at"`
+	ExpiresAt       time.Time                     `json:"expires_at"`
+}
+
+// CheckoutTotals adalah ringkasan harga checkout session yang ditampilkan sebelum user membayar
+type CheckoutTotals struct {
+	Items      Money `json:"items"`
+	Shipping   Money `json:"shipping"`
+	Discount   Money `json:"discount"`
+	GrandTotal Money `json:"grand_total"`
+}
+
+// ShippingMethodFor mengembalikan shipping method cart store, memakai shipping method session jika toko belum memilih
+func (s *CheckoutSession) ShippingMethodFor(cartStoreID uuid.UUID) *ShippingMethod {
+	if m, ok := s.ShippingMethods[cartStoreID]; ok && m != nil {
+		return m
+	}
+	return s.ShippingMethod
+}
+
+// NotesFor mengembalikan catatan cart store, memakai catatan session jika toko tidak punya catatan sendiri
+func (s *CheckoutSession) NotesFor(cartStoreID uuid.UUID) string {
+	if notes, ok := s.StoreNotes[cartStoreID]; ok {
+		return notes
+	}
+	return s.Notes
+}
+
+// Recalculate menghitung ulang total item, ongkir, potongan dan grand total dari isi session
+func (s *CheckoutSession) Recalculate() {
+	totals := CheckoutTotals{Items: IDR(0), Shipping: IDR(0), Discount: IDR(0)}
+	for _, cs := range s.CartStore {
+		for _, item := range cs.Items {
+			if item.Product != nil {
+				totals.Items = totals.Items.Add(IDR(item.Pricing().Subtotal))
+			}
+		}
+		totals.Shipping = totals.Shipping.Add(s.ShippingCosts[cs.ID])
+		totals.Discount = totals.Discount.Add(s.Discounts[cs.ID])
+	}
+
+	totals.GrandTotal = totals.Items.Add(totals.Shipping).Sub(totals.Discount)
+	if totals.GrandTotal.IsNegative() {
+		totals.GrandTotal = IDR(0)
+	}
+
+	s.TotalPrice = totals.Items
+	s.Totals = totals
 }
 
 type CheckoutStore struct {
 	db *sql.DB
 }
 
-func (s *CheckoutStore) CreateOrderFromCheckout(ctx context.Context, checkout *CheckoutSession) error {
+// CreateOrderFromCheckout membuat satu order per cart store dan mengembalikan order lengkap yang dibuat
+func (s *CheckoutStore) CreateOrderFromCheckout(ctx context.Context, checkout *CheckoutSession) ([]*Order, error) {
 	tx, err := s.db.BeginTx(ctx, nil)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	defer tx.Rollback()
 
 	// Validasi komponen penting
-	if checkout.ShippingMethod == nil || checkout.PaymentMethod == nil || checkout.ShippingAddress == nil {
-		return fmt.Errorf("missing required checkout components")
+	if checkout.PaymentMethod == nil || checkout.ShippingAddress == nil {
+		return nil, fmt.Errorf("missing required checkout components")
+	}
+	for _, cartStore := range checkout.CartStore {
+		if checkout.ShippingMethodFor(cartStore.ID) == nil {
+			return nil, fmt.Errorf("missing shipping method for cart store %s", cartStore.ID)
+		}
+	}
+
+	// Catat pemakaian voucher di transaksi yang sama dengan pembuatan order
+	var voucherUsageID sql.NullInt64
+	if checkout.Voucher != nil {
+		discount := IDR(0)
+		for _, d := range checkout.Discounts {
+			discount = discount.Add(d)
+		}
+
+		voucherStore := &VoucherStore{s.db}
+		usageID, err := voucherStore.redeemTx(ctx, tx, checkout.Voucher.ID, checkout.UserID, checkout.SessionID, discount)
+		if err != nil {
+			return nil, err
+		}
+		voucherUsageID = sql.NullInt64{Int64: usageID, Valid: true}
 	}
 
+	orderStore := &OrderStore{s.db}
+	paymentStore := &PaymentStore{s.db}
+	orders := make([]*Order, 0, len(checkout.CartStore))
 	for _, cartStore := range checkout.CartStore {
 		var orderID int64
 
-		err := tx.QueryRowContext(ctx, `
-			SELECT create_order_from_cart($1, $2, $3, $4, $5, $6)
+		// Tanpa ongkir dari rate engine, fungsi SQL memakai harga flat shipping method
+		var shippingCost *Money
+		if cost, ok := checkout.ShippingCosts[cartStore.ID]; ok {
+			shippingCost = &cost
+		}
+
+		var voucherID sql.NullInt64
+		discount := checkout.Discounts[cartStore.ID]
+		if checkout.Voucher != nil && discount.Amount > 0 {
+			voucherID = sql.NullInt64{Int64: checkout.Voucher.ID, Valid: true}
+		}
+
+		// Shipping method dan catatan dipilih per toko, dengan pilihan session sebagai default
+		shippingMethod := checkout.ShippingMethodFor(cartStore.ID)
+		notes := checkout.NotesFor(cartStore.ID)
+
+		itemPrices, err := cartStoreItemPricesTx(ctx, tx, cartStore.ID)
+		if err != nil {
+			return nil, err
+		}
+
+		err = tx.QueryRowContext(ctx, `
+			SELECT create_order_from_cart($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
 		`,
 			checkout.UserID,             // $1: p_user_id
 			cartStore.ID,                // $2: p_cart_store_id (UUID)
 			checkout.PaymentMethod.ID,   // $3: p_payment_method_id
-			checkout.ShippingMethod.ID,  // $4: p_shipping_method_id
+			shippingMethod.ID,           // $4: p_shipping_method_id
 			checkout.ShippingAddress.ID, // $5: p_shipping_addresses_id (UUID)
-			checkout.Notes,              // $6: p_notes
+			notes,                       // $6: p_notes
+			shippingCost,                // $7: p_shipping_cost
+			discount,                    // $8: p_discount
+			voucherID,                   // $9: p_voucher_id
+			itemPrices,                  // $10: p_item_prices
 		).Scan(&orderID)
 
 		if err != nil {
-			return fmt.Errorf("failed to create order from cart store %s: %w", cartStore.ID, err)
+			return nil, fmt.Errorf("failed to create order from cart store %s: %w", cartStore.ID, err)
 		}
+
+		// Order yang memakai diskon voucher ditautkan ke pemakaiannya agar kuota kembali saat order batal
+		if voucherID.Valid {
+			_, err = tx.ExecContext(ctx, `UPDATE orders SET voucher_usage_id = $1 WHERE id = $2`, voucherUsageID, orderID)
+			if err != nil {
+				return nil, fmt.Errorf("failed to link voucher usage to order %d: %w", orderID, err)
+			}
+		}
+
+		// Baca ulang di transaksi yang sama agar item, tracking dan alamat sesuai dengan yang baru dibuat
+		order, err := orderStore.getOrderTx(ctx, tx, orderID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load order %d: %w", orderID, err)
+		}
+
+		if err := paymentStore.createPendingTx(ctx, tx, order); err != nil {
+			return nil, fmt.Errorf("failed to create payment for order %d: %w", orderID, err)
+		}
+
+		orders = append(orders, order)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
 	}
 
-	return tx.Commit()
+	return orders, nil
 }
@@ -5,6 +5,7 @@ import (
 	"database/sql"
 	"errors"
 	"math"
+	"strconv"
 	"time"
 )
 
@@ -25,6 +26,8 @@ type MetaCommentPaginated struct {
 	Limit     int       `json:"limit"`
 	TotalPage int       `json:"total_page"`
 	Coment    []Comment `json:"comment"`
+
+	Pagination CursorPage `json:"pagination"`
 }
 
 type CommentStore struct {
@@ -150,7 +153,7 @@ func (s *CommentStore) GetComments(ctx context.Context, slugProduct string, fq P
 	}
 	defer tx.Rollback()
 
-	comments, err := s.GetCommentBySlugProduct(ctx, tx, slugProduct, fq)
+	comments, page, err := s.GetCommentBySlugProduct(ctx, tx, slugProduct, fq)
 	if err != nil {
 		return MetaCommentPaginated{}, err
 	}
@@ -166,6 +169,8 @@ func (s *CommentStore) GetComments(ctx context.Context, slugProduct string, fq P
 		Limit:     fq.Limit,
 		Coment:    comments,
 		TotalPage: int(math.Ceil(float64(total) / float64(fq.Limit))), // Membulatkan ke atas
+
+		Pagination: page,
 	}
 
 	if err := tx.Commit(); err != nil {
@@ -176,32 +181,54 @@ func (s *CommentStore) GetComments(ctx context.Context, slugProduct string, fq P
 
 }
 
-func (s *CommentStore) GetCommentBySlugProduct(ctx context.Context, tx *sql.Tx, slugProduct string, fq PaginatedFeedQuery) ([]Comment, error) {
-	query := `SELECT comments.id, comments.content, comments.rating, comments.user_id, comments.product_id, comments.created_at, comments.updated_at, users.username, users.email, users.picture, users.id FROM comments JOIN users on users.id = comments.user_id where comments.product_id = (select id from products where slug = $1) AND ($4 = 0 OR comments.rating = $4) order by comments.id desc LIMIT $2 OFFSET $3`
+// GetCommentBySlugProduct mengambil satu halaman komentar produk, terbaru lebih dulu
+func (s *CommentStore) GetCommentBySlugProduct(ctx context.Context, tx *sql.Tx, slugProduct string, fq PaginatedFeedQuery) ([]Comment, CursorPage, error) {
+	b := &sqlFilter{}
+	b.where("comments.product_id = (select id from products where slug = " + b.arg(slugProduct) + ")")
+	if fq.Rating > 0 {
+		b.where("comments.rating = " + b.arg(fq.Rating))
+	}
+
+	order, err := b.keyset("comments", []orderKey{{expr: "comments.id", desc: true}}, fq.Position)
+	if err != nil {
+		return nil, CursorPage{}, err
+	}
 
 	skip := fq.Offset * (fq.Limit)
+	if fq.Position != nil {
+		skip = 0
+	}
+
+	query := `SELECT comments.id, comments.content, comments.rating, comments.user_id, comments.product_id, comments.created_at, comments.updated_at, users.username, users.email, users.picture, users.id FROM comments JOIN users on users.id = comments.user_id ` + b.clause() + ` order by ` + orderByClause(order) + ` LIMIT ` + b.arg(fq.Limit+1) + ` OFFSET ` + b.arg(skip)
 
 	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
 	defer cancel()
 
-	rows, err := tx.QueryContext(ctx, query, slugProduct, fq.Limit, skip, fq.Rating)
+	rows, err := tx.QueryContext(ctx, query, b.args...)
 	if err != nil {
-		return nil, err
+		return nil, CursorPage{}, err
 	}
 	defer rows.Close()
 
 	comments := make([]Comment, 0)
+	var keys [][]string
 	for rows.Next() {
 		var comment Comment
 		comment.User = User{}
 		err := rows.Scan(&comment.ID, &comment.Content, &comment.Rating, &comment.UserID, &comment.ProductID, &comment.CreatedAt, &comment.UpdateAt, &comment.User.Username, &comment.User.Email, &comment.User.Picture, &comment.User.ID)
 		if err != nil {
-			return nil, err
+			return nil, CursorPage{}, err
 		}
 		comments = append(comments, comment)
+		keys = append(keys, []string{strconv.FormatInt(comment.ID, 10)})
 	}
 
-	return comments, nil
+	if err := rows.Err(); err != nil {
+		return nil, CursorPage{}, err
+	}
+
+	comments, page := newCursorPage(comments, keys, "comments", fq)
+	return comments, page, nil
 }
 
 func (s *CommentStore) CountCommentBySlugProduct(ctx context.Context, tx *sql.Tx, slugProduct string) (int, error) {
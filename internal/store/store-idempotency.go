@@ -0,0 +1,126 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+const IdempotencyKeyTTL = 24 * time.Hour
+
+// IdempotencyRecord adalah response pertama dari request dengan header Idempotency-Key.
+// StatusCode bernilai 0 selama request pertama masih diproses.
+type IdempotencyRecord struct {
+	UserID      int64     `json:"user_id"`
+	Key         string    `json:"key"`
+	RequestHash string    `json:"request_hash"`
+	StatusCode  int       `json:"status_code"`
+	ContentType string    `json:"content_type,omitempty"`
+	Body        []byte    `json:"body,omitempty"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// IsCompleted mengembalikan true jika response request pertama sudah tersimpan
+func (r *IdempotencyRecord) IsCompleted() bool {
+	return r.StatusCode != 0
+}
+
+type IdempotencyStore struct {
+	db *sql.DB
+}
+
+// Get mendapatkan record idempotency yang belum kadaluarsa
+func (s *IdempotencyStore) Get(ctx context.Context, userID int64, key string) (*IdempotencyRecord, error) {
+	query := `
+		SELECT user_id, key, request_hash, status_code, COALESCE(content_type, ''), response_body, created_at
+		FROM idempotency_keys
+		WHERE user_id = $1 AND key = $2 AND expires_at > now()`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	r := &IdempotencyRecord{}
+	err := s.db.QueryRowContext(ctx, query, userID, key).Scan(
+		&r.UserID, &r.Key, &r.RequestHash, &r.StatusCode, &r.ContentType, &r.Body, &r.CreatedAt,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+
+	return r, nil
+}
+
+// Reserve menandai key sedang diproses, mengembalikan false jika key sudah dipakai request lain
+func (s *IdempotencyStore) Reserve(ctx context.Context, r *IdempotencyRecord) (bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	var reserved bool
+	err := withTx(s.db, ctx, func(tx *sql.Tx) error {
+		_, err := tx.ExecContext(ctx,
+			`DELETE FROM idempotency_keys WHERE user_id = $1 AND key = $2 AND expires_at <= now()`,
+			r.UserID, r.Key)
+		if err != nil {
+			return err
+		}
+
+		query := `
+			INSERT INTO idempotency_keys (user_id, key, request_hash, expires_at)
+			VALUES ($1, $2, $3, $4)
+			ON CONFLICT (user_id, key) DO NOTHING
+			RETURNING created_at`
+
+		err = tx.QueryRowContext(ctx, query, r.UserID, r.Key, r.RequestHash, time.Now().Add(IdempotencyKeyTTL)).Scan(&r.CreatedAt)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return nil
+			}
+			return err
+		}
+
+		reserved = true
+		return nil
+	})
+
+	return reserved, err
+}
+
+// Save menyimpan response dari request pertama
+func (s *IdempotencyStore) Save(ctx context.Context, r *IdempotencyRecord) error {
+	query := `
+		UPDATE idempotency_keys
+		SET status_code = $1, content_type = $2, response_body = $3
+		WHERE user_id = $4 AND key = $5`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	_, err := s.db.ExecContext(ctx, query, r.StatusCode, r.ContentType, r.Body, r.UserID, r.Key)
+	return err
+}
+
+// Delete melepas key agar request bisa diulang, dipakai jika request pertama gagal
+func (s *IdempotencyStore) Delete(ctx context.Context, userID int64, key string) error {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	_, err := s.db.ExecContext(ctx, `DELETE FROM idempotency_keys WHERE user_id = $1 AND key = $2`, userID, key)
+	return err
+}
+
+// DeleteExpired menghapus record yang sudah kadaluarsa
+func (s *IdempotencyStore) DeleteExpired(ctx context.Context) (int64, error) {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	res, err := s.db.ExecContext(ctx, `DELETE FROM idempotency_keys WHERE expires_at <= now()`)
+	if err != nil {
+		return 0, err
+	}
+
+	return res.RowsAffected()
+}
@@ -0,0 +1,165 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"sort"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// ReservationItem adalah jumlah stok satu produk atau varian yang ditahan checkout session
+type ReservationItem struct {
+	CartStoreID uuid.UUID
+	ProductID   int64
+	VariantID   *int64
+	Quantity    int64
+	Stock       int64 // stok saat session dibuat, dipakai implementasi yang tidak membaca database
+}
+
+// ReservationItems mengumpulkan item session yang perlu ditahan, diurutkan per produk dan varian
+// agar dua checkout yang berebut stok selalu mengunci baris dengan urutan yang sama
+func ReservationItems(session *CheckoutSession) []ReservationItem {
+	var items []ReservationItem
+	for _, cs := range session.CartStore {
+		for _, item := range cs.Items {
+			ri := ReservationItem{
+				CartStoreID: cs.ID,
+				ProductID:   item.ProductID,
+				VariantID:   item.VariantID,
+				Quantity:    item.Quantity,
+			}
+
+			switch {
+			case item.Variant != nil:
+				ri.Stock = int64(item.Variant.Stock)
+			case item.Product != nil:
+				ri.Stock = int64(item.Product.Stock)
+			}
+
+			items = append(items, ri)
+		}
+	}
+
+	sort.SliceStable(items, func(i, j int) bool {
+		if items[i].ProductID != items[j].ProductID {
+			return items[i].ProductID < items[j].ProductID
+		}
+		return variantKey(items[i].VariantID) < variantKey(items[j].VariantID)
+	})
+
+	return items
+}
+
+func variantKey(id *int64) int64 {
+	if id == nil {
+		return 0
+	}
+	return *id
+}
+
+// InventoryReservationStore menahan stok checkout di tabel inventory_reservations, dipakai jika Redis tidak aktif
+type InventoryReservationStore struct {
+	db *sql.DB
+}
+
+// Reserve menahan stok semua item session sampai session kadaluarsa. Tahanan session lain milik user
+// pada cart store yang sama dilepas karena session tersebut sudah digantikan. Memanggil ulang untuk
+// session yang sama menggantikan tahanan sebelumnya.
+func (s *InventoryReservationStore) Reserve(ctx context.Context, session *CheckoutSession) error {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	cartStoreIDs := make([]uuid.UUID, 0, len(session.CartStore))
+	for _, cs := range session.CartStore {
+		cartStoreIDs = append(cartStoreIDs, cs.ID)
+	}
+
+	return withTx(s.db, ctx, func(tx *sql.Tx) error {
+		_, err := tx.ExecContext(ctx, `
+			DELETE FROM inventory_reservations
+			WHERE session_id = $1 OR (user_id = $2 AND cart_store_id = ANY($3))`,
+			session.SessionID, session.UserID, pq.Array(cartStoreIDs))
+		if err != nil {
+			return err
+		}
+
+		for _, item := range ReservationItems(session) {
+			// Kunci baris stok agar checkout lain menunggu sampai tahanan ini tercatat
+			stock, err := lockStockTx(ctx, tx, item)
+			if err != nil {
+				return err
+			}
+
+			var held int64
+			err = tx.QueryRowContext(ctx, `
+				SELECT COALESCE(SUM(quantity), 0)
+				FROM inventory_reservations
+				WHERE product_id = $1 AND variant_id IS NOT DISTINCT FROM $2 AND expires_at > now()`,
+				item.ProductID, item.VariantID,
+			).Scan(&held)
+			if err != nil {
+				return err
+			}
+
+			if item.Quantity > stock-held {
+				return &StockError{ProductID: item.ProductID}
+			}
+
+			_, err = tx.ExecContext(ctx, `
+				INSERT INTO inventory_reservations (session_id, user_id, cart_store_id, product_id, variant_id, quantity, expires_at)
+				VALUES ($1, $2, $3, $4, $5, $6, $7)`,
+				session.SessionID, session.UserID, item.CartStoreID, item.ProductID, item.VariantID, item.Quantity, session.ExpiresAt,
+			)
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// lockStockTx mengunci baris produk atau varian dan mengembalikan stoknya
+func lockStockTx(ctx context.Context, tx *sql.Tx, item ReservationItem) (int64, error) {
+	query := `SELECT stock FROM products WHERE id = $1 FOR UPDATE`
+	args := []any{item.ProductID}
+	if item.VariantID != nil {
+		query = `SELECT stock FROM product_variants WHERE id = $1 AND product_id = $2 AND is_active FOR UPDATE`
+		args = []any{*item.VariantID, item.ProductID}
+	}
+
+	var stock int64
+	if err := tx.QueryRowContext(ctx, query, args...).Scan(&stock); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, &StockError{ProductID: item.ProductID}
+		}
+		return 0, err
+	}
+
+	return stock, nil
+}
+
+// Release melepas semua stok yang ditahan session
+func (s *InventoryReservationStore) Release(ctx context.Context, sessionID string) error {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	_, err := s.db.ExecContext(ctx, `DELETE FROM inventory_reservations WHERE session_id = $1`, sessionID)
+	return err
+}
+
+// DeleteExpired menghapus tahanan dari session yang sudah kadaluarsa
+func (s *InventoryReservationStore) DeleteExpired(ctx context.Context) (int64, error) {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	res, err := s.db.ExecContext(ctx, `DELETE FROM inventory_reservations WHERE expires_at <= now()`)
+	if err != nil {
+		return 0, err
+	}
+
+	return res.RowsAffected()
+}
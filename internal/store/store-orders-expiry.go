@@ -0,0 +1,92 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+// expireOrdersLockKey adalah key pg advisory lock agar hanya satu replika API yang membatalkan order kadaluarsa
+const expireOrdersLockKey int64 = 730_100_601
+
+// ExpiredOrder adalah order yang dibatalkan ExpireUnpaid, nomor order dipakai untuk membatalkan tagihannya di gateway
+type ExpiredOrder struct {
+	ID          int64
+	OrderNumber string
+}
+
+// ExpireUnpaid membatalkan order Pending yang belum dibayar sebelum deadline dan mengembalikan stoknya.
+// Jika replika lain sedang menjalankan proses yang sama, fungsi langsung kembali tanpa membatalkan apa pun.
+func (s *OrderStore) ExpireUnpaid(ctx context.Context, deadline time.Time, limit int) ([]ExpiredOrder, error) {
+	if limit <= 0 {
+		limit = 100
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration*6)
+	defer cancel()
+
+	var expired []ExpiredOrder
+	err := withTx(s.db, ctx, func(tx *sql.Tx) error {
+		var locked bool
+		if err := tx.QueryRowContext(ctx, `SELECT pg_try_advisory_xact_lock($1)`, expireOrdersLockKey).Scan(&locked); err != nil {
+			return err
+		}
+
+		if !locked {
+			return nil
+		}
+
+		query := `
+			SELECT o.id, o.order_number
+			FROM orders o
+			WHERE o.status_id = $1
+				AND o.created_at < $2
+				AND NOT EXISTS (SELECT 1 FROM payments p WHERE p.order_id = o.id AND p.status = $3)
+			ORDER BY o.created_at
+			LIMIT $4
+			FOR UPDATE SKIP LOCKED`
+
+		rows, err := tx.QueryContext(ctx, query, OrderStatusPending, deadline, PaymentStatusPaid, limit)
+		if err != nil {
+			return err
+		}
+
+		var orders []ExpiredOrder
+		for rows.Next() {
+			var o ExpiredOrder
+			if err := rows.Scan(&o.ID, &o.OrderNumber); err != nil {
+				rows.Close()
+				return err
+			}
+			orders = append(orders, o)
+		}
+		rows.Close()
+
+		if err := rows.Err(); err != nil {
+			return err
+		}
+
+		actor := OrderActor{Role: OrderActorSystem}
+		for _, o := range orders {
+			err := s.releaseOrderTx(ctx, tx, o.ID, OrderStatusPending, OrderStatusCancelled, "Order cancelled: payment deadline passed", actor)
+			if err != nil {
+				return err
+			}
+
+			_, err = tx.ExecContext(ctx,
+				`UPDATE payments SET status = $1, updated_at = now() WHERE order_id = $2 AND status = $3`,
+				PaymentStatusExpired, o.ID, PaymentStatusPending)
+			if err != nil {
+				return err
+			}
+		}
+
+		expired = orders
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return expired, nil
+}
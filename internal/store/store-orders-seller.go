@@ -0,0 +1,155 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+)
+
+var (
+	ErrTrackingNumberRequired = errors.New("nomor resi wajib diisi untuk pesanan yang dikirim")
+	ErrOrderNotPaid           = errors.New("pesanan belum dibayar")
+)
+
+// SellerOrderFilter adalah filter daftar pesanan yang masuk ke sebuah toko
+type SellerOrderFilter struct {
+	StatusID int64
+	Since    string
+	Until    string
+}
+
+// GetByTokoID mendapatkan pesanan yang berisi produk dari toko, terbaru lebih dulu
+func (s *OrderStore) GetByTokoID(ctx context.Context, tokoID int64, filter SellerOrderFilter, fq PaginatedFeedQuery) ([]*Order, error) {
+	if fq.Limit <= 0 {
+		fq.Limit = 10
+	}
+	if fq.Offset < 0 {
+		fq.Offset = 0
+	}
+
+	query := `
+		SELECT o.id, o.user_id, o.order_number, o.status_id, o.payment_method_id,
+			o.shipping_method_id, o.shipping_cost, o.total_price, o.discount, o.voucher_id,
+			o.final_price, o.notes, COALESCE(o.tracking_number, ''), o.created_at, o.updated_at,
+			o.shipping_addresses_id,
+			o.shipping_recipient_name, o.shipping_recipient_phone, o.shipping_address, o.shipping_note,
+			os.id, os.name, os.description,
+			sm.id, sm.name, sm.description, sm.price, sm.is_active,
+			pm.id, pm.name, pm.description, COALESCE(pm.midtrans_code, ''), pm.is_active
+		FROM orders o
+		JOIN order_status os ON o.status_id = os.id
+		JOIN shipping_methods sm ON o.shipping_method_id = sm.id
+		JOIN payment_methods pm ON o.payment_method_id = pm.id
+		WHERE EXISTS (SELECT 1 FROM order_items oi WHERE oi.order_id = o.id AND oi.toko_id = $1)
+			AND ($2 = 0 OR o.status_id = $2)
+			AND (NULLIF($3, '') IS NULL OR o.created_at >= NULLIF($3, '')::timestamptz)
+			AND (NULLIF($4, '') IS NULL OR o.created_at <= NULLIF($4, '')::timestamptz)
+		ORDER BY o.created_at DESC
+		LIMIT $5 OFFSET $6`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	var orders []*Order
+	err := withTx(s.db, ctx, func(tx *sql.Tx) error {
+		rows, err := tx.QueryContext(ctx, query, tokoID, filter.StatusID, filter.Since, filter.Until, fq.Limit, fq.Offset)
+		if err != nil {
+			return err
+		}
+
+		orders, err = scanOrderRows(rows)
+		rows.Close()
+		if err != nil {
+			return err
+		}
+
+		for _, order := range orders {
+			if err := s.getOrderItemsTx(ctx, tx, order); err != nil {
+				return err
+			}
+
+			if err := s.getLatestOrderTrackingTx(ctx, tx, order); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return orders, nil
+}
+
+// AcceptBySeller menerima pesanan Pending yang sudah lunas dan memindahkannya ke Processing. Pesanan yang
+// sudah diterima tidak bisa diterima lagi, dan pesanan yang belum dibayar ditolak agar barang tidak dikirim sebelum dibayar.
+func (s *OrderStore) AcceptBySeller(ctx context.Context, tokoID, orderID int64, notes string, actor OrderActor) error {
+	return s.updateSellerOrderStatus(ctx, tokoID, orderID, OrderStatusProcessing, notes, actor, func(ctx context.Context, tx *sql.Tx, current int64) error {
+		if current != OrderStatusPending {
+			return &OrderTransitionError{OrderID: orderID, From: current, To: OrderStatusProcessing}
+		}
+
+		var paid bool
+		query := `SELECT EXISTS (SELECT 1 FROM payments WHERE order_id = $1 AND status = $2)`
+		if err := tx.QueryRowContext(ctx, query, orderID, PaymentStatusPaid).Scan(&paid); err != nil {
+			return err
+		}
+
+		if !paid {
+			return ErrOrderNotPaid
+		}
+
+		return nil
+	})
+}
+
+// ShipBySeller menandai pesanan toko sebagai Shipped dan menyimpan nomor resi pengiriman
+func (s *OrderStore) ShipBySeller(ctx context.Context, tokoID, orderID int64, trackingNumber, notes string, actor OrderActor) error {
+	trackingNumber = strings.TrimSpace(trackingNumber)
+	if trackingNumber == "" {
+		return ErrTrackingNumberRequired
+	}
+
+	return s.updateSellerOrderStatus(ctx, tokoID, orderID, OrderStatusShipped, notes, actor, func(ctx context.Context, tx *sql.Tx, _ int64) error {
+		_, err := tx.ExecContext(ctx, `UPDATE orders SET tracking_number = $1 WHERE id = $2`, trackingNumber, orderID)
+		return err
+	})
+}
+
+// DeliverBySeller menandai pesanan toko sebagai Delivered
+func (s *OrderStore) DeliverBySeller(ctx context.Context, tokoID, orderID int64, notes string, actor OrderActor) error {
+	return s.updateSellerOrderStatus(ctx, tokoID, orderID, OrderStatusDelivered, notes, actor, nil)
+}
+
+// updateSellerOrderStatus memastikan pesanan berisi produk toko sebelum mengubah statusnya
+func (s *OrderStore) updateSellerOrderStatus(ctx context.Context, tokoID, orderID, statusID int64, notes string, actor OrderActor, beforeUpdate func(ctx context.Context, tx *sql.Tx, current int64) error) error {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	return withTx(s.db, ctx, func(tx *sql.Tx) error {
+		current, _, err := s.lockOrderTx(ctx, tx, orderID)
+		if err != nil {
+			return err
+		}
+
+		var exists bool
+		query := `SELECT EXISTS (SELECT 1 FROM order_items WHERE order_id = $1 AND toko_id = $2)`
+		if err := tx.QueryRowContext(ctx, query, orderID, tokoID).Scan(&exists); err != nil {
+			return err
+		}
+
+		if !exists {
+			return ErrNotFound
+		}
+
+		if beforeUpdate != nil {
+			if err := beforeUpdate(ctx, tx, current); err != nil {
+				return err
+			}
+		}
+
+		return s.updateStatusTx(ctx, tx, orderID, current, statusID, notes, actor)
+	})
+}
@@ -3,14 +3,85 @@ package store
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"strconv"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/lib/pq"
+	"github.com/yogaprasetya22/api-gotokopedia/internal/pricing"
 )
 
+// ID status pesanan sesuai data awal tabel order_status
+const (
+	OrderStatusPending int64 = iota + 1
+	OrderStatusProcessing
+	OrderStatusShipped
+	OrderStatusDelivered
+	OrderStatusCancelled
+	OrderStatusRefunded
+)
+
+// Peran pihak yang mengubah status pesanan, dicatat di order_tracking
+const (
+	OrderActorBuyer  = "buyer"
+	OrderActorSeller = "seller"
+	OrderActorAdmin  = "admin"
+	OrderActorSystem = "system"
+)
+
+// invalidOrderTransitionCode adalah SQLSTATE yang dipakai update_order_status untuk transisi yang ditolak
+const invalidOrderTransitionCode = "OS409"
+
+var ErrInvalidOrderTransition = errors.New("perubahan status pesanan tidak diizinkan")
+
+// OrderTransitionError dikembalikan ketika perubahan status tidak ada di tabel transisi
+type OrderTransitionError struct {
+	OrderID int64
+	From    int64
+	To      int64
+}
+
+func (e *OrderTransitionError) Error() string {
+	return fmt.Sprintf("perubahan status pesanan %d dari %d ke %d tidak diizinkan", e.OrderID, e.From, e.To)
+}
+
+func (e *OrderTransitionError) Is(target error) bool {
+	return target == ErrInvalidOrderTransition
+}
+
+// OrderActor adalah pihak yang melakukan perubahan status pesanan
+type OrderActor struct {
+	UserID int64
+	Role   string
+}
+
+// orderStatusTransitions berisi status tujuan yang diizinkan dari setiap status
+var orderStatusTransitions = map[int64][]int64{
+	OrderStatusPending:    {OrderStatusProcessing, OrderStatusCancelled},
+	OrderStatusProcessing: {OrderStatusShipped, OrderStatusCancelled, OrderStatusRefunded},
+	OrderStatusShipped:    {OrderStatusDelivered, OrderStatusRefunded},
+	OrderStatusDelivered:  {OrderStatusRefunded},
+}
+
+// CanTransitionOrderStatus mengecek apakah status pesanan boleh berubah dari `from` ke `to`.
+// Status yang sama diizinkan agar catatan tracking tetap bisa ditambahkan.
+func CanTransitionOrderStatus(from, to int64) bool {
+	if from == to {
+		return true
+	}
+
+	for _, next := range orderStatusTransitions[from] {
+		if next == to {
+			return true
+		}
+	}
+
+	return false
+}
+
 // Order merepresentasikan pesanan
 type Order struct {
 	ID                  int64     `json:"id"`
@@ -20,13 +91,19 @@ type Order struct {
 	StatusID            int64     `json:"status_id"`
 	PaymentMethodID     int64     `json:"payment_method_id"`
 	ShippingMethodID    int64     `json:"shipping_method_id"`
-	ShippingCost        float64   `json:"shipping_cost"`
-	TotalPrice          float64   `json:"total_price"`
-	FinalPrice          float64   `json:"final_price"`
+	ShippingCost        Money     `json:"shipping_cost"`
+	TotalPrice          Money     `json:"total_price"`
+	Discount            Money     `json:"discount"` // potongan voucher
+	VoucherID           *int64    `json:"voucher_id,omitempty"`
+	FinalPrice          Money     `json:"final_price"`
 	Notes               string    `json:"notes,omitempty"`
+	TrackingNumber      string    `json:"tracking_number,omitempty"`
 	CreatedAt           time.Time `json:"created_at"`
 	UpdatedAt           time.Time `json:"updated_at"`
 
+	// Snapshot alamat pengiriman saat order dibuat
+	ShippingSnapshot *OrderShippingSnapshot `json:"shipping_snapshot,omitempty"`
+
 	// Relasi
 	Status            *OrderStatus       `json:"status,omitempty"`
 	ShippingMethod    *ShippingMethod    `json:"shipping_method,omitempty"`
@@ -36,17 +113,31 @@ type Order struct {
 	ShippingAddresses *ShippingAddresses `json:"shipping_addresses,omitempty"`
 }
 
+// OrderShippingSnapshot adalah salinan alamat pengiriman yang tidak ikut berubah saat alamat diedit atau dihapus
+type OrderShippingSnapshot struct {
+	RecipientName  string `json:"recipient_name"`
+	RecipientPhone string `json:"recipient_phone"`
+	Address        string `json:"address"`
+	NoteForCourier string `json:"note_for_courier,omitempty"`
+}
+
 // OrderItem merepresentasikan item dalam pesanan
 type OrderItem struct {
 	ID            int64     `json:"id"`
 	OrderID       int64     `json:"order_id"`
 	ProductID     int64     `json:"product_id"`
 	TokoID        int64     `json:"toko_id"`
+	ProductName   string    `json:"product_name"`  // snapshot nama produk saat dibeli
+	ProductSlug   string    `json:"product_slug"`  // snapshot slug produk saat dibeli
+	ProductImage  string    `json:"product_image"` // snapshot gambar pertama produk saat dibeli
+	VariantID     *int64    `json:"variant_id,omitempty"`
+	VariantSKU    string    `json:"variant_sku,omitempty"`  // snapshot SKU varian saat dibeli
+	VariantName   string    `json:"variant_name,omitempty"` // snapshot nama varian saat dibeli
 	Quantity      int       `json:"quantity"`
-	Price         float64   `json:"price"`
-	DiscountPrice float64   `json:"discount_price"`
-	Discount      float64   `json:"discount"`
-	Subtotal      float64   `json:"subtotal"`
+	Price         Money     `json:"price"`          // harga sebelum diskon
+	DiscountPrice Money     `json:"discount_price"` // harga efektif yang dibayar
+	Discount      float64   `json:"discount"`       // persentase potongan
+	Subtotal      Money     `json:"subtotal"`
 	CreatedAt     time.Time `json:"created_at"`
 
 	// Relasi
@@ -60,6 +151,8 @@ type OrderTracking struct {
 	OrderID   int64     `json:"order_id"`
 	StatusID  int64     `json:"status_id"`
 	Notes     string    `json:"notes,omitempty"`
+	ActorID   *int64    `json:"actor_id,omitempty"`
+	ActorRole string    `json:"actor_role,omitempty"`
 	CreatedAt time.Time `json:"created_at"`
 
 	// Relasi
@@ -82,10 +175,10 @@ type OrderDetail struct {
 	Status         string    `json:"status"`
 	PaymentMethod  string    `json:"payment_method"`
 	ShippingMethod string    `json:"shipping_method"`
-	ShippingCost   float64   `json:"shipping_cost"`
-	TotalPrice     float64   `json:"total_price"`
-	Discount       float64   `json:"discount"`
-	FinalPrice     float64   `json:"final_price"`
+	ShippingCost   Money     `json:"shipping_cost"`
+	TotalPrice     Money     `json:"total_price"`
+	Discount       Money     `json:"discount"`
+	FinalPrice     Money     `json:"final_price"`
 	Notes          string    `json:"notes,omitempty"`
 	CreatedAt      time.Time `json:"created_at"`
 	UpdatedAt      time.Time `json:"updated_at"`
@@ -101,27 +194,185 @@ func (s *OrderStore) CreateFromCart(ctx context.Context, cartStoreID uuid.UUID,
 	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
 	defer cancel()
 
-	_, err := s.db.ExecContext(ctx,
-		`SELECT public.create_order_from_cart($1, $2, $3, $4, $5, $6)`,
-		userID, cartStoreID, paymentMethodID, shippingMethodID, shippingAddressesID, notes)
+	return withTx(s.db, ctx, func(tx *sql.Tx) error {
+		itemPrices, err := cartStoreItemPricesTx(ctx, tx, cartStoreID)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.ExecContext(ctx,
+			`SELECT public.create_order_from_cart($1, $2, $3, $4, $5, $6, p_item_prices => $7)`,
+			userID, cartStoreID, paymentMethodID, shippingMethodID, shippingAddressesID, notes, itemPrices)
 
-	return err
+		return err
+	})
 }
 
-// UpdateStatus memperbarui status pesanan
-func (s *OrderStore) UpdateStatus(ctx context.Context, orderID, statusID int64, notes string) error {
+// cartStoreItemPricesTx menghitung harga setiap item di cart store dengan pricing service.
+// Hasilnya berupa objek JSON dengan key cart item ID untuk parameter p_item_prices create_order_from_cart,
+// karena satu produk bisa masuk keranjang beberapa kali dengan varian berbeda.
+func cartStoreItemPricesTx(ctx context.Context, tx *sql.Tx, cartStoreID uuid.UUID) (string, error) {
+	query := `
+		SELECT ci.id, ci.quantity, ` + cartItemPriceColumns + `
+		FROM cart_items ci
+		JOIN products p ON ci.product_id = p.id
+		LEFT JOIN product_variants v ON ci.variant_id = v.id
+		WHERE ci.cart_store_id = $1`
+
+	rows, err := tx.QueryContext(ctx, query, cartStoreID)
+	if err != nil {
+		return "", err
+	}
+	defer rows.Close()
+
+	prices := make(map[string]pricing.Breakdown)
+	for rows.Next() {
+		var itemID uuid.UUID
+		var quantity int64
+		var price, discountPrice Money
+		var discount float64
+		if err := rows.Scan(&itemID, &quantity, &price, &discountPrice, &discount); err != nil {
+			return "", err
+		}
+
+		prices[itemID.String()] = pricing.Line(price.Amount, discountPrice.Amount, discount, quantity)
+	}
+
+	if err := rows.Err(); err != nil {
+		return "", err
+	}
+
+	data, err := json.Marshal(prices)
+	if err != nil {
+		return "", err
+	}
+
+	return string(data), nil
+}
+
+// UpdateStatus memperbarui status pesanan sesuai tabel transisi status
+func (s *OrderStore) UpdateStatus(ctx context.Context, orderID, statusID int64, notes string, actor OrderActor) error {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	return withTx(s.db, ctx, func(tx *sql.Tx) error {
+		current, _, err := s.lockOrderTx(ctx, tx, orderID)
+		if err != nil {
+			return err
+		}
+
+		return s.updateStatusTx(ctx, tx, orderID, current, statusID, notes, actor)
+	})
+}
+
+// Cancel membatalkan pesanan milik user selama masih Pending/Processing dan mengembalikan stok produk
+func (s *OrderStore) Cancel(ctx context.Context, orderID, userID int64, notes string) error {
 	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
 	defer cancel()
 
-	_, err := s.db.ExecContext(ctx,
-		`SELECT update_order_status($1, $2, $3)`,
-		orderID, statusID, notes)
+	return withTx(s.db, ctx, func(tx *sql.Tx) error {
+		current, ownerID, err := s.lockOrderTx(ctx, tx, orderID)
+		if err != nil {
+			return err
+		}
+
+		if ownerID != userID {
+			return ErrNotFound
+		}
+
+		actor := OrderActor{UserID: userID, Role: OrderActorBuyer}
+		return s.releaseOrderTx(ctx, tx, orderID, current, OrderStatusCancelled, notes, actor)
+	})
+}
+
+// Refund menandai pesanan sebagai Refunded dan mengembalikan stok produk
+func (s *OrderStore) Refund(ctx context.Context, orderID int64, notes string, actor OrderActor) error {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	return withTx(s.db, ctx, func(tx *sql.Tx) error {
+		current, _, err := s.lockOrderTx(ctx, tx, orderID)
+		if err != nil {
+			return err
+		}
+
+		return s.releaseOrderTx(ctx, tx, orderID, current, OrderStatusRefunded, notes, actor)
+	})
+}
+
+// lockOrderTx mengunci baris order sampai transaksi selesai dan mengembalikan status serta pemiliknya
+func (s *OrderStore) lockOrderTx(ctx context.Context, tx *sql.Tx, orderID int64) (statusID, userID int64, err error) {
+	query := `SELECT status_id, user_id FROM orders WHERE id = $1 FOR UPDATE`
+
+	err = tx.QueryRowContext(ctx, query, orderID).Scan(&statusID, &userID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, 0, ErrNotFound
+		}
+		return 0, 0, err
+	}
+
+	return statusID, userID, nil
+}
+
+// releaseOrderTx memindahkan pesanan ke status akhir (Cancelled/Refunded) dan mengembalikan stok serta sold produk.
+// Pesanan yang dibatalkan juga mengembalikan kuota voucher yang dipakainya.
+func (s *OrderStore) releaseOrderTx(ctx context.Context, tx *sql.Tx, orderID, from, to int64, notes string, actor OrderActor) error {
+	if from == to {
+		return &OrderTransitionError{OrderID: orderID, From: from, To: to}
+	}
+
+	if err := s.updateStatusTx(ctx, tx, orderID, from, to, notes, actor); err != nil {
+		return err
+	}
+
+	if _, err := tx.ExecContext(ctx, `SELECT restore_order_stock($1)`, orderID); err != nil {
+		return err
+	}
 
-	return err
+	// Order yang batal tidak menghitung pemakaian voucher, refund tetap dihitung karena order sudah berjalan
+	if to == OrderStatusCancelled {
+		return releaseUsageTx(ctx, tx, orderID)
+	}
+
+	return nil
+}
+
+// updateStatusTx memvalidasi transisi di Go lalu memanggil update_order_status yang juga memvalidasinya di SQL
+func (s *OrderStore) updateStatusTx(ctx context.Context, tx *sql.Tx, orderID, from, to int64, notes string, actor OrderActor) error {
+	if !CanTransitionOrderStatus(from, to) {
+		return &OrderTransitionError{OrderID: orderID, From: from, To: to}
+	}
+
+	actorID := sql.NullInt64{Int64: actor.UserID, Valid: actor.UserID > 0}
+	actorRole := sql.NullString{String: actor.Role, Valid: actor.Role != ""}
+
+	_, err := tx.ExecContext(ctx,
+		`SELECT update_order_status($1, $2, $3, $4, $5)`,
+		orderID, to, notes, actorID, actorRole)
+	if err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == invalidOrderTransitionCode {
+			return &OrderTransitionError{OrderID: orderID, From: from, To: to}
+		}
+		return err
+	}
+
+	return nil
 }
 
 // GetByUserID mendapatkan semua pesanan berdasarkan user ID dengan penanganan error yang lebih baik
 func (s *OrderStore) GetByUserID(ctx context.Context, userID int64, fq PaginatedFeedQuery) ([]*Order, error) {
+	page, err := s.ListByUserID(ctx, userID, fq)
+	if err != nil {
+		return nil, err
+	}
+
+	return page.Data, nil
+}
+
+// ListByUserID mendapatkan satu halaman pesanan user, terbaru lebih dulu, beserta cursor halaman
+func (s *OrderStore) ListByUserID(ctx context.Context, userID int64, fq PaginatedFeedQuery) (*Paginated[*Order], error) {
 	// Validasi input
 	if userID <= 0 {
 		return nil, errors.New("userID harus lebih besar dari 0")
@@ -160,69 +411,111 @@ func (s *OrderStore) GetByUserID(ctx context.Context, userID int64, fq Paginated
 	}()
 
 	// Pisahkan query utama menjadi lebih sederhana
-	orders, err := s.getUserOrders(txCtx, tx, userID, fq)
+	page, err := s.getUserOrders(txCtx, tx, userID, fq)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get user orders: %w", err)
 	}
 
 	// Isi relasi untuk setiap order
-	for _, order := range orders {
+	for _, order := range page.Data {
 		if err := s.getOrderRelations(txCtx, tx, order); err != nil {
 			return nil, fmt.Errorf("failed to get order relations: %w", err)
 		}
 	}
 
-	return orders, nil
+	return page, nil
 }
 
-// getUserOrders mendapatkan daftar order tanpa relasi
-func (s *OrderStore) getUserOrders(ctx context.Context, tx *sql.Tx, userID int64, fq PaginatedFeedQuery) ([]*Order, error) {
+// getUserOrders mendapatkan satu halaman order tanpa relasi
+func (s *OrderStore) getUserOrders(ctx context.Context, tx *sql.Tx, userID int64, fq PaginatedFeedQuery) (*Paginated[*Order], error) {
+	b := &sqlFilter{}
+	b.where("o.user_id = " + b.arg(userID))
+
+	order, err := b.keyset("orders", []orderKey{{expr: "o.created_at", desc: true}, {expr: "o.id", desc: true}}, fq.Position)
+	if err != nil {
+		return nil, err
+	}
+
+	offset := fq.Offset
+	if fq.Position != nil {
+		offset = 0
+	}
+
 	query := `
 		SELECT o.id, o.user_id, o.order_number, o.status_id, o.payment_method_id, 
-			o.shipping_method_id, o.shipping_cost, o.total_price,
-			o.final_price, o.notes, o.created_at, o.updated_at,
+			o.shipping_method_id, o.shipping_cost, o.total_price, o.discount, o.voucher_id,
+			o.final_price, o.notes, COALESCE(o.tracking_number, ''), o.created_at, o.updated_at,
 			o.shipping_addresses_id,
+			o.shipping_recipient_name, o.shipping_recipient_phone, o.shipping_address, o.shipping_note,
 			os.id, os.name, os.description,
 			sm.id, sm.name, sm.description, sm.price, sm.is_active,
-			pm.id, pm.name, pm.description, pm.is_active
+			pm.id, pm.name, pm.description, COALESCE(pm.midtrans_code, ''), pm.is_active
 		FROM orders o
 		JOIN order_status os ON o.status_id = os.id
 		JOIN shipping_methods sm ON o.shipping_method_id = sm.id
 		JOIN payment_methods pm ON o.payment_method_id = pm.id
-		WHERE o.user_id = $1
-		ORDER BY o.created_at DESC
-		LIMIT $2 OFFSET $3`
+		` + b.clause() + `
+		ORDER BY ` + orderByClause(order) + `
+		LIMIT ` + b.arg(fq.Limit+1) + ` OFFSET ` + b.arg(offset)
 
-	rows, err := tx.QueryContext(ctx, query, userID, fq.Limit, fq.Offset)
+	rows, err := tx.QueryContext(ctx, query, b.args...)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
+	orders, err := scanOrderRows(rows)
+	if err != nil {
+		return nil, err
+	}
+
+	keys := make([][]string, len(orders))
+	for i, o := range orders {
+		keys[i] = []string{timeKey(o.CreatedAt), strconv.FormatInt(o.ID, 10)}
+	}
+
+	page := &Paginated[*Order]{}
+	page.Data, page.Pagination = newCursorPage(orders, keys, "orders", fq)
+	if page.Data == nil {
+		page.Data = []*Order{}
+	}
+
+	return page, nil
+}
+
+// scanOrderRows membaca hasil query order beserta status, shipping method dan payment method
+func scanOrderRows(rows *sql.Rows) ([]*Order, error) {
 	var orders []*Order
 	for rows.Next() {
 		var order Order
 		var shippingAddressesID sql.NullString
+		var voucherID sql.NullInt64
 
 		// Initialize pointer fields before scanning
 		order.Status = &OrderStatus{}
 		order.ShippingMethod = &ShippingMethod{}
 		order.PaymentMethod = &PaymentMethod{}
+		order.ShippingSnapshot = &OrderShippingSnapshot{}
 
 		err := rows.Scan(
 			&order.ID, &order.UserID, &order.OrderNumber, &order.StatusID, &order.PaymentMethodID,
-			&order.ShippingMethodID, &order.ShippingCost, &order.TotalPrice,
-			&order.FinalPrice, &order.Notes, &order.CreatedAt, &order.UpdatedAt,
+			&order.ShippingMethodID, &order.ShippingCost, &order.TotalPrice, &order.Discount, &voucherID,
+			&order.FinalPrice, &order.Notes, &order.TrackingNumber, &order.CreatedAt, &order.UpdatedAt,
 			&shippingAddressesID,
+			&order.ShippingSnapshot.RecipientName, &order.ShippingSnapshot.RecipientPhone,
+			&order.ShippingSnapshot.Address, &order.ShippingSnapshot.NoteForCourier,
 			&order.Status.ID, &order.Status.Name, &order.Status.Description,
 			&order.ShippingMethod.ID, &order.ShippingMethod.Name, &order.ShippingMethod.Description,
 			&order.ShippingMethod.Price, &order.ShippingMethod.IsActive,
 			&order.PaymentMethod.ID, &order.PaymentMethod.Name, &order.PaymentMethod.Description,
-			&order.PaymentMethod.IsActive,
+			&order.PaymentMethod.MidtransCode, &order.PaymentMethod.IsActive,
 		)
 		if err != nil {
 			return nil, err
 		}
+		if voucherID.Valid {
+			order.VoucherID = &voucherID.Int64
+		}
 		if shippingAddressesID.Valid {
 			order.ShippingAddressesID, err = uuid.Parse(shippingAddressesID.String)
 			if err != nil {
@@ -290,17 +583,17 @@ func (s *OrderStore) getShippingMethod(ctx context.Context, tx *sql.Tx, order *O
 // getPaymentMethod mengisi data payment method
 func (s *OrderStore) getPaymentMethod(ctx context.Context, tx *sql.Tx, order *Order) error {
 	order.PaymentMethod = &PaymentMethod{}
-	query := `SELECT id, name, description, is_active FROM payment_methods WHERE id = $1`
+	query := `SELECT id, name, description, COALESCE(midtrans_code, ''), is_active FROM payment_methods WHERE id = $1`
 	return tx.QueryRowContext(ctx, query, order.PaymentMethodID).Scan(
 		&order.PaymentMethod.ID, &order.PaymentMethod.Name,
-		&order.PaymentMethod.Description, &order.PaymentMethod.IsActive,
+		&order.PaymentMethod.Description, &order.PaymentMethod.MidtransCode, &order.PaymentMethod.IsActive,
 	)
 }
 
 // getLatestOrderTrackingTx mendapatkan status tracking terbaru untuk order
 func (s *OrderStore) getLatestOrderTrackingTx(ctx context.Context, tx *sql.Tx, order *Order) error {
 	query := `
-		SELECT ot.id, ot.status_id, ot.notes, ot.created_at,
+		SELECT ot.id, ot.status_id, ot.notes, ot.actor_id, COALESCE(ot.actor_role, ''), ot.created_at,
 			   os.id, os.name, os.description
 		FROM order_tracking ot
 		JOIN order_status os ON ot.status_id = os.id
@@ -313,10 +606,14 @@ func (s *OrderStore) getLatestOrderTrackingTx(ctx context.Context, tx *sql.Tx, o
 		Status:  &OrderStatus{},
 	}
 
+	var actorID sql.NullInt64
 	err := tx.QueryRowContext(ctx, query, order.ID).Scan(
-		&tracking.ID, &tracking.StatusID, &tracking.Notes, &tracking.CreatedAt,
+		&tracking.ID, &tracking.StatusID, &tracking.Notes, &actorID, &tracking.ActorRole, &tracking.CreatedAt,
 		&tracking.Status.ID, &tracking.Status.Name, &tracking.Status.Description,
 	)
+	if actorID.Valid {
+		tracking.ActorID = &actorID.Int64
+	}
 
 	if err != nil && err != sql.ErrNoRows {
 		return err
@@ -331,33 +628,40 @@ func (s *OrderStore) getLatestOrderTrackingTx(ctx context.Context, tx *sql.Tx, o
 
 // GetByID mendapatkan pesanan berdasarkan ID dengan transaksi untuk memenuhi semua relasi
 func (s *OrderStore) GetByID(ctx context.Context, id int64) (*Order, error) {
-	// Mulai transaksi
-	tx, err := s.db.BeginTx(ctx, nil)
+	var order *Order
+
+	err := withTx(s.db, ctx, func(tx *sql.Tx) error {
+		var err error
+		order, err = s.getOrderTx(ctx, tx, id)
+		return err
+	})
 	if err != nil {
 		return nil, err
 	}
-	defer func() {
-		if err != nil {
-			tx.Rollback()
-		}
-	}()
 
+	return order, nil
+}
+
+// getOrderTx mendapatkan order lengkap dengan alamat, item dan riwayat tracking dalam transaksi
+func (s *OrderStore) getOrderTx(ctx context.Context, tx *sql.Tx, id int64) (*Order, error) {
 	// Query utama untuk mendapatkan data order
 	order := &Order{
 		Status:            &OrderStatus{},
 		ShippingMethod:    &ShippingMethod{},
 		PaymentMethod:     &PaymentMethod{},
 		ShippingAddresses: &ShippingAddresses{},
+		ShippingSnapshot:  &OrderShippingSnapshot{},
 	}
 
 	query := `
 		SELECT o.id, o.user_id, o.order_number, o.status_id, o.payment_method_id, 
-			o.shipping_method_id, o.shipping_cost, o.total_price,
-			o.final_price, o.notes, o.created_at, o.updated_at,
+			o.shipping_method_id, o.shipping_cost, o.total_price, o.discount, o.voucher_id,
+			o.final_price, o.notes, COALESCE(o.tracking_number, ''), o.created_at, o.updated_at,
 			o.shipping_addresses_id,
+			o.shipping_recipient_name, o.shipping_recipient_phone, o.shipping_address, o.shipping_note,
 			os.id, os.name, os.description,
 			sm.id, sm.name, sm.description, sm.price, sm.is_active,
-			pm.id, pm.name, pm.description, pm.is_active
+			pm.id, pm.name, pm.description, COALESCE(pm.midtrans_code, ''), pm.is_active
 		FROM orders o
 		JOIN order_status os ON o.status_id = os.id
 		JOIN shipping_methods sm ON o.shipping_method_id = sm.id
@@ -365,16 +669,19 @@ func (s *OrderStore) GetByID(ctx context.Context, id int64) (*Order, error) {
 		WHERE o.id = $1`
 
 	var shippingAddressesID sql.NullString
-	err = tx.QueryRowContext(ctx, query, id).Scan(
+	var voucherID sql.NullInt64
+	err := tx.QueryRowContext(ctx, query, id).Scan(
 		&order.ID, &order.UserID, &order.OrderNumber, &order.StatusID, &order.PaymentMethodID,
-		&order.ShippingMethodID, &order.ShippingCost, &order.TotalPrice,
-		&order.FinalPrice, &order.Notes, &order.CreatedAt, &order.UpdatedAt,
+		&order.ShippingMethodID, &order.ShippingCost, &order.TotalPrice, &order.Discount, &voucherID,
+		&order.FinalPrice, &order.Notes, &order.TrackingNumber, &order.CreatedAt, &order.UpdatedAt,
 		&shippingAddressesID,
+		&order.ShippingSnapshot.RecipientName, &order.ShippingSnapshot.RecipientPhone,
+		&order.ShippingSnapshot.Address, &order.ShippingSnapshot.NoteForCourier,
 		&order.Status.ID, &order.Status.Name, &order.Status.Description,
 		&order.ShippingMethod.ID, &order.ShippingMethod.Name, &order.ShippingMethod.Description,
 		&order.ShippingMethod.Price, &order.ShippingMethod.IsActive,
 		&order.PaymentMethod.ID, &order.PaymentMethod.Name, &order.PaymentMethod.Description,
-		&order.PaymentMethod.IsActive,
+		&order.PaymentMethod.MidtransCode, &order.PaymentMethod.IsActive,
 	)
 
 	if err != nil {
@@ -384,6 +691,10 @@ func (s *OrderStore) GetByID(ctx context.Context, id int64) (*Order, error) {
 		return nil, err
 	}
 
+	if voucherID.Valid {
+		order.VoucherID = &voucherID.Int64
+	}
+
 	// Get shipping addresses
 	if shippingAddressesID.Valid {
 		order.ShippingAddresses, err = s.getShippingAddressesByID(ctx, tx, uuid.MustParse(shippingAddressesID.String))
@@ -404,11 +715,6 @@ func (s *OrderStore) GetByID(ctx context.Context, id int64) (*Order, error) {
 		return nil, err
 	}
 
-	// Commit transaksi jika semua berhasil
-	if err = tx.Commit(); err != nil {
-		return nil, err
-	}
-
 	return order, nil
 }
 
@@ -421,7 +727,7 @@ func (s *OrderStore) getShippingAddressesByID(ctx context.Context, tx *sql.Tx, i
 
 	sa := &ShippingAddresses{}
 	var noteForCourier sql.NullString
-	err := s.db.QueryRowContext(ctx, query, id).Scan(&sa.ID, &sa.UserID, &sa.Label,
+	err := tx.QueryRowContext(ctx, query, id).Scan(&sa.ID, &sa.UserID, &sa.Label,
 		&sa.RecipientName, &sa.RecipientPhone, &sa.AddressLine1,
 		&noteForCourier, &sa.CreatedAt, &sa.UpdatedAt)
 	if err != nil {
@@ -439,7 +745,9 @@ func (s *OrderStore) getShippingAddressesByID(ctx context.Context, tx *sql.Tx, i
 // getOrderItemsTx mendapatkan item-item pesanan dalam transaksi
 func (s *OrderStore) getOrderItemsTx(ctx context.Context, tx *sql.Tx, order *Order) error {
 	query := `
-		SELECT oi.id, oi.product_id, oi.toko_id, oi.quantity, oi.price, 
+		SELECT oi.id, oi.product_id, oi.toko_id, oi.product_name, oi.product_slug, oi.product_image,
+			oi.variant_id, COALESCE(oi.variant_sku, ''), COALESCE(oi.variant_name, ''),
+			oi.quantity, oi.price, 
 			oi.discount_price, oi.discount, oi.subtotal, oi.created_at,
 			p.id, p.name, p.slug, p.description, p.price as product_price,
 			p.discount_price as product_discount_price, p.discount as product_discount,
@@ -470,10 +778,13 @@ func (s *OrderStore) getOrderItemsTx(ctx context.Context, tx *sql.Tx, order *Ord
 			productCreatedAt time.Time
 			productUpdatedAt time.Time
 			tokoCreatedAt    time.Time
+			variantID        sql.NullInt64
 		)
 
 		err := rows.Scan(
-			&item.ID, &item.ProductID, &item.TokoID, &item.Quantity, &item.Price,
+			&item.ID, &item.ProductID, &item.TokoID, &item.ProductName, &item.ProductSlug, &item.ProductImage,
+			&variantID, &item.VariantSKU, &item.VariantName,
+			&item.Quantity, &item.Price,
 			&item.DiscountPrice, &item.Discount, &item.Subtotal, &item.CreatedAt,
 			&item.Product.ID, &item.Product.Name, &item.Product.Slug, &item.Product.Description, &item.Product.Price,
 			&item.Product.DiscountPrice, &item.Product.Discount, pq.Array(&imageUrls),
@@ -487,6 +798,10 @@ func (s *OrderStore) getOrderItemsTx(ctx context.Context, tx *sql.Tx, order *Ord
 			return err
 		}
 
+		if variantID.Valid {
+			item.VariantID = &variantID.Int64
+		}
+
 		item.Product.ImageUrls = imageUrls
 		item.Product.CreatedAt = productCreatedAt
 		item.Product.UpdatedAt = productUpdatedAt
@@ -501,7 +816,7 @@ func (s *OrderStore) getOrderItemsTx(ctx context.Context, tx *sql.Tx, order *Ord
 // getOrderTrackingTx mendapatkan riwayat status pesanan dalam transaksi
 func (s *OrderStore) getOrderTrackingTx(ctx context.Context, tx *sql.Tx, order *Order) error {
 	query := `
-		SELECT ot.id, ot.status_id, ot.notes, ot.created_at,
+		SELECT ot.id, ot.status_id, ot.notes, ot.actor_id, COALESCE(ot.actor_role, ''), ot.created_at,
 			os.id, os.name, os.description
 		FROM order_tracking ot
 		JOIN order_status os ON ot.status_id = os.id
@@ -521,8 +836,9 @@ func (s *OrderStore) getOrderTrackingTx(ctx context.Context, tx *sql.Tx, order *
 			Status:  &OrderStatus{},
 		}
 
+		var actorID sql.NullInt64
 		err := rows.Scan(
-			&tracking.ID, &tracking.StatusID, &tracking.Notes, &tracking.CreatedAt,
+			&tracking.ID, &tracking.StatusID, &tracking.Notes, &actorID, &tracking.ActorRole, &tracking.CreatedAt,
 			&tracking.Status.ID, &tracking.Status.Name, &tracking.Status.Description,
 		)
 
@@ -530,6 +846,10 @@ func (s *OrderStore) getOrderTrackingTx(ctx context.Context, tx *sql.Tx, order *
 			return err
 		}
 
+		if actorID.Valid {
+			tracking.ActorID = &actorID.Int64
+		}
+
 		trackings = append(trackings, tracking)
 	}
 
@@ -585,7 +905,7 @@ func (s *OrderStore) GetShippingMethodByID(ctx context.Context, id int64) (*Ship
 
 // GetPaymentMethods mendapatkan semua metode pembayaran
 func (s *OrderStore) GetPaymentMethods(ctx context.Context) ([]*PaymentMethod, error) {
-	query := `SELECT id, name, description, is_active FROM payment_methods WHERE is_active = true`
+	query := `SELECT id, name, description, COALESCE(midtrans_code, ''), is_active FROM payment_methods WHERE is_active = true`
 
 	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
 	defer cancel()
@@ -599,7 +919,7 @@ func (s *OrderStore) GetPaymentMethods(ctx context.Context) ([]*PaymentMethod, e
 	var methods []*PaymentMethod
 	for rows.Next() {
 		method := &PaymentMethod{}
-		err := rows.Scan(&method.ID, &method.Name, &method.Description, &method.IsActive)
+		err := rows.Scan(&method.ID, &method.Name, &method.Description, &method.MidtransCode, &method.IsActive)
 		if err != nil {
 			return nil, err
 		}
@@ -611,14 +931,14 @@ func (s *OrderStore) GetPaymentMethods(ctx context.Context) ([]*PaymentMethod, e
 
 // GetPaymentMethodByID mendapatkan metode pembayaran berdasarkan ID
 func (s *OrderStore) GetPaymentMethodByID(ctx context.Context, id int64) (*PaymentMethod, error) {
-	query := `SELECT id, name, description, is_active FROM payment_methods WHERE id = $1`
+	query := `SELECT id, name, description, COALESCE(midtrans_code, ''), is_active FROM payment_methods WHERE id = $1`
 
 	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
 	defer cancel()
 
 	method := &PaymentMethod{}
 	err := s.db.QueryRowContext(ctx, query, id).Scan(
-		&method.ID, &method.Name, &method.Description, &method.IsActive,
+		&method.ID, &method.Name, &method.Description, &method.MidtransCode, &method.IsActive,
 	)
 	if err != nil {
 		if err == sql.ErrNoRows {
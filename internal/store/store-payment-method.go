@@ -8,10 +8,11 @@ import (
 )
 
 type PaymentMethod struct {
-	ID          int64  `json:"id"`
-	Name        string `json:"name"`
-	Description string `json:"description,omitempty"`
-	IsActive    bool   `json:"is_active"`
+	ID           int64  `json:"id"`
+	Name         string `json:"name"`
+	Description  string `json:"description,omitempty"`
+	MidtransCode string `json:"midtrans_code,omitempty"`
+	IsActive     bool   `json:"is_active"`
 }
 
 type PaymentMethodStore struct {
@@ -19,14 +20,14 @@ type PaymentMethodStore struct {
 }
 
 func (s *PaymentMethodStore) Create(ctx context.Context, pm *PaymentMethod) error {
-	query := `INSERT INTO payment_methods (name, description, is_active) VALUES ($1, $2, $3) RETURNING id`
+	query := `INSERT INTO payment_methods (name, description, midtrans_code, is_active) VALUES ($1, $2, NULLIF($3, ''), $4) RETURNING id`
 	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
 	defer cancel()
-	return s.db.QueryRowContext(ctx, query, pm.Name, pm.Description, pm.IsActive).Scan(&pm.ID)
+	return s.db.QueryRowContext(ctx, query, pm.Name, pm.Description, pm.MidtransCode, pm.IsActive).Scan(&pm.ID)
 }
 
 func (s *PaymentMethodStore) GetAll(ctx context.Context) ([]*PaymentMethod, error) {
-	query := `SELECT id, name, description, is_active FROM payment_methods`
+	query := `SELECT id, name, description, COALESCE(midtrans_code, ''), is_active FROM payment_methods`
 	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
 	defer cancel()
 	rows, err := s.db.QueryContext(ctx, query)
@@ -37,7 +38,7 @@ func (s *PaymentMethodStore) GetAll(ctx context.Context) ([]*PaymentMethod, erro
 	var paymentMethods []*PaymentMethod
 	for rows.Next() {
 		pm := &PaymentMethod{}
-		err := rows.Scan(&pm.ID, &pm.Name, &pm.Description, &pm.IsActive)
+		err := rows.Scan(&pm.ID, &pm.Name, &pm.Description, &pm.MidtransCode, &pm.IsActive)
 		if err != nil {
 			return nil, err
 		}
@@ -50,11 +51,11 @@ func (s *PaymentMethodStore) GetAll(ctx context.Context) ([]*PaymentMethod, erro
 }
 
 func (s *PaymentMethodStore) GetByID(ctx context.Context, id int64) (*PaymentMethod, error) {
-	query := `SELECT id, name, description, is_active FROM payment_methods WHERE id = $1`
+	query := `SELECT id, name, description, COALESCE(midtrans_code, ''), is_active FROM payment_methods WHERE id = $1`
 	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
 	defer cancel()
 	pm := &PaymentMethod{}
-	err := s.db.QueryRowContext(ctx, query, id).Scan(&pm.ID, &pm.Name, &pm.Description, &pm.IsActive)
+	err := s.db.QueryRowContext(ctx, query, id).Scan(&pm.ID, &pm.Name, &pm.Description, &pm.MidtransCode, &pm.IsActive)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, ErrNotFound
@@ -65,10 +66,10 @@ func (s *PaymentMethodStore) GetByID(ctx context.Context, id int64) (*PaymentMet
 }
 
 func (s *PaymentMethodStore) Update(ctx context.Context, pm *PaymentMethod) error {
-	query := `UPDATE payment_methods SET name = $1, description = $2, is_active = $3 WHERE id = $4`
+	query := `UPDATE payment_methods SET name = $1, description = $2, midtrans_code = NULLIF($3, ''), is_active = $4 WHERE id = $5`
 	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
 	defer cancel()
-	res, err := s.db.ExecContext(ctx, query, pm.Name, pm.Description, pm.IsActive, pm.ID)
+	res, err := s.db.ExecContext(ctx, query, pm.Name, pm.Description, pm.MidtransCode, pm.IsActive, pm.ID)
 	if err != nil {
 		return err
 	}
@@ -0,0 +1,258 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// Status pembayaran yang disimpan di kolom payments.status
+const (
+	PaymentStatusPending = "pending"
+	PaymentStatusPaid    = "paid"
+	PaymentStatusFailed  = "failed"
+	PaymentStatusExpired = "expired"
+
+	// PaymentStatusRefundRequired menandai uang yang diterima untuk order yang sudah dibatalkan atau kadaluarsa
+	// dan harus dikembalikan ke pembeli
+	PaymentStatusRefundRequired = "refund_required"
+)
+
+type Payment struct {
+	ID              int64      `json:"id"`
+	OrderID         int64      `json:"order_id"`
+	Amount          Money      `json:"amount"`
+	PaymentMethodID int64      `json:"payment_method_id"`
+	TransactionID   string     `json:"transaction_id,omitempty"`
+	Status          string     `json:"status"`
+	PaymentDate     *time.Time `json:"payment_date,omitempty"`
+	CreatedAt       time.Time  `json:"created_at"`
+	UpdatedAt       time.Time  `json:"updated_at"`
+}
+
+// IsSettled mengembalikan true jika uang pembayaran sudah diterima, callback berikutnya tidak mengubahnya lagi
+func (p *Payment) IsSettled() bool {
+	return p.Status == PaymentStatusPaid || p.Status == PaymentStatusRefundRequired
+}
+
+type PaymentStore struct {
+	db *sql.DB
+}
+
+// Create menyimpan pembayaran baru untuk sebuah order
+func (s *PaymentStore) Create(ctx context.Context, p *Payment) error {
+	query := `
+		INSERT INTO payments (order_id, amount, payment_method_id, transaction_id, status)
+		VALUES ($1, $2, $3, NULLIF($4, ''), $5)
+		RETURNING id, created_at, updated_at`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	return s.db.QueryRowContext(ctx, query,
+		p.OrderID, p.Amount, p.PaymentMethodID, p.TransactionID, p.Status,
+	).Scan(&p.ID, &p.CreatedAt, &p.UpdatedAt)
+}
+
+// createPendingTx menyimpan pembayaran pending tanpa transaksi gateway di transaksi yang sama dengan order,
+// sehingga setiap order dari checkout selalu punya baris pembayaran
+func (s *PaymentStore) createPendingTx(ctx context.Context, tx *sql.Tx, order *Order) error {
+	query := `
+		INSERT INTO payments (order_id, amount, payment_method_id, status)
+		VALUES ($1, $2, $3, $4)`
+
+	_, err := tx.ExecContext(ctx, query, order.ID, order.FinalPrice, order.PaymentMethodID, PaymentStatusPending)
+	return err
+}
+
+// AttachCharge mengisi ID transaksi gateway ke pembayaran pending order yang belum punya tagihan
+func (s *PaymentStore) AttachCharge(ctx context.Context, orderID int64, transactionID string) (*Payment, error) {
+	query := `
+		UPDATE payments
+		SET transaction_id = $1, updated_at = now()
+		WHERE id = (
+			SELECT id FROM payments
+			WHERE order_id = $2 AND status = $3 AND transaction_id IS NULL
+			ORDER BY created_at DESC, id DESC
+			LIMIT 1
+		)
+		RETURNING id, order_id, amount, payment_method_id, COALESCE(transaction_id, ''), status,
+			payment_date, created_at, updated_at`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	return scanPayment(s.db.QueryRowContext(ctx, query, transactionID, orderID, PaymentStatusPending))
+}
+
+// FailCharge menandai pembayaran pending order sebagai gagal ketika tagihan gateway tidak bisa dibuat,
+// lalu membatalkan order dan mengembalikan stoknya
+func (s *PaymentStore) FailCharge(ctx context.Context, orderID int64, notes string) error {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	return withTx(s.db, ctx, func(tx *sql.Tx) error {
+		orders := &OrderStore{s.db}
+		current, _, err := orders.lockOrderTx(ctx, tx, orderID)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.ExecContext(ctx,
+			`UPDATE payments SET status = $1, updated_at = now() WHERE order_id = $2 AND status = $3`,
+			PaymentStatusFailed, orderID, PaymentStatusPending)
+		if err != nil {
+			return err
+		}
+
+		if current != OrderStatusPending {
+			return nil
+		}
+
+		actor := OrderActor{Role: OrderActorSystem}
+		return orders.releaseOrderTx(ctx, tx, orderID, current, OrderStatusCancelled, notes, actor)
+	})
+}
+
+// GetByOrderID mendapatkan pembayaran terbaru dari sebuah order
+func (s *PaymentStore) GetByOrderID(ctx context.Context, orderID int64) (*Payment, error) {
+	query := `
+		SELECT id, order_id, amount, payment_method_id, COALESCE(transaction_id, ''), status,
+			payment_date, created_at, updated_at
+		FROM payments
+		WHERE order_id = $1
+		ORDER BY created_at DESC, id DESC
+		LIMIT 1`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	return scanPayment(s.db.QueryRowContext(ctx, query, orderID))
+}
+
+// GetByOrderNumber mendapatkan pembayaran terbaru dari order dengan nomor order yang ditandatangani gateway
+func (s *PaymentStore) GetByOrderNumber(ctx context.Context, orderNumber string) (*Payment, error) {
+	query := `
+		SELECT p.id, p.order_id, p.amount, p.payment_method_id, COALESCE(p.transaction_id, ''), p.status,
+			p.payment_date, p.created_at, p.updated_at
+		FROM payments p
+		JOIN orders o ON o.id = p.order_id
+		WHERE o.order_number = $1
+		ORDER BY p.created_at DESC, p.id DESC
+		LIMIT 1`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	return scanPayment(s.db.QueryRowContext(ctx, query, orderNumber))
+}
+
+// GetByTransactionID mendapatkan pembayaran berdasarkan ID transaksi dari gateway
+func (s *PaymentStore) GetByTransactionID(ctx context.Context, transactionID string) (*Payment, error) {
+	query := `
+		SELECT id, order_id, amount, payment_method_id, COALESCE(transaction_id, ''), status,
+			payment_date, created_at, updated_at
+		FROM payments
+		WHERE transaction_id = $1`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	return scanPayment(s.db.QueryRowContext(ctx, query, transactionID))
+}
+
+func scanPayment(row *sql.Row) (*Payment, error) {
+	p := &Payment{}
+	var paymentDate sql.NullTime
+
+	err := row.Scan(
+		&p.ID, &p.OrderID, &p.Amount, &p.PaymentMethodID, &p.TransactionID, &p.Status,
+		&paymentDate, &p.CreatedAt, &p.UpdatedAt,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+
+	if paymentDate.Valid {
+		p.PaymentDate = &paymentDate.Time
+	}
+
+	return p, nil
+}
+
+// UpdateStatus mengubah status pembayaran dari callback gateway. Status yang sama atau pembayaran yang sudah
+// lunas diabaikan sehingga callback yang dikirim ulang aman. Order yang lunas tetap Pending sampai diterima seller
+// lewat AcceptBySeller, pembayaran lunas untuk order yang sudah tidak Pending disimpan sebagai refund_required.
+func (s *PaymentStore) UpdateStatus(ctx context.Context, transactionID, status string) (*Payment, error) {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	var p *Payment
+	err := withTx(s.db, ctx, func(tx *sql.Tx) error {
+		query := `
+			SELECT id, order_id, amount, payment_method_id, COALESCE(transaction_id, ''), status,
+				payment_date, created_at, updated_at
+			FROM payments
+			WHERE transaction_id = $1
+			FOR UPDATE`
+
+		var err error
+		p, err = scanPayment(tx.QueryRowContext(ctx, query, transactionID))
+		if err != nil {
+			return err
+		}
+
+		if p.Status == status || p.IsSettled() {
+			return nil
+		}
+
+		orders := &OrderStore{s.db}
+		var current int64
+		if status == PaymentStatusPaid {
+			current, _, err = orders.lockOrderTx(ctx, tx, p.OrderID)
+			if err != nil {
+				return err
+			}
+
+			// Order yang sudah dibatalkan atau kadaluarsa tidak dihidupkan lagi, uangnya harus dikembalikan
+			if current != OrderStatusPending {
+				status = PaymentStatusRefundRequired
+			}
+		}
+
+		query = `
+			UPDATE payments
+			SET status = $1,
+				payment_date = CASE WHEN $1 IN ('paid', 'refund_required') THEN now() ELSE payment_date END,
+				updated_at = now()
+			WHERE id = $2
+			RETURNING payment_date, updated_at`
+
+		var paymentDate sql.NullTime
+		if err := tx.QueryRowContext(ctx, query, status, p.ID).Scan(&paymentDate, &p.UpdatedAt); err != nil {
+			return err
+		}
+
+		p.Status = status
+		if paymentDate.Valid {
+			p.PaymentDate = &paymentDate.Time
+		}
+
+		if status != PaymentStatusPaid {
+			return nil
+		}
+
+		// Order tetap Pending sampai diterima seller, pembayaran hanya dicatat di tracking
+		actor := OrderActor{Role: OrderActorSystem}
+		return orders.updateStatusTx(ctx, tx, p.OrderID, current, current, "Payment received, waiting for seller", actor)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return p, nil
+}
@@ -0,0 +1,400 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/yogaprasetya22/api-gotokopedia/internal/pricing"
+)
+
+var (
+	ErrVariantRequired = errors.New("produk memiliki varian, pilih varian terlebih dahulu")
+	ErrInvalidVariant  = errors.New("varian tidak valid untuk produk ini")
+)
+
+// ProductOptionType adalah jenis opsi varian produk, misalnya warna, ukuran atau penyimpanan
+type ProductOptionType struct {
+	ID        int64                 `json:"id"`
+	ProductID int64                 `json:"product_id"`
+	Name      string                `json:"name"`
+	Position  int                   `json:"position"`
+	Values    []*ProductOptionValue `json:"values"`
+}
+
+// ProductOptionValue adalah satu pilihan dari jenis opsi, misalnya "Hitam" atau "128GB"
+type ProductOptionValue struct {
+	ID           int64  `json:"id"`
+	OptionTypeID int64  `json:"option_type_id"`
+	Value        string `json:"value"`
+	Position     int    `json:"position"`
+}
+
+// ProductVariant adalah satu SKU untuk satu kombinasi nilai opsi dengan harga, stok dan gambar sendiri
+type ProductVariant struct {
+	ID            int64             `json:"id"`
+	ProductID     int64             `json:"product_id"`
+	SKU           string            `json:"sku"`
+	Name          string            `json:"name"` // gabungan nilai opsi, contoh "Hitam / 128GB"
+	Price         Money             `json:"price"`
+	DiscountPrice Money             `json:"discount_price"`
+	Stock         int               `json:"stock"`
+	ImageURL      string            `json:"image_url,omitempty"`
+	IsActive      bool              `json:"is_active"`
+	Position      int               `json:"position"`
+	Options       map[string]string `json:"options"` // nama jenis opsi -> nilai opsi
+	Version       int               `json:"version"`
+	CreatedAt     time.Time         `json:"created_at"`
+	UpdatedAt     time.Time         `json:"updated_at"`
+}
+
+// VariantMatrix adalah seluruh jenis opsi dan varian sebuah produk
+type VariantMatrix struct {
+	Options  []*ProductOptionType `json:"options"`
+	Variants []*ProductVariant    `json:"variants"`
+}
+
+// Pricing mengembalikan rincian harga varian untuk quantity unit.
+// Varian punya harga coret sendiri sehingga persentase diskon produk tidak dipakai.
+func (v *ProductVariant) Pricing(quantity int64) pricing.Breakdown {
+	return pricing.Line(v.Price.Amount, v.DiscountPrice.Amount, 0, quantity)
+}
+
+// Validate memastikan setiap varian memilih tepat satu nilai untuk setiap jenis opsi,
+// tidak ada kombinasi atau SKU ganda, lalu mengisi nama varian dari nilai opsinya
+func (m *VariantMatrix) Validate() error {
+	if len(m.Variants) == 0 {
+		return nil
+	}
+
+	if len(m.Options) == 0 {
+		return fmt.Errorf("%w: varian membutuhkan minimal satu jenis opsi", ErrInvalidVariant)
+	}
+
+	values := make(map[string]map[string]bool, len(m.Options))
+	for _, o := range m.Options {
+		if _, ok := values[o.Name]; ok {
+			return fmt.Errorf("%w: jenis opsi %q ganda", ErrInvalidVariant, o.Name)
+		}
+		values[o.Name] = make(map[string]bool, len(o.Values))
+		for _, v := range o.Values {
+			values[o.Name][v.Value] = true
+		}
+	}
+
+	skus := make(map[string]bool, len(m.Variants))
+	combinations := make(map[string]bool, len(m.Variants))
+	for _, v := range m.Variants {
+		if v.SKU == "" {
+			return fmt.Errorf("%w: sku wajib diisi", ErrInvalidVariant)
+		}
+		if skus[v.SKU] {
+			return fmt.Errorf("%w: sku %q ganda", ErrInvalidVariant, v.SKU)
+		}
+		skus[v.SKU] = true
+
+		if len(v.Options) != len(m.Options) {
+			return fmt.Errorf("%w: varian %q harus memilih satu nilai untuk setiap jenis opsi", ErrInvalidVariant, v.SKU)
+		}
+
+		names := make([]string, 0, len(m.Options))
+		for _, o := range m.Options {
+			value, ok := v.Options[o.Name]
+			if !ok || !values[o.Name][value] {
+				return fmt.Errorf("%w: nilai %q untuk opsi %q pada varian %q tidak dikenal", ErrInvalidVariant, value, o.Name, v.SKU)
+			}
+			names = append(names, value)
+		}
+
+		v.Name = strings.Join(names, " / ")
+		if combinations[v.Name] {
+			return fmt.Errorf("%w: kombinasi %q ganda", ErrInvalidVariant, v.Name)
+		}
+		combinations[v.Name] = true
+	}
+
+	return nil
+}
+
+// cartItemPriceColumns memilih price, discount_price dan discount item keranjang dari
+// product_variants v jika item memakai varian, selain itu dari products p
+const cartItemPriceColumns = `COALESCE(v.price, p.price), COALESCE(v.discount_price, p.discount_price),
+	CASE WHEN v.id IS NULL THEN p.discount ELSE 0 END`
+
+type ProductVariantStore struct {
+	db *sql.DB
+}
+
+// GetByProductID mendapatkan matriks opsi dan varian sebuah produk
+func (s *ProductVariantStore) GetByProductID(ctx context.Context, productID int64) (*VariantMatrix, error) {
+	var matrix *VariantMatrix
+
+	err := withTx(s.db, ctx, func(tx *sql.Tx) error {
+		var err error
+		matrix, err = variantMatrixTx(ctx, tx, productID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return matrix, nil
+}
+
+// GetByID mendapatkan satu varian beserta nilai opsinya
+func (s *ProductVariantStore) GetByID(ctx context.Context, id int64) (*ProductVariant, error) {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	var variant *ProductVariant
+	err := withTx(s.db, ctx, func(tx *sql.Tx) error {
+		var err error
+		variant, err = getVariantTx(ctx, tx, id)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return variant, nil
+}
+
+// Replace mengganti seluruh matriks varian produk. Varian dengan SKU yang sama diperbarui
+// sehingga ID-nya tetap dan item keranjang yang memakainya tidak hilang, varian yang tidak
+// ada lagi di matriks dihapus. Stok produk menjadi total stok semua varian aktif.
+func (s *ProductVariantStore) Replace(ctx context.Context, productID int64, matrix *VariantMatrix) error {
+	if err := matrix.Validate(); err != nil {
+		return err
+	}
+
+	return withTx(s.db, ctx, func(tx *sql.Tx) error {
+		ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+		defer cancel()
+
+		// Jenis opsi dibuat ulang, nilai opsi dan kombinasi varian ikut terhapus lewat cascade
+		if _, err := tx.ExecContext(ctx, `DELETE FROM product_option_types WHERE product_id = $1`, productID); err != nil {
+			return err
+		}
+
+		valueIDs := make(map[string]map[string]int64, len(matrix.Options))
+		for i, o := range matrix.Options {
+			o.ProductID = productID
+			o.Position = i
+			err := tx.QueryRowContext(ctx,
+				`INSERT INTO product_option_types (product_id, name, position) VALUES ($1, $2, $3) RETURNING id`,
+				productID, o.Name, o.Position,
+			).Scan(&o.ID)
+			if err != nil {
+				return err
+			}
+
+			valueIDs[o.Name] = make(map[string]int64, len(o.Values))
+			for j, v := range o.Values {
+				v.OptionTypeID = o.ID
+				v.Position = j
+				err := tx.QueryRowContext(ctx,
+					`INSERT INTO product_option_values (option_type_id, value, position) VALUES ($1, $2, $3) RETURNING id`,
+					o.ID, v.Value, v.Position,
+				).Scan(&v.ID)
+				if err != nil {
+					if strings.Contains(err.Error(), "product_option_values_type_value_key") {
+						return fmt.Errorf("%w: nilai %q ganda pada opsi %q", ErrInvalidVariant, v.Value, o.Name)
+					}
+					return err
+				}
+				valueIDs[o.Name][v.Value] = v.ID
+			}
+		}
+
+		// SKU unik secara global, SKU milik produk lain tidak boleh diambil alih
+		const upsertQuery = `
+			INSERT INTO product_variants (product_id, sku, name, price, discount_price, stock, image_url, is_active, position)
+			VALUES ($1, $2, $3, $4, $5, $6, NULLIF($7, ''), $8, $9)
+			ON CONFLICT (sku) DO UPDATE
+			SET name = EXCLUDED.name, price = EXCLUDED.price, discount_price = EXCLUDED.discount_price,
+				stock = EXCLUDED.stock, image_url = EXCLUDED.image_url, is_active = EXCLUDED.is_active,
+				position = EXCLUDED.position, version = product_variants.version + 1, updated_at = now()
+			WHERE product_variants.product_id = EXCLUDED.product_id
+			RETURNING id, version, created_at, updated_at`
+
+		skus := make([]string, 0, len(matrix.Variants))
+		for i, v := range matrix.Variants {
+			v.ProductID = productID
+			v.Position = i
+			err := tx.QueryRowContext(ctx, upsertQuery,
+				productID, v.SKU, v.Name, v.Price, v.DiscountPrice, v.Stock, v.ImageURL, v.IsActive, v.Position,
+			).Scan(&v.ID, &v.Version, &v.CreatedAt, &v.UpdatedAt)
+			if err != nil {
+				if errors.Is(err, sql.ErrNoRows) {
+					return fmt.Errorf("%w: sku %q sudah dipakai produk lain", ErrConflict, v.SKU)
+				}
+				return err
+			}
+
+			for name, value := range v.Options {
+				_, err := tx.ExecContext(ctx,
+					`INSERT INTO product_variant_values (variant_id, option_value_id) VALUES ($1, $2)`,
+					v.ID, valueIDs[name][value],
+				)
+				if err != nil {
+					return err
+				}
+			}
+
+			skus = append(skus, v.SKU)
+		}
+
+		_, err := tx.ExecContext(ctx,
+			`DELETE FROM product_variants WHERE product_id = $1 AND NOT (sku = ANY($2))`,
+			productID, pq.Array(skus),
+		)
+		if err != nil {
+			return err
+		}
+
+		if len(matrix.Variants) == 0 {
+			return nil
+		}
+
+		_, err = tx.ExecContext(ctx, `
+			UPDATE products
+			SET stock = (SELECT COALESCE(SUM(stock), 0) FROM product_variants WHERE product_id = $1 AND is_active),
+				updated_at = now()
+			WHERE id = $1`, productID)
+		return err
+	})
+}
+
+// variantMatrixTx membaca jenis opsi, nilai opsi dan varian produk dalam transaksi
+func variantMatrixTx(ctx context.Context, tx *sql.Tx, productID int64) (*VariantMatrix, error) {
+	const optionsQuery = `
+		SELECT ot.id, ot.name, ot.position, ov.id, ov.value, ov.position
+		FROM product_option_types ot
+		JOIN product_option_values ov ON ov.option_type_id = ot.id
+		WHERE ot.product_id = $1
+		ORDER BY ot.position, ov.position`
+
+	rows, err := tx.QueryContext(ctx, optionsQuery, productID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	matrix := &VariantMatrix{
+		Options:  []*ProductOptionType{},
+		Variants: []*ProductVariant{},
+	}
+	optionTypes := make(map[int64]*ProductOptionType)
+	for rows.Next() {
+		var o ProductOptionType
+		var v ProductOptionValue
+		if err := rows.Scan(&o.ID, &o.Name, &o.Position, &v.ID, &v.Value, &v.Position); err != nil {
+			return nil, err
+		}
+
+		existing, ok := optionTypes[o.ID]
+		if !ok {
+			o.ProductID = productID
+			existing = &o
+			optionTypes[o.ID] = existing
+			matrix.Options = append(matrix.Options, existing)
+		}
+		v.OptionTypeID = existing.ID
+		existing.Values = append(existing.Values, &v)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	variants, err := queryVariantsTx(ctx, tx, `v.product_id = $1 ORDER BY v.position, v.id`, productID)
+	if err != nil {
+		return nil, err
+	}
+	matrix.Variants = variants
+
+	return matrix, nil
+}
+
+// getVariantTx mendapatkan satu varian dalam transaksi
+func getVariantTx(ctx context.Context, tx *sql.Tx, id int64) (*ProductVariant, error) {
+	variants, err := queryVariantsTx(ctx, tx, `v.id = $1`, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(variants) == 0 {
+		return nil, ErrNotFound
+	}
+
+	return variants[0], nil
+}
+
+// queryVariantsTx membaca varian yang memenuhi kondisi where beserta nilai opsinya
+func queryVariantsTx(ctx context.Context, tx *sql.Tx, where string, args ...any) ([]*ProductVariant, error) {
+	query := `
+		SELECT v.id, v.product_id, v.sku, v.name, v.price, v.discount_price, v.stock,
+			COALESCE(v.image_url, ''), v.is_active, v.position, v.version, v.created_at, v.updated_at
+		FROM product_variants v
+		WHERE ` + where
+
+	rows, err := tx.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	variants := []*ProductVariant{}
+	byID := make(map[int64]*ProductVariant)
+	for rows.Next() {
+		v := &ProductVariant{Options: map[string]string{}}
+		err := rows.Scan(&v.ID, &v.ProductID, &v.SKU, &v.Name, &v.Price, &v.DiscountPrice, &v.Stock,
+			&v.ImageURL, &v.IsActive, &v.Position, &v.Version, &v.CreatedAt, &v.UpdatedAt)
+		if err != nil {
+			return nil, err
+		}
+		variants = append(variants, v)
+		byID[v.ID] = v
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(variants) == 0 {
+		return variants, nil
+	}
+
+	variantIDs := make([]int64, 0, len(variants))
+	for _, v := range variants {
+		variantIDs = append(variantIDs, v.ID)
+	}
+
+	const valuesQuery = `
+		SELECT vv.variant_id, ot.name, ov.value
+		FROM product_variant_values vv
+		JOIN product_option_values ov ON vv.option_value_id = ov.id
+		JOIN product_option_types ot ON ov.option_type_id = ot.id
+		WHERE vv.variant_id = ANY($1)`
+
+	valueRows, err := tx.QueryContext(ctx, valuesQuery, pq.Array(variantIDs))
+	if err != nil {
+		return nil, err
+	}
+	defer valueRows.Close()
+
+	for valueRows.Next() {
+		var variantID int64
+		var name, value string
+		if err := valueRows.Scan(&variantID, &name, &value); err != nil {
+			return nil, err
+		}
+		byID[variantID].Options[name] = value
+	}
+
+	return variants, valueRows.Err()
+}
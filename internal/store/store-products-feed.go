@@ -14,8 +14,8 @@ type DetailProduct struct {
 	Slug          string    `json:"slug" `
 	Country       string    `json:"country" `
 	Description   string    `json:"description,omitempty" `
-	Price         float64   `json:"price" `
-	DiscountPrice float64   `json:"discount_price" `
+	Price         Money     `json:"price" `
+	DiscountPrice Money     `json:"discount_price" `
 	Discount      float64   `json:"discount" `
 	Rating        float64   `json:"rating" `
 	Estimation    string    `json:"estimation" `
@@ -30,6 +30,10 @@ type DetailProduct struct {
 	Category      *Category `json:"category" `
 	Toko          *Toko     `json:"toko" `
 	Ulasan        Ulasan    `json:"ulasan"` // New field for review stats
+
+	// Matriks varian, kosong jika produk tidak memiliki varian
+	Options  []*ProductOptionType `json:"options"`
+	Variants []*ProductVariant    `json:"variants"`
 }
 
 type Ulasan struct {
@@ -38,19 +42,19 @@ type Ulasan struct {
 	RatingBreakdown map[int]int `json:"rating_breakdown"` // Count of each star rating
 }
 
-func (s *ProductStore) GetProductByTokos(ctx context.Context, slug_toko string, query PaginatedFeedQuery) ([]*Product, error) {
+func (s *ProductStore) GetProductByTokos(ctx context.Context, slug_toko string, query PaginatedFeedQuery) (*Paginated[*Product], error) {
 	tx, err := s.db.BeginTx(ctx, nil)
 	if err != nil {
 		return nil, err
 	}
 	defer tx.Rollback()
 
-	products, err := s.productByTokoSlug(ctx, tx, slug_toko, query)
+	page, err := s.productByTokoSlug(ctx, tx, slug_toko, query)
 	if err != nil {
 		return nil, err
 	}
 
-	err = s.commentsForProducts(ctx, tx, products)
+	err = s.commentsForProducts(ctx, tx, page.Data)
 	if err != nil {
 		return nil, err
 	}
@@ -59,52 +63,17 @@ func (s *ProductStore) GetProductByTokos(ctx context.Context, slug_toko string,
 		return nil, err
 	}
 
-	return products, nil
+	return page, nil
 }
 
-func (s *ProductStore) productByTokoSlug(ctx context.Context, tx *sql.Tx, slug_toko string, fq PaginatedFeedQuery) ([]*Product, error) {
-	query := `SELECT p.id, p.name, p.slug, p.country, p.description, p.price, p.discount_price, p.discount, p.estimation, p.stock, p.sold, p.is_for_sale, p.is_approved, p.created_at, p.updated_at, p.image_urls,
-		c.id, c.name, c.slug, 
-		t.id, t.user_id, t.slug, t.name, t.country, t.created_at,
-		u.id, u.username, u.email, u.picture, u.created_at, u.is_active
-	FROM products p
-	JOIN category c ON p.category_id = c.id
-	JOIN tokos t ON p.toko_id = t.id
-	JOIN users u ON t.user_id = u.id
-	WHERE t.slug = $1 AND p.is_approved = true AND (p.name ILIKE '%' || $4 || '%' OR p.description ILIKE '%' || $4 || '%')
-	ORDER BY p.created_at ` + fq.Sort + `
-	LIMIT $2 OFFSET $3`
+func (s *ProductStore) productByTokoSlug(ctx context.Context, tx *sql.Tx, slug_toko string, fq PaginatedFeedQuery) (*Paginated[*Product], error) {
+	filter := NewProductFilter(fq)
+	filter.TokoSlug = slug_toko
 
 	skip := fq.Offset * (fq.Limit)
+	order, scope := productOrder(fq, SortNewest)
 
-	rows, err := tx.QueryContext(ctx, query, slug_toko, fq.Limit, skip, fq.Search)
-	if err != nil {
-		return nil, err
-	}
-
-	defer rows.Close()
-
-	var products []*Product
-	for rows.Next() {
-		p := &Product{
-			Category: &Category{},
-			Toko:     &Toko{User: &SingleUser{}},
-		}
-		err := rows.Scan(&p.ID, &p.Name, &p.Slug, &p.Country, &p.Description, &p.Price, &p.DiscountPrice, &p.Discount, &p.Estimation, &p.Stock, &p.Sold, &p.IsForSale, &p.IsApproved, &p.CreatedAt, &p.UpdatedAt, pq.Array(&p.ImageUrls),
-			&p.Category.ID, &p.Category.Name, &p.Category.Slug,
-			&p.Toko.ID, &p.Toko.UserID, &p.Toko.Slug, &p.Toko.Name, &p.Toko.Country, &p.Toko.CreatedAt,
-			&p.Toko.User.ID, &p.Toko.User.Username, &p.Toko.User.Email, &p.Toko.User.Picture, &p.Toko.User.CreatedAt, &p.Toko.User.IsActive)
-		if err != nil {
-			return nil, err
-		}
-		products = append(products, p)
-	}
-
-	if err := rows.Err(); err != nil {
-		return nil, err
-	}
-
-	return products, nil
+	return listProducts(ctx, tx, filter, order, scope, fq, skip)
 }
 
 func (s *ProductStore) GetProduct(ctx context.Context, slug_toko, slug_product string) (*DetailProduct, error) {
@@ -124,6 +93,13 @@ func (s *ProductStore) GetProduct(ctx context.Context, slug_toko, slug_product s
 		return nil, err
 	}
 
+	matrix, err := variantMatrixTx(ctx, tx, product.ID)
+	if err != nil {
+		return nil, err
+	}
+	product.Options = matrix.Options
+	product.Variants = matrix.Variants
+
 	if err := tx.Commit(); err != nil {
 		return nil, err
 	}
@@ -159,19 +135,19 @@ func (s *ProductStore) productBySlug(ctx context.Context, tx *sql.Tx, slug_toko,
 	return p, nil
 }
 
-func (s *ProductStore) GetProductFeed(ctx context.Context, categoryIDs []int64, fq PaginatedFeedQuery) ([]*Product, error) {
+func (s *ProductStore) GetProductFeed(ctx context.Context, categoryIDs []int64, fq PaginatedFeedQuery) (*Paginated[*Product], error) {
 	tx, err := s.db.BeginTx(ctx, nil)
 	if err != nil {
 		return nil, err
 	}
 	defer tx.Rollback()
 
-	products, err := s.productFeed(ctx, tx, categoryIDs, fq)
+	page, err := s.productFeed(ctx, tx, categoryIDs, fq)
 	if err != nil {
 		return nil, err
 	}
 
-	err = s.commentsForProducts(ctx, tx, products)
+	err = s.commentsForProducts(ctx, tx, page.Data)
 	if err != nil {
 		return nil, err
 	}
@@ -180,69 +156,32 @@ func (s *ProductStore) GetProductFeed(ctx context.Context, categoryIDs []int64,
 		return nil, err
 	}
 
-	return products, nil
+	return page, nil
 }
 
-func (s *ProductStore) productFeed(ctx context.Context, tx *sql.Tx, categoryIDs []int64, fq PaginatedFeedQuery) ([]*Product, error) {
-	query := `SELECT p.id, p.name, p.slug, p.country, p.description, p.price, p.discount_price, p.discount, p.estimation, p.stock, p.sold, p.is_for_sale, p.is_approved, p.created_at, p.updated_at, p.image_urls, 
-           c.id, c.name, c.slug, 
-           t.id, t.user_id, t.slug, t.name, t.country, t.created_at, 
-           u.id, u.username, u.email, u.picture, u.created_at, u.is_active
-    FROM products p
-    JOIN category c ON p.category_id = c.id
-    JOIN tokos t ON p.toko_id = t.id
-    JOIN users u ON t.user_id = u.id
-    WHERE p.is_approved = true AND p.category_id = ANY($1) AND (p.name ILIKE '%' || $4 || '%' OR p.description ILIKE '%' || $4 || '%')
-   ORDER BY
-            p.created_at ` + fq.Sort + `
-    LIMIT $2 OFFSET $3`
+func (s *ProductStore) productFeed(ctx context.Context, tx *sql.Tx, categoryIDs []int64, fq PaginatedFeedQuery) (*Paginated[*Product], error) {
+	filter := NewProductFilter(fq)
+	filter.CategoryIDs = categoryIDs
 
 	skip := fq.Offset * (fq.Limit)
+	order, scope := productOrder(fq, SortNewest)
 
-	rows, err := tx.QueryContext(ctx, query, pq.Array(categoryIDs), fq.Limit, skip, fq.Search)
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
-
-	var products []*Product
-	for rows.Next() {
-		p := &Product{
-			Category: &Category{},
-			Toko: &Toko{
-				User: &SingleUser{},
-			},
-		}
-		err := rows.Scan(&p.ID, &p.Name, &p.Slug, &p.Country, &p.Description, &p.Price, &p.DiscountPrice, &p.Discount, &p.Estimation, &p.Stock, &p.Sold, &p.IsForSale, &p.IsApproved, &p.CreatedAt, &p.UpdatedAt, pq.Array(&p.ImageUrls),
-			&p.Category.ID, &p.Category.Name, &p.Category.Slug,
-			&p.Toko.ID, &p.Toko.UserID, &p.Toko.Slug, &p.Toko.Name, &p.Toko.Country, &p.Toko.CreatedAt,
-			&p.Toko.User.ID, &p.Toko.User.Username, &p.Toko.User.Email, &p.Toko.User.Picture, &p.Toko.User.CreatedAt, &p.Toko.User.IsActive)
-		if err != nil {
-			return nil, err
-		}
-		products = append(products, p)
-	}
-
-	if err := rows.Err(); err != nil {
-		return nil, err
-	}
-
-	return products, nil
+	return listProducts(ctx, tx, filter, order, scope, fq, skip)
 }
 
-func (s *ProductStore) GetProductCategoryFeed(ctx context.Context, fq PaginatedFeedQuery) ([]*Product, error) {
+func (s *ProductStore) GetProductCategoryFeed(ctx context.Context, fq PaginatedFeedQuery) (*Paginated[*Product], error) {
 	tx, err := s.db.BeginTx(ctx, nil)
 	if err != nil {
 		return nil, err
 	}
 	defer tx.Rollback()
 
-	products, err := s.productsByCategorySlug(ctx, tx, fq)
+	page, err := s.productsByCategorySlug(ctx, tx, fq)
 	if err != nil {
 		return nil, err
 	}
 
-	err = s.commentsForProducts(ctx, tx, products)
+	err = s.commentsForProducts(ctx, tx, page.Data)
 	if err != nil {
 		return nil, err
 	}
@@ -251,51 +190,12 @@ func (s *ProductStore) GetProductCategoryFeed(ctx context.Context, fq PaginatedF
 		return nil, err
 	}
 
-	return products, nil
+	return page, nil
 }
 
-func (s *ProductStore) productsByCategorySlug(ctx context.Context, tx *sql.Tx, fq PaginatedFeedQuery) ([]*Product, error) {
-	query := `SELECT p.id, p.name, p.slug, p.country, p.description, p.price, p.discount_price, p.discount, p.estimation, p.stock, p.sold, p.is_for_sale, p.is_approved, p.created_at, p.updated_at, p.image_urls, 
-               c.id, c.name, c.slug, 
-               t.id, t.user_id, t.slug, t.name, t.country, t.created_at, 
-               u.id, u.username, u.email, u.picture, u.created_at, u.is_active
-        FROM products p
-        JOIN category c ON p.category_id = c.id
-        JOIN tokos t ON p.toko_id = t.id
-        JOIN users u ON t.user_id = u.id
-        WHERE p.is_approved = true AND (SELECT id FROM category WHERE slug = $1) = c.id AND (p.name ILIKE '%' || $4 || '%' OR p.description ILIKE '%' || $4 || '%')
-        ORDER BY p.sold ` + fq.Sort + `
-        LIMIT $2 OFFSET $3`
-
+func (s *ProductStore) productsByCategorySlug(ctx context.Context, tx *sql.Tx, fq PaginatedFeedQuery) (*Paginated[*Product], error) {
 	skip := fq.Offset * (fq.Limit)
+	order, scope := productOrder(fq, SortBestSelling)
 
-	rows, err := tx.QueryContext(ctx, query, fq.Category, fq.Limit, skip, fq.Search)
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
-
-	var products []*Product
-	for rows.Next() {
-		p := &Product{
-			Category: &Category{},
-			Toko: &Toko{
-				User: &SingleUser{},
-			},
-		}
-		err := rows.Scan(&p.ID, &p.Name, &p.Slug, &p.Country, &p.Description, &p.Price, &p.DiscountPrice, &p.Discount, &p.Estimation, &p.Stock, &p.Sold, &p.IsForSale, &p.IsApproved, &p.CreatedAt, &p.UpdatedAt, pq.Array(&p.ImageUrls),
-			&p.Category.ID, &p.Category.Name, &p.Category.Slug,
-			&p.Toko.ID, &p.Toko.UserID, &p.Toko.Slug, &p.Toko.Name, &p.Toko.Country, &p.Toko.CreatedAt,
-			&p.Toko.User.ID, &p.Toko.User.Username, &p.Toko.User.Email, &p.Toko.User.Picture, &p.Toko.User.CreatedAt, &p.Toko.User.IsActive)
-		if err != nil {
-			return nil, err
-		}
-		products = append(products, p)
-	}
-
-	if err := rows.Err(); err != nil {
-		return nil, err
-	}
-
-	return products, nil
+	return listProducts(ctx, tx, NewProductFilter(fq), order, scope, fq, skip)
 }
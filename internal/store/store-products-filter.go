@@ -0,0 +1,396 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// Potongan SQL harga produk, mengikuti pricing.Unit agar filter dan facet memakai harga yang dibayar pembeli
+const (
+	productEffectivePrice = `(CASE
+		WHEN p.discount_price > 0 AND p.discount_price <> p.price THEN
+			CASE WHEN LEAST(p.price, p.discount_price) > 0 THEN LEAST(p.price, p.discount_price) ELSE GREATEST(p.price, p.discount_price) END
+		WHEN p.discount > 0 AND p.discount < 100 THEN round(p.price * (100 - p.discount) / 100)
+		ELSE p.price END)::bigint`
+
+	productListPrice = `(CASE WHEN p.discount_price > 0 AND p.discount_price <> p.price THEN GREATEST(p.price, p.discount_price) ELSE p.price END)`
+
+	productFilterFrom = `FROM products p
+	JOIN category c ON p.category_id = c.id
+	JOIN tokos t ON p.toko_id = t.id
+	JOIN users u ON t.user_id = u.id
+	LEFT JOIN LATERAL (SELECT AVG(rating) AS rating FROM comments WHERE product_id = p.id) pr ON true`
+)
+
+// PriceBucketBounds adalah batas rentang harga facet, rentang terakhir tidak punya batas atas
+var PriceBucketBounds = []int64{100_000, 500_000, 1_000_000, 5_000_000}
+
+// ProductFilter adalah filter katalog produk, field kosong atau bernilai 0 tidak difilter
+type ProductFilter struct {
+	TokoSlug     string
+	CategorySlug string
+	CategoryIDs  []int64
+	Search       string
+	MinPrice     Money
+	MaxPrice     Money
+	MinRating    int
+	Country      string
+	MinDiscount  float64
+	InStock      bool
+	Since        string
+	Until        string
+
+	feedSnapshot *uuid.UUID // diisi GetPersonalizedFeed agar skor snapshot feed user bisa dipakai sebagai order key
+}
+
+// NewProductFilter membuat filter dari query feed
+func NewProductFilter(fq PaginatedFeedQuery) ProductFilter {
+	return ProductFilter{
+		CategorySlug: fq.Category,
+		Search:       fq.Search,
+		MinPrice:     MoneyFromMajor(fq.MinPrice),
+		MaxPrice:     MoneyFromMajor(fq.MaxPrice),
+		MinRating:    fq.Rating,
+		Country:      fq.Country,
+		MinDiscount:  fq.Discount,
+		InStock:      fq.InStock,
+		Since:        fq.Since,
+		Until:        fq.Until,
+	}
+}
+
+// productFacet menandai filter yang dilewati saat menghitung facet miliknya sendiri,
+// agar pilihan lain di facet yang sama tetap terlihat jumlahnya
+type productFacet int
+
+const (
+	facetNone productFacet = iota
+	facetCategory
+	facetPrice
+	facetRating
+	facetCountry
+)
+
+// sqlFilter menyusun kondisi WHERE dengan nilai yang selalu dikirim sebagai parameter
+type sqlFilter struct {
+	conds []string
+	args  []any
+}
+
+// arg menambahkan nilai parameter dan mengembalikan placeholder-nya
+func (b *sqlFilter) arg(v any) string {
+	b.args = append(b.args, v)
+	return fmt.Sprintf("$%d", len(b.args))
+}
+
+func (b *sqlFilter) where(cond string) {
+	b.conds = append(b.conds, cond)
+}
+
+func (b *sqlFilter) clause() string {
+	if len(b.conds) == 0 {
+		return ""
+	}
+	return "WHERE " + strings.Join(b.conds, " AND ")
+}
+
+// apply menambahkan kondisi filter ke builder, kecuali filter milik facet omit
+func (f ProductFilter) apply(b *sqlFilter, omit productFacet) {
+	b.where("p.is_approved = true")
+
+	if f.TokoSlug != "" {
+		b.where("t.slug = " + b.arg(f.TokoSlug))
+	}
+
+	if len(f.CategoryIDs) > 0 {
+		b.where("p.category_id = ANY(" + b.arg(pq.Array(f.CategoryIDs)) + ")")
+	}
+
+	if f.Search != "" {
+		match, _, _ := productSearch(b.arg(f.Search))
+		b.where(match)
+	}
+
+	if f.InStock {
+		b.where("p.stock > 0")
+	}
+
+	if f.MinDiscount > 0 {
+		b.where(productEffectivePrice + " * 100 <= " + productListPrice + " * (100 - " + b.arg(f.MinDiscount) + "::numeric)")
+	}
+
+	if f.Since != "" {
+		b.where("p.created_at >= " + b.arg(f.Since) + "::timestamp")
+	}
+
+	if f.Until != "" {
+		b.where("p.created_at <= " + b.arg(f.Until) + "::timestamp")
+	}
+
+	if f.CategorySlug != "" && omit != facetCategory {
+		b.where("c.slug = " + b.arg(f.CategorySlug))
+	}
+
+	if omit != facetPrice {
+		if f.MinPrice.Amount > 0 {
+			b.where(productEffectivePrice + " >= " + b.arg(f.MinPrice.Amount))
+		}
+		if f.MaxPrice.Amount > 0 {
+			b.where(productEffectivePrice + " <= " + b.arg(f.MaxPrice.Amount))
+		}
+	}
+
+	if f.MinRating > 0 && omit != facetRating {
+		b.where("COALESCE(pr.rating, 0) >= " + b.arg(f.MinRating))
+	}
+
+	if f.Country != "" && omit != facetCountry {
+		b.where("t.country = " + b.arg(f.Country))
+	}
+}
+
+// queryer dipenuhi *sql.DB dan *sql.Tx
+type queryer interface {
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+}
+
+// listProducts mengambil satu halaman produk yang lolos filter dengan urutan order. Jika fq.Position
+// diisi halaman dibaca dari posisi cursor dan offset diabaikan.
+func listProducts(ctx context.Context, q queryer, f ProductFilter, order []orderKey, scope string, fq PaginatedFeedQuery, offset int) (*Paginated[*Product], error) {
+	b := &sqlFilter{}
+	search := b.arg(f.Search)
+	_, rank, snippet := productSearch(search)
+	f.apply(b, facetNone)
+
+	var join string
+	if f.feedSnapshot != nil {
+		join = feedSnapshotJoin(b, *f.feedSnapshot)
+	}
+
+	r := strings.NewReplacer(":q", search)
+	for i := range order {
+		order[i].expr = r.Replace(order[i].expr)
+	}
+
+	order, err := b.keyset(scope, order, fq.Position)
+	if err != nil {
+		return nil, err
+	}
+	if fq.Position != nil {
+		offset = 0
+	}
+
+	query := `SELECT p.id, p.name, p.slug, p.country, p.description, p.price, p.discount_price, p.discount, p.estimation, p.stock, p.sold, p.is_for_sale, p.is_approved, p.created_at, p.updated_at, p.image_urls,
+		c.id, c.name, c.slug,
+		t.id, t.user_id, t.slug, t.name, t.country, t.created_at,
+		u.id, u.username, u.email, u.picture, u.created_at, u.is_active,
+		` + rank + ` AS search_rank, ` + snippet + `,
+		` + keysetSelect(order) + `
+	` + productFilterFrom + join + `
+	` + b.clause() + `
+	ORDER BY ` + orderByClause(order) + `
+	LIMIT ` + b.arg(fq.Limit+1) + ` OFFSET ` + b.arg(offset)
+
+	rows, err := q.QueryContext(ctx, query, b.args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var (
+		products []*Product
+		keys     [][]string
+	)
+	for rows.Next() {
+		p := &Product{
+			Category: &Category{},
+			Toko:     &Toko{User: &SingleUser{}},
+		}
+		dest := []any{&p.ID, &p.Name, &p.Slug, &p.Country, &p.Description, &p.Price, &p.DiscountPrice, &p.Discount, &p.Estimation, &p.Stock, &p.Sold, &p.IsForSale, &p.IsApproved, &p.CreatedAt, &p.UpdatedAt, pq.Array(&p.ImageUrls),
+			&p.Category.ID, &p.Category.Name, &p.Category.Slug,
+			&p.Toko.ID, &p.Toko.UserID, &p.Toko.Slug, &p.Toko.Name, &p.Toko.Country, &p.Toko.CreatedAt,
+			&p.Toko.User.ID, &p.Toko.User.Username, &p.Toko.User.Email, &p.Toko.User.Picture, &p.Toko.User.CreatedAt, &p.Toko.User.IsActive,
+			&p.SearchRank, &p.Snippet}
+
+		rowKeys := make([]string, len(order))
+		for i := range rowKeys {
+			dest = append(dest, &rowKeys[i])
+		}
+
+		if err := rows.Scan(dest...); err != nil {
+			return nil, err
+		}
+		products = append(products, p)
+		keys = append(keys, rowKeys)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	page := &Paginated[*Product]{}
+	page.Data, page.Pagination = newCursorPage(products, keys, scope, fq)
+	if page.Data == nil {
+		page.Data = []*Product{}
+	}
+
+	return page, nil
+}
+
+// FacetCount adalah jumlah produk untuk satu nilai facet
+type FacetCount struct {
+	Value string `json:"value"`
+	Label string `json:"label"`
+	Count int64  `json:"count"`
+}
+
+// PriceBucket adalah jumlah produk dengan harga efektif di rentang [Min, Max), Max nil berarti tanpa batas atas
+type PriceBucket struct {
+	Min   Money  `json:"min"`
+	Max   *Money `json:"max"`
+	Count int64  `json:"count"`
+}
+
+// RatingBucket adalah jumlah produk dengan rata-rata rating minimal MinRating
+type RatingBucket struct {
+	MinRating int   `json:"min_rating"`
+	Count     int64 `json:"count"`
+}
+
+// ProductFacets adalah jumlah produk per pilihan filter untuk sidebar katalog
+type ProductFacets struct {
+	Categories   []FacetCount   `json:"categories"`
+	PriceBuckets []PriceBucket  `json:"price_buckets"`
+	Ratings      []RatingBucket `json:"ratings"`
+	Countries    []FacetCount   `json:"countries"`
+}
+
+// GetProductFacets menghitung facet katalog untuk filter. Setiap facet dihitung tanpa filternya sendiri
+// agar pembeli bisa berpindah pilihan, misalnya melihat jumlah produk kategori lain saat satu kategori dipilih.
+func (s *ProductStore) GetProductFacets(ctx context.Context, f ProductFilter) (*ProductFacets, error) {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	facets := &ProductFacets{}
+
+	var err error
+	if facets.Categories, err = s.groupFacet(ctx, f, facetCategory, "c.slug", "c.name"); err != nil {
+		return nil, err
+	}
+
+	if facets.Countries, err = s.groupFacet(ctx, f, facetCountry, "t.country", "t.country"); err != nil {
+		return nil, err
+	}
+
+	if facets.PriceBuckets, err = s.priceFacet(ctx, f); err != nil {
+		return nil, err
+	}
+
+	if facets.Ratings, err = s.ratingFacet(ctx, f); err != nil {
+		return nil, err
+	}
+
+	return facets, nil
+}
+
+// groupFacet menghitung produk per nilai kolom value
+func (s *ProductStore) groupFacet(ctx context.Context, f ProductFilter, facet productFacet, value, label string) ([]FacetCount, error) {
+	b := &sqlFilter{}
+	f.apply(b, facet)
+
+	query := `SELECT ` + value + `, ` + label + `, COUNT(*)
+	` + productFilterFrom + `
+	` + b.clause() + `
+	GROUP BY ` + value + `, ` + label + `
+	ORDER BY COUNT(*) DESC, ` + label
+
+	rows, err := s.db.QueryContext(ctx, query, b.args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	counts := []FacetCount{}
+	for rows.Next() {
+		var fc FacetCount
+		if err := rows.Scan(&fc.Value, &fc.Label, &fc.Count); err != nil {
+			return nil, err
+		}
+		counts = append(counts, fc)
+	}
+
+	return counts, rows.Err()
+}
+
+// priceFacet menghitung produk per rentang PriceBucketBounds, rentang kosong tetap dikembalikan
+func (s *ProductStore) priceFacet(ctx context.Context, f ProductFilter) ([]PriceBucket, error) {
+	b := &sqlFilter{}
+	f.apply(b, facetPrice)
+
+	query := `SELECT width_bucket(` + productEffectivePrice + `, ` + b.arg(pq.Array(PriceBucketBounds)) + `::bigint[]) AS bucket, COUNT(*)
+	` + productFilterFrom + `
+	` + b.clause() + `
+	GROUP BY bucket`
+
+	rows, err := s.db.QueryContext(ctx, query, b.args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	buckets := make([]PriceBucket, len(PriceBucketBounds)+1)
+	for i := range buckets {
+		if i > 0 {
+			buckets[i].Min = IDR(PriceBucketBounds[i-1])
+		} else {
+			buckets[i].Min = IDR(0)
+		}
+		if i < len(PriceBucketBounds) {
+			upper := IDR(PriceBucketBounds[i])
+			buckets[i].Max = &upper
+		}
+	}
+
+	for rows.Next() {
+		var bucket int
+		var count int64
+		if err := rows.Scan(&bucket, &count); err != nil {
+			return nil, err
+		}
+		if bucket >= 0 && bucket < len(buckets) {
+			buckets[bucket].Count = count
+		}
+	}
+
+	return buckets, rows.Err()
+}
+
+// ratingFacet menghitung produk dengan rata-rata rating minimal 5, 4, 3, 2 dan 1, sesuai nilai filter rating
+func (s *ProductStore) ratingFacet(ctx context.Context, f ProductFilter) ([]RatingBucket, error) {
+	b := &sqlFilter{}
+	f.apply(b, facetRating)
+
+	query := `SELECT
+		COUNT(*) FILTER (WHERE COALESCE(pr.rating, 0) >= 5),
+		COUNT(*) FILTER (WHERE COALESCE(pr.rating, 0) >= 4),
+		COUNT(*) FILTER (WHERE COALESCE(pr.rating, 0) >= 3),
+		COUNT(*) FILTER (WHERE COALESCE(pr.rating, 0) >= 2),
+		COUNT(*) FILTER (WHERE COALESCE(pr.rating, 0) >= 1)
+	` + productFilterFrom + `
+	` + b.clause()
+
+	buckets := []RatingBucket{{MinRating: 5}, {MinRating: 4}, {MinRating: 3}, {MinRating: 2}, {MinRating: 1}}
+	err := s.db.QueryRowContext(ctx, query, b.args...).Scan(
+		&buckets[0].Count, &buckets[1].Count, &buckets[2].Count, &buckets[3].Count, &buckets[4].Count,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return buckets, nil
+}
@@ -0,0 +1,324 @@
+package store
+
+import (
+	"context"
+	"crypto/sha1"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// FeedWeights adalah bobot setiap sinyal skor feed personal. Setiap sinyal dinormalisasi ke 0..1
+// sehingga skor produk adalah rata-rata berbobot dari sinyal-sinyal tersebut.
+type FeedWeights struct {
+	Bought     float64 // kategori yang pernah dibeli user
+	Followed   float64 // toko milik user yang diikuti
+	Viewed     float64 // kategori produk yang baru dilihat user
+	Trending   float64 // jumlah terjual dalam FeedTrendingWindow
+	Popularity float64 // total terjual sepanjang waktu
+}
+
+var DefaultFeedWeights = FeedWeights{
+	Bought:     0.30,
+	Followed:   0.25,
+	Viewed:     0.20,
+	Trending:   0.15,
+	Popularity: 0.10,
+}
+
+const (
+	// FeedTrendingWindow adalah rentang order item yang dihitung sebagai trending
+	FeedTrendingWindow = "7 days"
+
+	// FeedSnapshotTTL adalah masa berlaku urutan feed yang dibekukan untuk cursor,
+	// harus lebih lama dari cache halaman feed agar cursor di halaman cache masih bisa dipakai
+	FeedSnapshotTTL = 30 * time.Minute
+
+	// FeedSnapshotReuse adalah umur maksimal snapshot yang dipakai ulang untuk halaman pertama user dan filter
+	// yang sama, sehingga pengunjung anonim berbagi satu snapshot dan sisa umurnya cukup untuk cursor
+	FeedSnapshotReuse = 5 * time.Minute
+
+	// feedRecentViews adalah jumlah produk terakhir dilihat yang dipakai sebagai sinyal
+	feedRecentViews = 50
+
+	// feedCandidates adalah jumlah maksimal produk dari setiap sumber kandidat feed
+	feedCandidates = 200
+
+	// feedSnapshotSize adalah jumlah produk dengan skor tertinggi yang disimpan di snapshot feed
+	feedSnapshotSize = 500
+)
+
+// productFeedScores menghitung skor feed untuk user :u. Hanya kandidat yang diskor: produk terlaris dari
+// kategori yang pernah dibeli atau dilihat, dari toko yang diikuti, produk trending dan produk terlaris.
+// User anonim (id 0) tidak punya riwayat beli, follow maupun lihat, sehingga skornya hanya dari trending dan popularitas.
+const productFeedScores = `WITH
+	bought AS (
+		SELECT bp.category_id, COUNT(*) AS n
+		FROM order_items oi
+		JOIN orders o ON o.id = oi.order_id
+		JOIN products bp ON bp.id = oi.product_id
+		WHERE o.user_id = :u
+		GROUP BY bp.category_id
+	),
+	followed AS (
+		SELECT ft.id AS toko_id
+		FROM followers f
+		JOIN tokos ft ON ft.user_id = f.user_id
+		WHERE f.follower_id = :u
+	),
+	viewed AS (
+		SELECT vp.category_id
+		FROM product_views pv
+		JOIN products vp ON vp.id = pv.product_id
+		WHERE pv.user_id = :u
+		ORDER BY pv.viewed_at DESC
+		LIMIT :views
+	),
+	trending AS (
+		SELECT oi.product_id, SUM(oi.quantity) AS qty
+		FROM order_items oi
+		WHERE oi.created_at > now() - :window::interval
+		GROUP BY oi.product_id
+		ORDER BY qty DESC
+		LIMIT :candidates
+	),
+	candidates AS (
+		(SELECT cp.id FROM products cp
+		WHERE cp.is_approved = true
+			AND (cp.category_id IN (SELECT category_id FROM bought) OR cp.category_id IN (SELECT category_id FROM viewed))
+		ORDER BY cp.sold DESC, cp.id DESC
+		LIMIT :candidates)
+		UNION
+		(SELECT cp.id FROM products cp
+		JOIN followed fl ON fl.toko_id = cp.toko_id
+		WHERE cp.is_approved = true
+		ORDER BY cp.sold DESC, cp.id DESC
+		LIMIT :candidates)
+		UNION
+		SELECT product_id FROM trending
+		UNION
+		(SELECT cp.id FROM products cp
+		WHERE cp.is_approved = true
+		ORDER BY cp.sold DESC, cp.id DESC
+		LIMIT :candidates)
+	),
+	feed_max AS (
+		SELECT
+			(SELECT MAX(n) FROM bought)::float8 AS bought,
+			(SELECT ln(1 + MAX(qty)) FROM trending)::float8 AS trending,
+			(SELECT ln(1 + MAX(sold)) FROM products WHERE is_approved = true)::float8 AS sold
+	),
+	feed_scores AS (
+		SELECT sp.id AS product_id,
+			:w_bought * COALESCE(b.n / NULLIF(m.bought, 0), 0)
+			+ :w_followed * (CASE WHEN sp.toko_id IN (SELECT toko_id FROM followed) THEN 1 ELSE 0 END)
+			+ :w_viewed * (CASE WHEN sp.category_id IN (SELECT category_id FROM viewed) THEN 1 ELSE 0 END)
+			+ :w_trending * COALESCE(ln(1 + tr.qty) / NULLIF(m.trending, 0), 0)
+			+ :w_popularity * COALESCE(ln(1 + GREATEST(sp.sold, 0)) / NULLIF(m.sold, 0), 0) AS score
+		FROM candidates cd
+		JOIN products sp ON sp.id = cd.id
+		CROSS JOIN feed_max m
+		LEFT JOIN bought b ON b.category_id = sp.category_id
+		LEFT JOIN trending tr ON tr.product_id = sp.id
+		WHERE sp.is_approved = true
+	)
+`
+
+const productFeedScoresJoin = `
+	JOIN feed_scores fs ON fs.product_id = p.id`
+
+// feedScores menambahkan parameter skor feed user ke builder dan mengembalikan klausa WITH-nya
+func feedScores(b *sqlFilter, userID int64, w FeedWeights) string {
+	r := strings.NewReplacer(
+		":u", b.arg(userID),
+		":views", b.arg(feedRecentViews),
+		":candidates", b.arg(feedCandidates),
+		":window", b.arg(FeedTrendingWindow),
+		":w_bought", b.arg(w.Bought)+"::float8",
+		":w_followed", b.arg(w.Followed)+"::float8",
+		":w_viewed", b.arg(w.Viewed)+"::float8",
+		":w_trending", b.arg(w.Trending)+"::float8",
+		":w_popularity", b.arg(w.Popularity)+"::float8",
+	)
+	return r.Replace(productFeedScores)
+}
+
+// feedSnapshotJoin menambahkan parameter snapshot ke builder dan mengembalikan JOIN skor yang dibekukan,
+// alias fs sama dengan feed_scores sehingga order key SortForYou tetap berlaku
+func feedSnapshotJoin(b *sqlFilter, snapshotID uuid.UUID) string {
+	return `
+	JOIN feed_snapshot_scores fs ON fs.product_id = p.id AND fs.snapshot_id = ` + b.arg(snapshotID)
+}
+
+// feedSnapshotKey membedakan snapshot feed per filter
+func feedSnapshotKey(f ProductFilter) string {
+	data, _ := json.Marshal(f)
+	sum := sha1.Sum(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// feedSnapshotTx mengembalikan snapshot user dan filter yang sama yang dibuat dalam FeedSnapshotReuse,
+// atau membuat snapshot baru. Advisory lock mencegah request bersamaan membuat snapshot ganda.
+func feedSnapshotTx(ctx context.Context, tx *sql.Tx, userID int64, f ProductFilter) (uuid.UUID, error) {
+	key := feedSnapshotKey(f)
+
+	_, err := tx.ExecContext(ctx, `SELECT pg_advisory_xact_lock(hashtext($1))`,
+		"feed_snapshot:"+strconv.FormatInt(userID, 10)+":"+key)
+	if err != nil {
+		return uuid.Nil, err
+	}
+
+	var id uuid.UUID
+	err = tx.QueryRowContext(ctx, `
+		SELECT id FROM feed_snapshots
+		WHERE user_id = $1 AND filter_key = $2 AND created_at > $3 AND expires_at > now()
+		ORDER BY created_at DESC
+		LIMIT 1`,
+		userID, key, time.Now().Add(-FeedSnapshotReuse),
+	).Scan(&id)
+	switch {
+	case err == nil:
+		return id, nil
+	case errors.Is(err, sql.ErrNoRows):
+		return createFeedSnapshotTx(ctx, tx, userID, key, f)
+	default:
+		return uuid.Nil, err
+	}
+}
+
+// createFeedSnapshotTx menghitung skor kandidat feed user yang lolos filter dan menyimpan produk
+// dengan skor tertinggi sebagai snapshot, halaman berikutnya dibaca dari snapshot yang sama
+func createFeedSnapshotTx(ctx context.Context, tx *sql.Tx, userID int64, key string, f ProductFilter) (uuid.UUID, error) {
+	id := uuid.New()
+
+	_, err := tx.ExecContext(ctx,
+		`INSERT INTO feed_snapshots (id, user_id, filter_key, expires_at) VALUES ($1, $2, $3, $4)`,
+		id, userID, key, time.Now().Add(FeedSnapshotTTL))
+	if err != nil {
+		return uuid.Nil, err
+	}
+
+	b := &sqlFilter{}
+	f.apply(b, facetNone)
+	with := feedScores(b, userID, DefaultFeedWeights)
+
+	query := with + `INSERT INTO feed_snapshot_scores (snapshot_id, product_id, score)
+	SELECT ` + b.arg(id) + `::uuid, p.id, fs.score
+	` + productFilterFrom + productFeedScoresJoin + `
+	` + b.clause() + `
+	ORDER BY fs.score DESC, p.id DESC
+	LIMIT ` + b.arg(feedSnapshotSize)
+
+	if _, err := tx.ExecContext(ctx, query, b.args...); err != nil {
+		return uuid.Nil, err
+	}
+
+	return id, nil
+}
+
+// feedSnapshotFromCursor membaca snapshot dari scope cursor yang diawali prefix. Snapshot milik user
+// lain atau yang sudah kadaluarsa membuat cursor tidak valid.
+func feedSnapshotFromCursor(ctx context.Context, tx *sql.Tx, userID int64, prefix string, cur *Cursor) (uuid.UUID, error) {
+	if !strings.HasPrefix(cur.Scope, prefix) {
+		return uuid.Nil, ErrInvalidCursor
+	}
+
+	id, err := uuid.Parse(strings.TrimPrefix(cur.Scope, prefix))
+	if err != nil {
+		return uuid.Nil, ErrInvalidCursor
+	}
+
+	var exists bool
+	err = tx.QueryRowContext(ctx,
+		`SELECT EXISTS (SELECT 1 FROM feed_snapshots WHERE id = $1 AND user_id = $2 AND expires_at > now())`,
+		id, userID,
+	).Scan(&exists)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	if !exists {
+		return uuid.Nil, ErrInvalidCursor
+	}
+
+	return id, nil
+}
+
+// GetPersonalizedFeed mengambil feed produk yang diurutkan skor personal user. userID 0 untuk
+// pengunjung anonim, feed-nya diurutkan dari produk trending dan terlaris. Halaman pertama membekukan
+// skor di snapshot dan cursor-nya terikat ke snapshot tersebut, sehingga produk yang baru dilihat
+// atau dibeli tidak mengubah urutan halaman berikutnya. Snapshot dipakai ulang untuk user dan filter
+// yang sama selama FeedSnapshotReuse agar halaman pertama tidak selalu menulis snapshot baru.
+func (s *ProductStore) GetPersonalizedFeed(ctx context.Context, userID int64, fq PaginatedFeedQuery) (*Paginated[*Product], error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	filter := NewProductFilter(fq)
+
+	skip := fq.Offset * (fq.Limit)
+	order, scope := productOrder(fq, SortForYou)
+	scope += ":" + strconv.FormatInt(userID, 10) + ":" // skor berbeda per user, cursor tidak bisa dipakai user lain
+
+	var snapshotID uuid.UUID
+	if fq.Position != nil {
+		snapshotID, err = feedSnapshotFromCursor(ctx, tx, userID, scope, fq.Position)
+	} else {
+		snapshotID, err = feedSnapshotTx(ctx, tx, userID, filter)
+	}
+	if err != nil {
+		return nil, err
+	}
+	filter.feedSnapshot = &snapshotID
+	scope += snapshotID.String()
+
+	page, err := listProducts(ctx, tx, filter, order, scope, fq, skip)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.commentsForProducts(ctx, tx, page.Data)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return page, nil
+}
+
+// DeleteExpiredFeedSnapshots menghapus snapshot feed yang sudah kadaluarsa beserta skornya
+func (s *ProductStore) DeleteExpiredFeedSnapshots(ctx context.Context) (int64, error) {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	res, err := s.db.ExecContext(ctx, `DELETE FROM feed_snapshots WHERE expires_at <= now()`)
+	if err != nil {
+		return 0, err
+	}
+
+	return res.RowsAffected()
+}
+
+// RecordView mencatat user melihat produk, kunjungan ulang memperbarui waktu lihat terakhir
+func (s *ProductStore) RecordView(ctx context.Context, userID, productID int64) error {
+	query := `
+		INSERT INTO product_views (user_id, product_id) VALUES ($1, $2)
+		ON CONFLICT (user_id, product_id)
+		DO UPDATE SET view_count = product_views.view_count + 1, viewed_at = now()`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	_, err := s.db.ExecContext(ctx, query, userID, productID)
+	return err
+}
@@ -0,0 +1,24 @@
+package store
+
+import "strings"
+
+// Potongan SQL pencarian produk, :q diganti placeholder parameter kata kunci.
+// Kata kunci kosong mencocokkan semua produk dengan rank 0 sehingga urutan feed tidak berubah.
+const (
+	productSearchQuery = `(websearch_to_tsquery('indonesian', :q) || websearch_to_tsquery('english', :q))`
+
+	productSearchMatchTemplate = `(:q = '' OR p.search_vector @@ ` + productSearchQuery + ` OR p.name % :q)`
+
+	productSearchRankTemplate = `CASE WHEN :q = '' THEN 0
+		ELSE ts_rank_cd(p.search_vector, ` + productSearchQuery + `) + similarity(p.name, :q) END`
+
+	productSearchSnippetTemplate = `CASE WHEN :q = '' THEN ''
+		ELSE ts_headline('indonesian', coalesce(p.description, ''), ` + productSearchQuery + `,
+			'StartSel=<mark>, StopSel=</mark>, MaxWords=25, MinWords=10, MaxFragments=2, FragmentDelimiter=" … "') END`
+)
+
+// productSearch mengembalikan kondisi WHERE, ekspresi relevansi dan snippet untuk kata kunci di placeholder param
+func productSearch(param string) (match, rank, snippet string) {
+	r := strings.NewReplacer(":q", param)
+	return r.Replace(productSearchMatchTemplate), r.Replace(productSearchRankTemplate), r.Replace(productSearchSnippetTemplate)
+}
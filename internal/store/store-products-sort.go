@@ -0,0 +1,91 @@
+package store
+
+import "strings"
+
+// ProductSort adalah urutan feed produk yang bisa dipilih lewat query sort
+type ProductSort string
+
+const (
+	SortRelevance   ProductSort = "relevance"
+	SortNewest      ProductSort = "newest"
+	SortPriceAsc    ProductSort = "price_asc"
+	SortPriceDesc   ProductSort = "price_desc"
+	SortRating      ProductSort = "rating"
+	SortBestSelling ProductSort = "best_selling"
+
+	// SortForYou mengurutkan dari skor feed personal, hanya tersedia di GetPersonalizedFeed
+	SortForYou ProductSort = "for_you"
+)
+
+// orderKey adalah satu kolom ORDER BY, expr hanya berasal dari whitelist di file ini
+type orderKey struct {
+	expr string
+	desc bool
+}
+
+func (k orderKey) String() string {
+	if k.desc {
+		return k.expr + " DESC"
+	}
+	return k.expr + " ASC"
+}
+
+// productSortKeys adalah whitelist urutan produk, nilai sort dari request tidak pernah masuk ke SQL.
+// :q pada ekspresi relevansi diganti placeholder kata kunci oleh listProducts.
+var productSortKeys = map[ProductSort]orderKey{
+	SortRelevance:   {expr: productSearchRankTemplate, desc: true},
+	SortNewest:      {expr: "p.created_at", desc: true},
+	SortPriceAsc:    {expr: productEffectivePrice},
+	SortPriceDesc:   {expr: productEffectivePrice, desc: true},
+	SortRating:      {expr: "COALESCE(pr.rating, 0)", desc: true},
+	SortBestSelling: {expr: "p.sold", desc: true},
+	SortForYou:      {expr: "fs.score", desc: true},
+}
+
+// productOrder menentukan urutan feed dari fq.Sort. Nilai lama asc dan desc mengurutkan kolom bawaan
+// endpoint (fallback) dengan arah tersebut, dan jika ada kata kunci hasil paling relevan tetap di atas.
+// Urutan selalu diakhiri p.id agar halaman berikutnya tidak mengulang atau melewatkan produk.
+// scope mengikat cursor ke urutan ini sehingga cursor dari urutan lain ditolak.
+func productOrder(fq PaginatedFeedQuery, fallback ProductSort) (order []orderKey, scope string) {
+	var keys []orderKey
+
+	switch fq.Sort {
+	case "", "asc", "desc":
+		if fq.Search != "" {
+			keys = append(keys, productSortKeys[SortRelevance])
+		}
+
+		key := productSortKeys[fallback]
+		if fq.Sort != "" {
+			key.desc = fq.Sort == "desc"
+		}
+		keys = append(keys, key)
+	case string(SortRelevance):
+		keys = append(keys, productSortKeys[SortRelevance], productSortKeys[fallback])
+	default:
+		key, ok := productSortKeys[ProductSort(fq.Sort)]
+		if !ok {
+			key = productSortKeys[fallback]
+		}
+		keys = append(keys, key)
+	}
+
+	keys = append(keys, orderKey{expr: "p.id", desc: keys[len(keys)-1].desc})
+	return keys, "products:" + string(fallback) + ":" + fq.Sort
+}
+
+func orderByClause(keys []orderKey) string {
+	parts := make([]string, len(keys))
+	for i, k := range keys {
+		parts[i] = k.String()
+	}
+	return strings.Join(parts, ", ")
+}
+
+// sortDirection mengubah sort asc atau desc menjadi arah ORDER BY, nilai lain dianggap desc
+func sortDirection(sort string) string {
+	if sort == "asc" {
+		return "ASC"
+	}
+	return "DESC"
+}
@@ -6,6 +6,7 @@ import (
 	"time"
 
 	"github.com/lib/pq"
+	"github.com/yogaprasetya22/api-gotokopedia/internal/pricing"
 )
 
 type Product struct {
@@ -14,8 +15,8 @@ type Product struct {
 	Slug          string    `json:"slug" `
 	Country       string    `json:"country" `
 	Description   string    `json:"description,omitempty" `
-	Price         float64   `json:"price" `
-	DiscountPrice float64   `json:"discount_price" `
+	Price         Money     `json:"price" `
+	DiscountPrice Money     `json:"discount_price" `
 	Discount      float64   `json:"discount" `
 	Rating        float64   `json:"rating" `
 	Estimation    string    `json:"estimation" `
@@ -27,8 +28,21 @@ type Product struct {
 	UpdatedAt     time.Time `json:"updated_at" `
 	ImageUrls     []string  `json:"image_urls" `
 	Version       int       `json:"version"`
+	WeightGram    int       `json:"weight_gram,omitempty"`
+	LengthCm      int       `json:"length_cm,omitempty"`
+	WidthCm       int       `json:"width_cm,omitempty"`
+	HeightCm      int       `json:"height_cm,omitempty"`
 	Category      *Category `json:"category" `
 	Toko          *Toko     `json:"toko" `
+
+	// Hasil pencarian, kosong jika feed tidak memakai kata kunci
+	SearchRank float64 `json:"search_rank,omitempty"`
+	Snippet    string  `json:"snippet,omitempty"`
+}
+
+// Pricing mengembalikan rincian harga produk untuk quantity unit
+func (p *Product) Pricing(quantity int64) pricing.Breakdown {
+	return pricing.Line(p.Price.Amount, p.DiscountPrice.Amount, p.Discount, quantity)
 }
 
 type ProductStore struct {
@@ -36,12 +50,12 @@ type ProductStore struct {
 }
 
 func (s *ProductStore) Create(ctx context.Context, p *Product) error {
-	const query = `INSERT INTO products (name, slug, country, description, price, discount_price, discount, estimation, stock, sold, is_for_sale, is_approved, image_urls, category_id, toko_id) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15) RETURNING id, created_at, updated_at`
+	const query = `INSERT INTO products (name, slug, country, description, price, discount_price, discount, estimation, stock, sold, is_for_sale, is_approved, image_urls, category_id, toko_id, weight_gram, length_cm, width_cm, height_cm) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19) RETURNING id, created_at, updated_at`
 
 	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
 	defer cancel()
 
-	err := s.db.QueryRowContext(ctx, query, p.Name, p.Slug, p.Country, p.Description, p.Price, p.DiscountPrice, p.Discount, p.Estimation, p.Stock, p.Sold, p.IsForSale, p.IsApproved, pq.Array(p.ImageUrls), p.Category.ID, p.Toko.ID).Scan(&p.ID, &p.CreatedAt, &p.UpdatedAt)
+	err := s.db.QueryRowContext(ctx, query, p.Name, p.Slug, p.Country, p.Description, p.Price, p.DiscountPrice, p.Discount, p.Estimation, p.Stock, p.Sold, p.IsForSale, p.IsApproved, pq.Array(p.ImageUrls), p.Category.ID, p.Toko.ID, p.WeightGram, p.LengthCm, p.WidthCm, p.HeightCm).Scan(&p.ID, &p.CreatedAt, &p.UpdatedAt)
 	if err != nil {
 		return err
 	}
@@ -49,53 +63,16 @@ func (s *ProductStore) Create(ctx context.Context, p *Product) error {
 	return nil
 }
 
-func (s *ProductStore) GetAllProduct(ctx context.Context, fq PaginatedFeedQuery) ([]*Product, error) {
-	query := `SELECT p.id, p.name, p.slug, p.country, p.description, p.price, p.discount_price, p.discount, p.estimation, p.stock, p.sold, p.is_for_sale, p.is_approved, p.created_at, p.updated_at, p.image_urls, 
-               c.id, c.name, c.slug, 
-               t.id, t.user_id, t.slug, t.name, t.country, t.created_at, 
-               u.id, u.username, u.email, u.created_at, u.is_active
-        FROM products p
-        JOIN category c ON p.category_id = c.id
-        JOIN tokos t ON p.toko_id = t.id
-        JOIN users u ON t.user_id = u.id
-        WHERE p.is_approved = true
-        ORDER BY p.sold DESC
-        LIMIT $1 OFFSET $2`
-
+func (s *ProductStore) GetAllProduct(ctx context.Context, fq PaginatedFeedQuery) (*Paginated[*Product], error) {
 	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
 	defer cancel()
 
-	rows, err := s.db.QueryContext(ctx, query, fq.Limit, fq.Offset)
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
-
-	var products []*Product
-	for rows.Next() {
-		p := &Product{
-			Category: &Category{},
-			Toko:     &Toko{User: &SingleUser{}},
-		}
-		err := rows.Scan(&p.ID, &p.Name, &p.Slug, &p.Country, &p.Description, &p.Price, &p.DiscountPrice, &p.Discount, &p.Estimation, &p.Stock, &p.Sold, &p.IsForSale, &p.IsApproved, &p.CreatedAt, &p.UpdatedAt, pq.Array(&p.ImageUrls),
-			&p.Category.ID, &p.Category.Name, &p.Category.Slug,
-			&p.Toko.ID, &p.Toko.UserID, &p.Toko.Slug, &p.Toko.Name, &p.Toko.Country, &p.Toko.CreatedAt,
-			&p.Toko.User.ID, &p.Toko.User.Username, &p.Toko.User.Email, &p.Toko.User.CreatedAt, &p.Toko.User.IsActive)
-		if err != nil {
-			return nil, err
-		}
-		products = append(products, p)
-	}
-
-	if err := rows.Err(); err != nil {
-		return nil, err
-	}
-
-	return products, nil
+	order, scope := productOrder(fq, SortBestSelling)
+	return listProducts(ctx, s.db, NewProductFilter(fq), order, scope, fq, fq.Offset)
 }
 
 func (s *ProductStore) GetByID(ctx context.Context, id int64) (*Product, error) {
-	const query = `SELECT id, name, slug, country, description, price, discount_price, discount, estimation, stock, sold, is_for_sale, is_approved, created_at, updated_at, image_urls, category_id, toko_id , version FROM products WHERE id = $1`
+	const query = `SELECT id, name, slug, country, description, price, discount_price, discount, estimation, stock, sold, is_for_sale, is_approved, created_at, updated_at, image_urls, category_id, toko_id , version, weight_gram, length_cm, width_cm, height_cm FROM products WHERE id = $1`
 
 	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
 	defer cancel()
@@ -104,7 +81,7 @@ func (s *ProductStore) GetByID(ctx context.Context, id int64) (*Product, error)
 			Category: &Category{},
 			Toko:     &Toko{User: &SingleUser{}},
 		}
-	err := s.db.QueryRowContext(ctx, query, id).Scan(&product.ID, &product.Name, &product.Slug, &product.Country, &product.Description, &product.Price, &product.DiscountPrice, &product.Discount, &product.Estimation, &product.Stock, &product.Sold, &product.IsForSale, &product.IsApproved, &product.CreatedAt, &product.UpdatedAt, pq.Array(&product.ImageUrls), &product.Category.ID, &product.Toko.ID, &product.Version)
+	err := s.db.QueryRowContext(ctx, query, id).Scan(&product.ID, &product.Name, &product.Slug, &product.Country, &product.Description, &product.Price, &product.DiscountPrice, &product.Discount, &product.Estimation, &product.Stock, &product.Sold, &product.IsForSale, &product.IsApproved, &product.CreatedAt, &product.UpdatedAt, pq.Array(&product.ImageUrls), &product.Category.ID, &product.Toko.ID, &product.Version, &product.WeightGram, &product.LengthCm, &product.WidthCm, &product.HeightCm)
 	if err != nil {
 		switch {
 		case err == sql.ErrNoRows:
@@ -118,12 +95,12 @@ func (s *ProductStore) GetByID(ctx context.Context, id int64) (*Product, error)
 }
 
 func (s *ProductStore) Update(ctx context.Context, product *Product) error {
-	query := `UPDATE products SET name = $1, slug = $2, country = $3, description = $4, price = $5, discount_price = $6, discount = $7, estimation = $8, stock = $9, sold = $10, is_for_sale = $11, is_approved = $12, image_urls = $13, category_id = $14, toko_id = $15, version = version + 1 WHERE id = $16 AND version = $17 RETURNING updated_at`
+	query := `UPDATE products SET name = $1, slug = $2, country = $3, description = $4, price = $5, discount_price = $6, discount = $7, estimation = $8, stock = $9, sold = $10, is_for_sale = $11, is_approved = $12, image_urls = $13, category_id = $14, toko_id = $15, weight_gram = $16, length_cm = $17, width_cm = $18, height_cm = $19, version = version + 1 WHERE id = $20 AND version = $21 RETURNING updated_at`
 
 	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
 	defer cancel()
 
-	err := s.db.QueryRowContext(ctx, query, product.Name, product.Slug, product.Country, product.Description, product.Price, product.DiscountPrice, product.Discount, product.Estimation, product.Stock, product.Sold, product.IsForSale, product.IsApproved, pq.Array(product.ImageUrls), product.Category.ID, product.Toko.ID, product.ID, product.Version).Scan(&product.UpdatedAt)
+	err := s.db.QueryRowContext(ctx, query, product.Name, product.Slug, product.Country, product.Description, product.Price, product.DiscountPrice, product.Discount, product.Estimation, product.Stock, product.Sold, product.IsForSale, product.IsApproved, pq.Array(product.ImageUrls), product.Category.ID, product.Toko.ID, product.WeightGram, product.LengthCm, product.WidthCm, product.HeightCm, product.ID, product.Version).Scan(&product.UpdatedAt)
 	if err != nil {
 		switch {
 		case err == sql.ErrNoRows:
@@ -0,0 +1,143 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+)
+
+const (
+	RegionLevelProvince    = 1
+	RegionLevelCity        = 2
+	RegionLevelDistrict    = 3
+	RegionLevelSubDistrict = 4
+)
+
+// Region adalah satu wilayah di referensi provinsi > kota/kabupaten > kecamatan > kelurahan
+type Region struct {
+	ID         string   `json:"id"`
+	ParentID   *string  `json:"parent_id,omitempty"`
+	Level      int      `json:"level"`
+	Name       string   `json:"name"`
+	PostalCode string   `json:"postal_code,omitempty"`
+	Latitude   *float64 `json:"latitude,omitempty"`
+	Longitude  *float64 `json:"longitude,omitempty"`
+}
+
+type RegionStore struct {
+	db *sql.DB
+}
+
+const regionColumns = `id, parent_id, level, name, COALESCE(postal_code, ''), latitude, longitude`
+
+// GetByID mendapatkan satu wilayah berdasarkan kode wilayah
+func (s *RegionStore) GetByID(ctx context.Context, id string) (*Region, error) {
+	query := `SELECT ` + regionColumns + ` FROM regions WHERE id = $1`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	region, err := scanRegion(s.db.QueryRowContext(ctx, query, id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+
+	return region, nil
+}
+
+// GetChildren mendapatkan wilayah di bawah parentID, parentID kosong berarti daftar provinsi
+func (s *RegionStore) GetChildren(ctx context.Context, parentID string) ([]*Region, error) {
+	query := `SELECT ` + regionColumns + ` FROM regions WHERE parent_id = $1 ORDER BY name`
+	args := []any{parentID}
+	if parentID == "" {
+		query = `SELECT ` + regionColumns + ` FROM regions WHERE parent_id IS NULL ORDER BY name`
+		args = nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	regions := []*Region{}
+	for rows.Next() {
+		region, err := scanRegion(rows)
+		if err != nil {
+			return nil, err
+		}
+		regions = append(regions, region)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return regions, nil
+}
+
+// GetPath mendapatkan rantai wilayah dari provinsi sampai wilayah id
+func (s *RegionStore) GetPath(ctx context.Context, id string) ([]*Region, error) {
+	query := `
+		WITH RECURSIVE path AS (
+			SELECT ` + regionColumns + ` FROM regions WHERE id = $1
+			UNION ALL
+			SELECT r.id, r.parent_id, r.level, r.name, COALESCE(r.postal_code, ''), r.latitude, r.longitude
+			FROM regions r
+			JOIN path p ON r.id = p.parent_id
+		)
+		SELECT * FROM path ORDER BY level`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var path []*Region
+	for rows.Next() {
+		region, err := scanRegion(rows)
+		if err != nil {
+			return nil, err
+		}
+		path = append(path, region)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(path) == 0 {
+		return nil, ErrNotFound
+	}
+
+	return path, nil
+}
+
+func scanRegion(row interface{ Scan(...any) error }) (*Region, error) {
+	region := &Region{}
+	var parentID sql.NullString
+	var lat, lng sql.NullFloat64
+	if err := row.Scan(&region.ID, &parentID, &region.Level, &region.Name, &region.PostalCode, &lat, &lng); err != nil {
+		return nil, err
+	}
+
+	if parentID.Valid {
+		region.ParentID = &parentID.String
+	}
+	if lat.Valid && lng.Valid {
+		region.Latitude = &lat.Float64
+		region.Longitude = &lng.Float64
+	}
+
+	return region, nil
+}
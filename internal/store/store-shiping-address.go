@@ -18,7 +18,15 @@ type ShippingAddresses struct {
 	RecipientName  string    `json:"recipient_name"`
 	RecipientPhone string    `json:"recipient_phone"`
 	AddressLine1   string    `json:"address_line1"`
+	RegionID       string    `json:"region_id,omitempty"`
+	Province       string    `json:"province,omitempty"`
+	City           string    `json:"city,omitempty"`
+	District       string    `json:"district,omitempty"`
+	SubDistrict    string    `json:"sub_district,omitempty"`
+	PostalCode     string    `json:"postal_code,omitempty"`
 	NoteForCourier string    `json:"note_for_courier,omitempty"`
+	Latitude       *float64  `json:"latitude,omitempty"`
+	Longitude      *float64  `json:"longitude,omitempty"`
 	CreatedAt      time.Time `json:"created_at"`
 	UpdatedAt      time.Time `json:"updated_at"`
 }
@@ -44,13 +52,21 @@ func (s *ShippingAddresStore) SetDefaultAddress(ctx context.Context, addressID u
 	}
 
 	// 2. Set alamat yang dipilih sebagai default
-	_, err = tx.ExecContext(ctx,
-		`UPDATE shipping_addresses SET is_default = true WHERE id = $1 AND user_id = $2`,
+	res, err := tx.ExecContext(ctx,
+		`UPDATE shipping_addresses SET is_default = true WHERE id = $1 AND user_id = $2 AND deleted_at IS NULL`,
 		addressID, userID)
 	if err != nil {
 		return err
 	}
 
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return ErrNotFound
+	}
+
 	// 3. Update referensi di tabel users
 	_, err = tx.ExecContext(ctx,
 		`UPDATE users SET default_shipping_address_id = $1 WHERE id = $2`,
@@ -65,9 +81,10 @@ func (s *ShippingAddresStore) SetDefaultAddress(ctx context.Context, addressID u
 // GetDefaultAddress mengambil alamat default user
 func (s *ShippingAddresStore) GetDefaultAddress(ctx context.Context, userID int64) (*ShippingAddresses, error) {
 	query := `SELECT id, user_id, label, recipient_name, recipient_phone, 
-                     address_line1, note_for_courier, is_default, created_at, updated_at 
+                     address_line1, COALESCE(region_id, ''), province, city, district, sub_district, postal_code,
+                     note_for_courier, latitude, longitude, is_default, created_at, updated_at 
               FROM shipping_addresses 
-              WHERE user_id = $1 AND is_default = true`
+              WHERE user_id = $1 AND is_default = true AND deleted_at IS NULL`
 
 	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
 	defer cancel()
@@ -77,7 +94,8 @@ func (s *ShippingAddresStore) GetDefaultAddress(ctx context.Context, userID int6
 	err := s.db.QueryRowContext(ctx, query, userID).Scan(
 		&sa.ID, &sa.UserID, &sa.Label,
 		&sa.RecipientName, &sa.RecipientPhone, &sa.AddressLine1,
-		&noteForCourier,
+		&sa.RegionID, &sa.Province, &sa.City, &sa.District, &sa.SubDistrict, &sa.PostalCode,
+		&noteForCourier, &sa.Latitude, &sa.Longitude,
 		&sa.IsDefault, &sa.CreatedAt, &sa.UpdatedAt)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
@@ -96,8 +114,9 @@ func (s *ShippingAddresStore) GetDefaultAddress(ctx context.Context, userID int6
 
 func (s *ShippingAddresStore) GetByID(ctx context.Context, id uuid.UUID, userID int64) (*ShippingAddresses, error) {
 	query := `SELECT id, user_id, label, recipient_name, recipient_phone, 
-                     address_line1, note_for_courier, is_default, created_at, updated_at 
-              FROM shipping_addresses WHERE id = $1 AND user_id = $2`
+                     address_line1, COALESCE(region_id, ''), province, city, district, sub_district, postal_code,
+                     note_for_courier, latitude, longitude, is_default, created_at, updated_at 
+              FROM shipping_addresses WHERE id = $1 AND user_id = $2 AND deleted_at IS NULL`
 
 	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
 	defer cancel()
@@ -106,8 +125,13 @@ func (s *ShippingAddresStore) GetByID(ctx context.Context, id uuid.UUID, userID
 	err := s.db.QueryRowContext(ctx, query, id, userID).Scan(
 		&sa.ID, &sa.UserID, &sa.Label,
 		&sa.RecipientName, &sa.RecipientPhone, &sa.AddressLine1,
-		&sa.NoteForCourier, &sa.IsDefault, &sa.CreatedAt, &sa.UpdatedAt)
+		&sa.RegionID, &sa.Province, &sa.City, &sa.District, &sa.SubDistrict, &sa.PostalCode,
+		&sa.NoteForCourier, &sa.Latitude, &sa.Longitude,
+		&sa.IsDefault, &sa.CreatedAt, &sa.UpdatedAt)
 	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNotFound
+		}
 		return nil, err
 	}
 
@@ -116,9 +140,10 @@ func (s *ShippingAddresStore) GetByID(ctx context.Context, id uuid.UUID, userID
 
 func (s *ShippingAddresStore) ListByUser(ctx context.Context, userID int64) ([]*ShippingAddresses, error) {
 	query := `SELECT id, user_id, label, recipient_name, recipient_phone, 
-                     address_line1, note_for_courier, is_default, created_at, updated_at 
+                     address_line1, COALESCE(region_id, ''), province, city, district, sub_district, postal_code,
+                     note_for_courier, latitude, longitude, is_default, created_at, updated_at 
               FROM shipping_addresses 
-              WHERE user_id = $1 
+              WHERE user_id = $1 AND deleted_at IS NULL
               ORDER BY is_default DESC, created_at DESC`
 
 	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
@@ -136,7 +161,9 @@ func (s *ShippingAddresStore) ListByUser(ctx context.Context, userID int64) ([]*
 		err := rows.Scan(
 			&sa.ID, &sa.UserID, &sa.Label,
 			&sa.RecipientName, &sa.RecipientPhone, &sa.AddressLine1,
-			&sa.NoteForCourier, &sa.IsDefault, &sa.CreatedAt, &sa.UpdatedAt)
+			&sa.RegionID, &sa.Province, &sa.City, &sa.District, &sa.SubDistrict, &sa.PostalCode,
+			&sa.NoteForCourier, &sa.Latitude, &sa.Longitude,
+			&sa.IsDefault, &sa.CreatedAt, &sa.UpdatedAt)
 		if err != nil {
 			return nil, err
 		}
@@ -157,7 +184,7 @@ func (s *ShippingAddresStore) Create(ctx context.Context, sa *ShippingAddresses)
 	// 1. Cek apakah ini alamat pertama user
 	var count int
 	err = tx.QueryRowContext(ctx,
-		`SELECT COUNT(*) FROM shipping_addresses WHERE user_id = $1`,
+		`SELECT COUNT(*) FROM shipping_addresses WHERE user_id = $1 AND deleted_at IS NULL`,
 		sa.UserID).Scan(&count)
 	if err != nil {
 		return fmt.Errorf("failed to count user addresses: %w", err)
@@ -182,8 +209,9 @@ func (s *ShippingAddresStore) Create(ctx context.Context, sa *ShippingAddresses)
 	// 4. Insert alamat baru
 	query := `INSERT INTO shipping_addresses 
               (user_id, label, recipient_name, recipient_phone, 
-               address_line1, note_for_courier, is_default) 
-              VALUES ($1, $2, $3, $4, $5, $6, $7) 
+               address_line1, region_id, province, city, district, sub_district, postal_code,
+               note_for_courier, latitude, longitude, is_default) 
+              VALUES ($1, $2, $3, $4, $5, NULLIF($6, ''), $7, $8, $9, $10, $11, $12, $13, $14, $15) 
               RETURNING id, created_at, updated_at, is_default`
 
 	err = tx.QueryRowContext(ctx, query,
@@ -192,7 +220,15 @@ func (s *ShippingAddresStore) Create(ctx context.Context, sa *ShippingAddresses)
 		sa.RecipientName,
 		sa.RecipientPhone,
 		sa.AddressLine1,
+		sa.RegionID,
+		sa.Province,
+		sa.City,
+		sa.District,
+		sa.SubDistrict,
+		sa.PostalCode,
 		sa.NoteForCourier,
+		sa.Latitude,
+		sa.Longitude,
 		sa.IsDefault).Scan(&sa.ID, &sa.CreatedAt, &sa.UpdatedAt, &sa.IsDefault)
 	if err != nil {
 		return fmt.Errorf("failed to insert shipping address: %w", err)
@@ -218,89 +254,112 @@ func (s *ShippingAddresStore) Create(ctx context.Context, sa *ShippingAddresses)
 
 func (s *ShippingAddresStore) Update(ctx context.Context, sa *ShippingAddresses) error {
 	query := `UPDATE shipping_addresses 
-	SET label = $1, recipient_name = $2, recipient_phone = $3, address_line1 = $4, note_for_courier = $5, updated_at = NOW() 
-	WHERE id = $6 AND user_id = $7`
+	SET label = $1, recipient_name = $2, recipient_phone = $3, address_line1 = $4, 
+	region_id = NULLIF($5, ''), province = $6, city = $7, district = $8, sub_district = $9, postal_code = $10,
+	note_for_courier = $11, latitude = $12, longitude = $13, updated_at = NOW() 
+	WHERE id = $14 AND user_id = $15 AND deleted_at IS NULL`
 
 	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
 	defer cancel()
 
-	_, err := s.db.ExecContext(ctx, query,
+	res, err := s.db.ExecContext(ctx, query,
 		sa.Label,
 		sa.RecipientName,
 		sa.RecipientPhone,
 		sa.AddressLine1,
+		sa.RegionID,
+		sa.Province,
+		sa.City,
+		sa.District,
+		sa.SubDistrict,
+		sa.PostalCode,
 		sa.NoteForCourier,
+		sa.Latitude,
+		sa.Longitude,
 		sa.ID,
 		sa.UserID)
 	if err != nil {
 		return err
 	}
 
-	return nil
-}
-
-func (s *ShippingAddresStore) Delete(ctx context.Context, id uuid.UUID, userID int64) error {
-	// Cek apakah alamat yang akan dihapus adalah default
-	var isDefault bool
-	err := s.db.QueryRowContext(ctx,
-		`SELECT is_default FROM shipping_addresses WHERE id = $1 AND user_id = $2`,
-		id, userID).Scan(&isDefault)
-	if err != nil {
-		return err
-	}
-
-	tx, err := s.db.BeginTx(ctx, nil)
+	affected, err := res.RowsAffected()
 	if err != nil {
 		return err
 	}
-	defer tx.Rollback()
-
-	// Hapus alamat
-	_, err = tx.ExecContext(ctx,
-		`DELETE FROM shipping_addresses WHERE id = $1 AND user_id = $2`,
-		id, userID)
-	if err != nil {
-		return err
+	if affected == 0 {
+		return ErrNotFound
 	}
 
-	// Jika alamat yang dihapus adalah default
-	if isDefault {
-		// Cari alamat lain untuk dijadikan default
-		var newDefaultID uuid.UUID
-		err = tx.QueryRowContext(ctx,
-			`SELECT id FROM shipping_addresses 
-             WHERE user_id = $1 LIMIT 1`,
-			userID).Scan(&newDefaultID)
+	return nil
+}
 
-		if err == nil {
-			// Set alamat baru sebagai default
-			_, err = tx.ExecContext(ctx,
-				`UPDATE shipping_addresses SET is_default = true 
-                 WHERE id = $1 AND user_id = $2`,
-				newDefaultID, userID)
-			if err != nil {
-				return err
+// Delete menghapus alamat user. Alamat yang sudah dipakai order hanya di-soft delete agar
+// orders_shipping_addresses_id_fkey tetap valid, dan jika alamat default yang dihapus
+// alamat terbaru lainnya dijadikan default
+func (s *ShippingAddresStore) Delete(ctx context.Context, id uuid.UUID, userID int64) error {
+	return withTx(s.db, ctx, func(tx *sql.Tx) error {
+		ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+		defer cancel()
+
+		var isDefault, usedByOrders bool
+		err := tx.QueryRowContext(ctx, `
+			SELECT sa.is_default, EXISTS (SELECT 1 FROM orders o WHERE o.shipping_addresses_id = sa.id)
+			FROM shipping_addresses sa
+			WHERE sa.id = $1 AND sa.user_id = $2 AND sa.deleted_at IS NULL
+			FOR UPDATE`,
+			id, userID).Scan(&isDefault, &usedByOrders)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return ErrNotFound
 			}
+			return err
+		}
 
-			// Update reference di users
+		if usedByOrders {
 			_, err = tx.ExecContext(ctx,
-				`UPDATE users SET default_shipping_address_id = $1 WHERE id = $2`,
-				newDefaultID, userID)
-			if err != nil {
-				return err
-			}
-		} else if err == sql.ErrNoRows {
+				`UPDATE shipping_addresses SET is_default = false, deleted_at = NOW(), updated_at = NOW() WHERE id = $1`,
+				id)
+		} else {
+			_, err = tx.ExecContext(ctx,
+				`DELETE FROM shipping_addresses WHERE id = $1`,
+				id)
+		}
+		if err != nil {
+			return err
+		}
+
+		if !isDefault {
+			return nil
+		}
+
+		// Promosikan alamat terbaru lainnya sebagai default
+		var newDefaultID uuid.UUID
+		err = tx.QueryRowContext(ctx, `
+			SELECT id FROM shipping_addresses
+			WHERE user_id = $1 AND deleted_at IS NULL
+			ORDER BY created_at DESC
+			LIMIT 1`,
+			userID).Scan(&newDefaultID)
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
 			// Tidak ada alamat lain, set ke NULL di users
 			_, err = tx.ExecContext(ctx,
 				`UPDATE users SET default_shipping_address_id = NULL WHERE id = $1`,
 				userID)
-			if err != nil {
-				return err
-			}
-		} else {
+			return err
+		case err != nil:
 			return err
 		}
-	}
 
-	return tx.Commit()
+		if _, err := tx.ExecContext(ctx,
+			`UPDATE shipping_addresses SET is_default = true WHERE id = $1`,
+			newDefaultID); err != nil {
+			return err
+		}
+
+		_, err = tx.ExecContext(ctx,
+			`UPDATE users SET default_shipping_address_id = $1 WHERE id = $2`,
+			newDefaultID, userID)
+		return err
+	})
 }
@@ -11,7 +11,7 @@ type ShippingMethod struct {
     ID          int64   `json:"id"`
     Name        string  `json:"name"`
     Description string  `json:"description,omitempty"`
-    Price       float64 `json:"price"`
+    Price       Money   `json:"price"`
     IsActive    bool    `json:"is_active"`
 }
 
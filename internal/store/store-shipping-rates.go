@@ -0,0 +1,133 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"github.com/yogaprasetya22/api-gotokopedia/internal/shipping"
+)
+
+// CartStoreParcel adalah paket dari satu cart store: lokasi toko asal dan berat/dimensi setiap item
+type CartStoreParcel struct {
+	CartStoreID uuid.UUID
+	TokoID      int64
+	Origin      *shipping.Point
+	Items       []shipping.Item
+}
+
+type ShippingRateStore struct {
+	db *sql.DB
+}
+
+// GetByMethodIDs mendapatkan tabel tarif per shipping method, urut dari rentang jarak terdekat
+func (s *ShippingRateStore) GetByMethodIDs(ctx context.Context, methodIDs []int64) (map[int64][]shipping.Rate, error) {
+	query := `
+		SELECT shipping_method_id, min_distance_km, max_distance_km, base_price, price_per_kg, etd_min_days, etd_max_days
+		FROM shipping_rates
+		WHERE shipping_method_id = ANY($1)
+		ORDER BY shipping_method_id, min_distance_km`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query, pq.Array(methodIDs))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	rates := make(map[int64][]shipping.Rate)
+	for rows.Next() {
+		var methodID int64
+		var maxDistance sql.NullFloat64
+		var rate shipping.Rate
+		if err := rows.Scan(
+			&methodID,
+			&rate.MinDistanceKm,
+			&maxDistance,
+			&rate.BasePrice,
+			&rate.PricePerKg,
+			&rate.EtdMinDays,
+			&rate.EtdMaxDays,
+		); err != nil {
+			return nil, err
+		}
+
+		if maxDistance.Valid {
+			rate.MaxDistanceKm = &maxDistance.Float64
+		}
+
+		rates[methodID] = append(rates[methodID], rate)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return rates, nil
+}
+
+// GetCartStoreParcels mendapatkan lokasi toko dan berat/dimensi item untuk setiap cart store
+func (s *ShippingRateStore) GetCartStoreParcels(ctx context.Context, cartStoreIDs []uuid.UUID) ([]*CartStoreParcel, error) {
+	query := `
+		SELECT cs.id, cs.toko_id, t.latitude, t.longitude,
+			   p.weight_gram, p.length_cm, p.width_cm, p.height_cm, ci.quantity
+		FROM cart_stores cs
+		JOIN tokos t ON cs.toko_id = t.id
+		JOIN cart_items ci ON ci.cart_store_id = cs.id
+		JOIN products p ON ci.product_id = p.id
+		WHERE cs.id = ANY($1)
+		ORDER BY cs.created_at, ci.created_at`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query, pq.Array(cartStoreIDs))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var parcels []*CartStoreParcel
+	byID := make(map[uuid.UUID]*CartStoreParcel)
+	for rows.Next() {
+		var cartStoreID uuid.UUID
+		var tokoID int64
+		var lat, lng sql.NullFloat64
+		var item shipping.Item
+		if err := rows.Scan(
+			&cartStoreID,
+			&tokoID,
+			&lat,
+			&lng,
+			&item.WeightGram,
+			&item.LengthCm,
+			&item.WidthCm,
+			&item.HeightCm,
+			&item.Quantity,
+		); err != nil {
+			return nil, err
+		}
+
+		parcel, ok := byID[cartStoreID]
+		if !ok {
+			parcel = &CartStoreParcel{CartStoreID: cartStoreID, TokoID: tokoID}
+			if lat.Valid && lng.Valid {
+				parcel.Origin = &shipping.Point{Latitude: lat.Float64, Longitude: lng.Float64}
+			}
+
+			byID[cartStoreID] = parcel
+			parcels = append(parcels, parcel)
+		}
+
+		parcel.Items = append(parcel.Items, item)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return parcels, nil
+}
@@ -0,0 +1,413 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Jenis voucher
+const (
+	VoucherTypePercentage   = "percentage"
+	VoucherTypeFixed        = "fixed"
+	VoucherTypeFreeShipping = "free_shipping"
+)
+
+var (
+	ErrVoucherInactive      = errors.New("voucher tidak aktif atau di luar masa berlaku")
+	ErrVoucherUsageLimit    = errors.New("kuota voucher sudah habis")
+	ErrVoucherMinSpend      = errors.New("total belanja belum memenuhi minimum voucher")
+	ErrVoucherNotApplicable = errors.New("voucher tidak berlaku untuk toko di checkout ini")
+)
+
+// Voucher merepresentasikan potongan harga yang bisa dipakai saat checkout.
+// TokoID nil berarti voucher berlaku di semua toko.
+type Voucher struct {
+	ID                int64     `json:"id"`
+	Code              string    `json:"code"`
+	Name              string    `json:"name"`
+	Description       string    `json:"description,omitempty"`
+	Type              string    `json:"type"`
+	Amount            Money     `json:"amount"`                 // nominal potongan voucher fixed
+	Percent           float64   `json:"percent"`                // persen potongan voucher percentage, 0 sampai 100
+	MaxDiscount       *Money    `json:"max_discount,omitempty"` // batas potongan percentage dan free_shipping
+	MinSpend          Money     `json:"min_spend"`
+	TokoID            *int64    `json:"toko_id,omitempty"`
+	UsageLimit        *int      `json:"usage_limit,omitempty"`
+	UsageLimitPerUser *int      `json:"usage_limit_per_user,omitempty"`
+	UsedCount         int       `json:"used_count"`
+	StartsAt          time.Time `json:"starts_at"`
+	EndsAt            time.Time `json:"ends_at"`
+	IsActive          bool      `json:"is_active"`
+	CreatedAt         time.Time `json:"created_at"`
+	UpdatedAt         time.Time `json:"updated_at"`
+}
+
+// VoucherLine adalah belanja satu cart store yang dihitung potongan vouchernya
+type VoucherLine struct {
+	CartStoreID  uuid.UUID
+	TokoID       int64
+	Subtotal     Money
+	ShippingCost Money
+}
+
+// Validate mengecek status, masa berlaku dan kuota voucher untuk user yang sudah memakai voucher userUsage kali
+func (v *Voucher) Validate(now time.Time, userUsage int) error {
+	if !v.IsActive || now.Before(v.StartsAt) || !now.Before(v.EndsAt) {
+		return ErrVoucherInactive
+	}
+
+	if v.UsageLimit != nil && v.UsedCount >= *v.UsageLimit {
+		return ErrVoucherUsageLimit
+	}
+
+	if v.UsageLimitPerUser != nil && userUsage >= *v.UsageLimitPerUser {
+		return ErrVoucherUsageLimit
+	}
+
+	return nil
+}
+
+// Calculate menghitung potongan per cart store. Voucher toko hanya memotong belanja toko tersebut,
+// potongan voucher platform dibagi ke setiap cart store sebanding dengan belanjanya.
+func (v *Voucher) Calculate(lines []VoucherLine) (map[uuid.UUID]Money, error) {
+	var eligible []VoucherLine
+	spend, shippingCost := IDR(0), IDR(0)
+	for _, line := range lines {
+		if v.TokoID != nil && *v.TokoID != line.TokoID {
+			continue
+		}
+		eligible = append(eligible, line)
+		spend = spend.Add(line.Subtotal)
+		shippingCost = shippingCost.Add(line.ShippingCost)
+	}
+
+	if len(eligible) == 0 {
+		return nil, ErrVoucherNotApplicable
+	}
+
+	if spend.Cmp(v.MinSpend) < 0 {
+		return nil, ErrVoucherMinSpend
+	}
+
+	var total Money
+	var base func(VoucherLine) Money
+	switch v.Type {
+	case VoucherTypePercentage:
+		total = spend.Percent(v.Percent)
+		base = func(l VoucherLine) Money { return l.Subtotal }
+	case VoucherTypeFixed:
+		total = v.Amount.Min(spend)
+		base = func(l VoucherLine) Money { return l.Subtotal }
+	case VoucherTypeFreeShipping:
+		total = shippingCost
+		base = func(l VoucherLine) Money { return l.ShippingCost }
+	default:
+		return nil, ErrVoucherNotApplicable
+	}
+
+	if v.MaxDiscount != nil && v.Type != VoucherTypeFixed {
+		total = total.Min(*v.MaxDiscount)
+	}
+
+	baseTotal := IDR(0)
+	for _, line := range eligible {
+		baseTotal = baseTotal.Add(base(line))
+	}
+
+	// Sisa pembulatan masuk ke cart store terakhir agar jumlahnya tetap sama dengan total potongan
+	discounts := make(map[uuid.UUID]Money, len(eligible))
+	remaining := total
+	for i, line := range eligible {
+		share := remaining
+		if i < len(eligible)-1 {
+			share = total.Share(base(line).Amount, baseTotal.Amount)
+		}
+		share = share.Min(base(line))
+		discounts[line.CartStoreID] = share
+		remaining = remaining.Sub(share)
+	}
+
+	return discounts, nil
+}
+
+type VoucherStore struct {
+	db *sql.DB
+}
+
+const voucherColumns = `id, code, name, COALESCE(description, ''), type, amount, percent, max_discount, min_spend, toko_id,
+	usage_limit, usage_limit_per_user, used_count, starts_at, ends_at, is_active, created_at, updated_at`
+
+// Create menambahkan voucher baru, kode voucher disimpan dalam huruf besar
+func (s *VoucherStore) Create(ctx context.Context, v *Voucher) error {
+	query := `
+		INSERT INTO vouchers (code, name, description, type, amount, percent, max_discount, min_spend, toko_id,
+			usage_limit, usage_limit_per_user, starts_at, ends_at, is_active)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)
+		RETURNING id, used_count, created_at, updated_at`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	v.Code = strings.ToUpper(v.Code)
+	err := s.db.QueryRowContext(ctx, query,
+		v.Code, v.Name, v.Description, v.Type, v.Amount, v.Percent, v.MaxDiscount, v.MinSpend, v.TokoID,
+		v.UsageLimit, v.UsageLimitPerUser, v.StartsAt, v.EndsAt, v.IsActive,
+	).Scan(&v.ID, &v.UsedCount, &v.CreatedAt, &v.UpdatedAt)
+	if err != nil {
+		if strings.Contains(err.Error(), "vouchers_code_key") {
+			return ErrConflict
+		}
+		return err
+	}
+
+	return nil
+}
+
+// Update memperbarui voucher, used_count tidak ikut diubah
+func (s *VoucherStore) Update(ctx context.Context, v *Voucher) error {
+	query := `
+		UPDATE vouchers
+		SET code = $1, name = $2, description = $3, type = $4, amount = $5, percent = $6, max_discount = $7,
+			min_spend = $8, toko_id = $9, usage_limit = $10, usage_limit_per_user = $11, starts_at = $12,
+			ends_at = $13, is_active = $14, updated_at = now()
+		WHERE id = $15
+		RETURNING used_count, updated_at`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	v.Code = strings.ToUpper(v.Code)
+	err := s.db.QueryRowContext(ctx, query,
+		v.Code, v.Name, v.Description, v.Type, v.Amount, v.Percent, v.MaxDiscount, v.MinSpend, v.TokoID,
+		v.UsageLimit, v.UsageLimitPerUser, v.StartsAt, v.EndsAt, v.IsActive, v.ID,
+	).Scan(&v.UsedCount, &v.UpdatedAt)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrNotFound
+		case strings.Contains(err.Error(), "vouchers_code_key"):
+			return ErrConflict
+		default:
+			return err
+		}
+	}
+
+	return nil
+}
+
+// GetByID mendapatkan voucher berdasarkan ID
+func (s *VoucherStore) GetByID(ctx context.Context, id int64) (*Voucher, error) {
+	query := `SELECT ` + voucherColumns + ` FROM vouchers WHERE id = $1`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	v, err := scanVoucher(s.db.QueryRowContext(ctx, query, id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+
+	return v, nil
+}
+
+// GetByCode mendapatkan voucher berdasarkan kode, tidak membedakan huruf besar dan kecil
+func (s *VoucherStore) GetByCode(ctx context.Context, code string) (*Voucher, error) {
+	query := `SELECT ` + voucherColumns + ` FROM vouchers WHERE code = $1`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	v, err := scanVoucher(s.db.QueryRowContext(ctx, query, strings.ToUpper(code)))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+
+	return v, nil
+}
+
+// List mendapatkan semua voucher, yang terbaru lebih dulu
+func (s *VoucherStore) List(ctx context.Context) ([]*Voucher, error) {
+	query := `SELECT ` + voucherColumns + ` FROM vouchers ORDER BY created_at DESC, id DESC`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	vouchers := []*Voucher{}
+	for rows.Next() {
+		v, err := scanVoucher(rows)
+		if err != nil {
+			return nil, err
+		}
+		vouchers = append(vouchers, v)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return vouchers, nil
+}
+
+// Delete menghapus voucher, order yang pernah memakainya tetap menyimpan nilai discount
+func (s *VoucherStore) Delete(ctx context.Context, id int64) error {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	res, err := s.db.ExecContext(ctx, `DELETE FROM vouchers WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if affected == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+// CountUserUsage menghitung berapa kali user sudah memakai voucher
+func (s *VoucherStore) CountUserUsage(ctx context.Context, voucherID, userID int64) (int, error) {
+	query := `SELECT COUNT(*) FROM voucher_usages WHERE voucher_id = $1 AND user_id = $2`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	var count int
+	if err := s.db.QueryRowContext(ctx, query, voucherID, userID).Scan(&count); err != nil {
+		return 0, err
+	}
+
+	return count, nil
+}
+
+// redeemTx mengunci voucher, memvalidasi ulang kuotanya lalu mencatat pemakaian oleh checkout session.
+// ID pemakaian disimpan di order agar kuotanya bisa dikembalikan oleh releaseUsageTx.
+func (s *VoucherStore) redeemTx(ctx context.Context, tx *sql.Tx, voucherID, userID int64, sessionID string, discount Money) (int64, error) {
+	query := `SELECT ` + voucherColumns + ` FROM vouchers WHERE id = $1 FOR UPDATE`
+
+	v, err := scanVoucher(tx.QueryRowContext(ctx, query, voucherID))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, ErrVoucherInactive
+		}
+		return 0, err
+	}
+
+	var userUsage int
+	err = tx.QueryRowContext(ctx,
+		`SELECT COUNT(*) FROM voucher_usages WHERE voucher_id = $1 AND user_id = $2`,
+		voucherID, userID,
+	).Scan(&userUsage)
+	if err != nil {
+		return 0, err
+	}
+
+	if err := v.Validate(time.Now(), userUsage); err != nil {
+		return 0, err
+	}
+
+	var usageID int64
+	err = tx.QueryRowContext(ctx,
+		`INSERT INTO voucher_usages (voucher_id, user_id, session_id, discount) VALUES ($1, $2, $3, $4) RETURNING id`,
+		voucherID, userID, sessionID, discount,
+	).Scan(&usageID)
+	if err != nil {
+		return 0, err
+	}
+
+	_, err = tx.ExecContext(ctx, `UPDATE vouchers SET used_count = used_count + 1 WHERE id = $1`, voucherID)
+	if err != nil {
+		return 0, err
+	}
+
+	return usageID, nil
+}
+
+// releaseUsageTx mengembalikan kuota voucher yang dipakai order setelah order dibatalkan. Satu pemakaian
+// bisa dibagi beberapa order dari checkout yang sama, kuotanya baru dikembalikan jika semua order tersebut batal.
+func releaseUsageTx(ctx context.Context, tx *sql.Tx, orderID int64) error {
+	// Kunci pemakaian agar pembatalan bersamaan order lain dari checkout yang sama melihat status terbaru
+	var usageID, voucherID int64
+	err := tx.QueryRowContext(ctx, `
+		SELECT vu.id, vu.voucher_id FROM voucher_usages vu
+		JOIN orders o ON o.voucher_usage_id = vu.id
+		WHERE o.id = $1
+		FOR UPDATE OF vu`, orderID,
+	).Scan(&usageID, &voucherID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil
+		}
+		return err
+	}
+
+	var active bool
+	err = tx.QueryRowContext(ctx, `
+		SELECT EXISTS (
+			SELECT 1 FROM orders
+			WHERE voucher_usage_id = $1 AND id <> $2 AND status_id <> $3
+		)`, usageID, orderID, OrderStatusCancelled,
+	).Scan(&active)
+	if err != nil {
+		return err
+	}
+	if active {
+		return nil
+	}
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM voucher_usages WHERE id = $1`, usageID); err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, `UPDATE vouchers SET used_count = GREATEST(used_count - 1, 0) WHERE id = $1`, voucherID)
+	return err
+}
+
+func scanVoucher(row interface{ Scan(...any) error }) (*Voucher, error) {
+	v := &Voucher{}
+	var tokoID sql.NullInt64
+	var usageLimit, usageLimitPerUser sql.NullInt32
+	err := row.Scan(
+		&v.ID, &v.Code, &v.Name, &v.Description, &v.Type, &v.Amount, &v.Percent, &v.MaxDiscount, &v.MinSpend, &tokoID,
+		&usageLimit, &usageLimitPerUser, &v.UsedCount, &v.StartsAt, &v.EndsAt, &v.IsActive, &v.CreatedAt, &v.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if tokoID.Valid {
+		v.TokoID = &tokoID.Int64
+	}
+	if usageLimit.Valid {
+		limit := int(usageLimit.Int32)
+		v.UsageLimit = &limit
+	}
+	if usageLimitPerUser.Valid {
+		limit := int(usageLimitPerUser.Int32)
+		v.UsageLimitPerUser = &limit
+	}
+
+	return v, nil
+}
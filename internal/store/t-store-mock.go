@@ -3,6 +3,8 @@ package store
 import (
 	"context"
 	"database/sql"
+	"fmt"
+	"sync"
 	"time"
 
 	"github.com/google/uuid"
@@ -13,7 +15,11 @@ func NewMockStore() Storage {
 	return Storage{
 		Users: &MockUserStore{},
 		// Products: &MockProductStore{},
-		Carts: &MockCartStore{},
+		Carts:             &MockCartStore{},
+		Payments:          &MockPaymentStore{},
+		IdempotencyKeys:   &MockIdempotencyStore{},
+		ShippingAddresses: &MockShippingAddressStore{},
+		Regions:           &MockRegionStore{},
 	}
 }
 
@@ -57,8 +63,8 @@ func (m *MockUserStore) Delete(ctx context.Context, id int64) error {
 
 type MockCartStore struct{ mock.Mock }
 
-func (m *MockCartStore) AddToCartTransaction(ctx context.Context, userID, productID, quantity int64) (*Cart, error) {
-	args := m.Called(ctx, userID, productID, quantity)
+func (m *MockCartStore) AddToCartTransaction(ctx context.Context, userID, productID int64, variantID *int64, quantity int64) (*Cart, error) {
+	args := m.Called(ctx, userID, productID, variantID, quantity)
 	return args.Get(0).(*Cart), args.Error(1)
 }
 
@@ -103,3 +109,156 @@ func (m *MockCartStore) DeleteByCartStoreID(ctx context.Context, cartStoreID uui
 func (m *MockCartStore) RemoveCartItemByID(ctx context.Context, cartItemID uuid.UUID) error {
 	return nil
 }
+
+type MockPaymentStore struct{ mock.Mock }
+
+func (m *MockPaymentStore) Create(ctx context.Context, p *Payment) error {
+	args := m.Called(ctx, p)
+	return args.Error(0)
+}
+
+func (m *MockPaymentStore) GetByOrderID(ctx context.Context, orderID int64) (*Payment, error) {
+	args := m.Called(ctx, orderID)
+	return args.Get(0).(*Payment), args.Error(1)
+}
+
+func (m *MockPaymentStore) GetByOrderNumber(ctx context.Context, orderNumber string) (*Payment, error) {
+	args := m.Called(ctx, orderNumber)
+	return args.Get(0).(*Payment), args.Error(1)
+}
+
+func (m *MockPaymentStore) GetByTransactionID(ctx context.Context, transactionID string) (*Payment, error) {
+	args := m.Called(ctx, transactionID)
+	return args.Get(0).(*Payment), args.Error(1)
+}
+
+func (m *MockPaymentStore) AttachCharge(ctx context.Context, orderID int64, transactionID string) (*Payment, error) {
+	args := m.Called(ctx, orderID, transactionID)
+	return args.Get(0).(*Payment), args.Error(1)
+}
+
+func (m *MockPaymentStore) FailCharge(ctx context.Context, orderID int64, notes string) error {
+	args := m.Called(ctx, orderID, notes)
+	return args.Error(0)
+}
+
+func (m *MockPaymentStore) UpdateStatus(ctx context.Context, transactionID, status string) (*Payment, error) {
+	args := m.Called(ctx, transactionID, status)
+	return args.Get(0).(*Payment), args.Error(1)
+}
+
+type MockShippingAddressStore struct{ mock.Mock }
+
+func (m *MockShippingAddressStore) SetDefaultAddress(ctx context.Context, addressID uuid.UUID, userID int64) error {
+	args := m.Called(ctx, addressID, userID)
+	return args.Error(0)
+}
+
+func (m *MockShippingAddressStore) GetDefaultAddress(ctx context.Context, userID int64) (*ShippingAddresses, error) {
+	args := m.Called(ctx, userID)
+	return args.Get(0).(*ShippingAddresses), args.Error(1)
+}
+
+func (m *MockShippingAddressStore) ListByUser(ctx context.Context, userID int64) ([]*ShippingAddresses, error) {
+	args := m.Called(ctx, userID)
+	return args.Get(0).([]*ShippingAddresses), args.Error(1)
+}
+
+func (m *MockShippingAddressStore) GetByID(ctx context.Context, id uuid.UUID, userID int64) (*ShippingAddresses, error) {
+	args := m.Called(ctx, id, userID)
+	return args.Get(0).(*ShippingAddresses), args.Error(1)
+}
+
+func (m *MockShippingAddressStore) Create(ctx context.Context, sa *ShippingAddresses) error {
+	args := m.Called(ctx, sa)
+	return args.Error(0)
+}
+
+func (m *MockShippingAddressStore) Update(ctx context.Context, sa *ShippingAddresses) error {
+	args := m.Called(ctx, sa)
+	return args.Error(0)
+}
+
+func (m *MockShippingAddressStore) Delete(ctx context.Context, id uuid.UUID, userID int64) error {
+	args := m.Called(ctx, id, userID)
+	return args.Error(0)
+}
+
+type MockRegionStore struct{ mock.Mock }
+
+func (m *MockRegionStore) GetByID(ctx context.Context, id string) (*Region, error) {
+	args := m.Called(ctx, id)
+	return args.Get(0).(*Region), args.Error(1)
+}
+
+func (m *MockRegionStore) GetChildren(ctx context.Context, parentID string) ([]*Region, error) {
+	args := m.Called(ctx, parentID)
+	return args.Get(0).([]*Region), args.Error(1)
+}
+
+func (m *MockRegionStore) GetPath(ctx context.Context, id string) ([]*Region, error) {
+	args := m.Called(ctx, id)
+	return args.Get(0).([]*Region), args.Error(1)
+}
+
+// MockIdempotencyStore menyimpan record idempotency di memori
+type MockIdempotencyStore struct {
+	mu      sync.Mutex
+	records map[string]*IdempotencyRecord
+}
+
+func (m *MockIdempotencyStore) recordKey(userID int64, key string) string {
+	return fmt.Sprintf("%d:%s", userID, key)
+}
+
+func (m *MockIdempotencyStore) Get(ctx context.Context, userID int64, key string) (*IdempotencyRecord, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	r, ok := m.records[m.recordKey(userID, key)]
+	if !ok {
+		return nil, ErrNotFound
+	}
+
+	c := *r
+	return &c, nil
+}
+
+func (m *MockIdempotencyStore) Reserve(ctx context.Context, r *IdempotencyRecord) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.records == nil {
+		m.records = make(map[string]*IdempotencyRecord)
+	}
+
+	k := m.recordKey(r.UserID, r.Key)
+	if _, ok := m.records[k]; ok {
+		return false, nil
+	}
+
+	c := *r
+	m.records[k] = &c
+	return true, nil
+}
+
+func (m *MockIdempotencyStore) Save(ctx context.Context, r *IdempotencyRecord) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	c := *r
+	m.records[m.recordKey(r.UserID, r.Key)] = &c
+	return nil
+}
+
+func (m *MockIdempotencyStore) Delete(ctx context.Context, userID int64, key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.records, m.recordKey(userID, key))
+	return nil
+}
+
+func (m *MockIdempotencyStore) DeleteExpired(ctx context.Context) (int64, error) {
+	return 0, nil
+}